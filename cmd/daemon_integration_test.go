@@ -51,6 +51,73 @@ logging:
 	})
 }
 
+func TestDaemon_ProcessesExistingFilesOnStartup(t *testing.T) {
+	// Tests that files that landed while the daemon was stopped are processed
+	testutil.Run(t, testutil.TestCase{
+		Name: "move existing file on startup",
+		Config: `
+rules:
+  - name: move-all
+    locations: {{join .TmpDir "source"}}
+    actions:
+      - move: {{join .TmpDir "dest"}}
+
+daemon:
+  debounce: 50ms
+
+logging:
+  level: debug
+`,
+		Before: []testutil.FileEntry{
+			testutil.Dir("source"),
+			testutil.Dir("dest"),
+			testutil.File("source/existing.txt").WithContent("hello"),
+		},
+		Expect: []testutil.FileEntry{
+			testutil.File("dest/existing.txt"),
+		},
+		Missing: []string{
+			"source/existing.txt",
+		},
+	})
+}
+
+func TestDaemon_PollWatch(t *testing.T) {
+	// Tests that polled locations pick up files without fsnotify events
+	testutil.Run(t, testutil.TestCase{
+		Name: "move file found by polling",
+		Config: `
+rules:
+  - name: move-all
+    locations: {{join .TmpDir "source"}}
+    watch:
+      poll: 1s
+    actions:
+      - move: {{join .TmpDir "dest"}}
+
+daemon:
+  debounce: 50ms
+
+logging:
+  level: debug
+`,
+		Before: []testutil.FileEntry{
+			testutil.Dir("source"),
+			testutil.Dir("dest"),
+		},
+		Trigger: []testutil.FileEntry{
+			testutil.File("source/test.txt").WithContent("hello"),
+		},
+		Expect: []testutil.FileEntry{
+			testutil.File("dest/test.txt"),
+		},
+		Missing: []string{
+			"source/test.txt",
+		},
+		Timeout: 4 * time.Second,
+	})
+}
+
 func TestDaemon_Filter(t *testing.T) {
 	// Tests that filters only process matching files
 	testutil.Run(t, testutil.TestCase{
@@ -577,3 +644,80 @@ logging:
 		t.Error("daemon did not stop within timeout")
 	}
 }
+
+func TestDaemon_ConfigReload(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := tmpDir + "/source"
+	destDir := tmpDir + "/dest"
+	otherDir := tmpDir + "/other"
+
+	for _, dir := range []string{sourceDir, destDir, otherDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+
+	configFor := func(dest string) string {
+		return `
+rules:
+  - name: move-all
+    locations: ` + sourceDir + `
+    actions:
+      - move: ` + dest + `
+
+daemon:
+  debounce: 50ms
+`
+	}
+	configPath := tmpDir + "/config.yaml"
+	if err := os.WriteFile(configPath, []byte(configFor(destDir)), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- daemon.Run(ctx, configPath, afero.NewOsFs(), func(level string) {})
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	// An invalid edit keeps the current rules running
+	if err := os.WriteFile(configPath, []byte("rules: [\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+
+	if err := os.WriteFile(sourceDir+"/first.txt", []byte("first"), 0644); err != nil {
+		t.Fatalf("failed to create first.txt: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if _, err := os.Stat(destDir + "/first.txt"); err != nil {
+		t.Errorf("expected dest/first.txt to exist after an invalid config edit: %v", err)
+	}
+
+	// A valid edit replaces the rules
+	if err := os.WriteFile(configPath, []byte(configFor(otherDir)), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+
+	if err := os.WriteFile(sourceDir+"/second.txt", []byte("second"), 0644); err != nil {
+		t.Fatalf("failed to create second.txt: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if _, err := os.Stat(otherDir + "/second.txt"); err != nil {
+		t.Errorf("expected other/second.txt to exist after the config was reloaded: %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("daemon returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("daemon did not stop within timeout")
+	}
+}
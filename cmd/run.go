@@ -6,9 +6,11 @@ import (
 
 	"github.com/prettymuchbryce/autotidy/internal/config"
 	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/journal"
 	"github.com/prettymuchbryce/autotidy/internal/pathutil"
 	"github.com/prettymuchbryce/autotidy/internal/report"
 	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/state"
 	"github.com/spf13/cobra"
 
 	// Import for side effects (filter/action registration)
@@ -50,14 +52,20 @@ var runCmd = &cobra.Command{
 			return nil
 		}
 
-		// Create the appropriate filesystem based on dry-run flag
+		// Create the appropriate filesystem based on dry-run flag.
+		// Only real runs are journaled, since dry runs change nothing.
 		var filesystem fs.FileSystem
+		var jrnl *journal.Journal
 		if runDryRun {
 			filesystem = fs.NewDryRun()
 			fmt.Println("Dry-run mode enabled (pass --dry-run=false to perform a one-off run of all rules)")
 		} else {
 			filesystem = fs.NewReal()
 			fmt.Println("Dry-run mode disabled - performing a one-off run of all rules")
+			jrnl, err = journal.Open()
+			if err != nil {
+				slog.Warn("failed to open journal, actions will not be recorded", "error", err)
+			}
 		}
 
 		// Create reporter for structured output
@@ -66,11 +74,17 @@ var runCmd = &cobra.Command{
 		for i := range cfg.Rules {
 			rule := &cfg.Rules[i]
 			runner := rules.NewRuleRunner(rule, filesystem, reporter)
+			runner.SetJournal(jrnl)
 			if _, err := runner.Execute(); err != nil {
 				slog.Error("failed to execute rule", "rule", rule.Name, "error", err)
 			}
 		}
 
+		// Persist file hashes computed by filters such as duplicate
+		if err := state.SaveSharedHashCache(); err != nil {
+			slog.Warn("failed to save hash cache", "error", err)
+		}
+
 		return nil
 	},
 }
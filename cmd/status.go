@@ -41,7 +41,7 @@ var statusCmd = &cobra.Command{
 		isDefault := config.IsDefaultConfig(status.ConfigPath)
 		if isDefault {
 			welcome := "👋 Welcome to autotidy\n\n" + "1. Get started by adding rules to the config file at the path below.\n" +
-				"2. Reload rules with " + highlightStyle.Render("autotidy reload") + " after making changes."
+				"2. Rules reload when you save the file, or run " + highlightStyle.Render("autotidy reload") + " to reload now."
 			fmt.Println(boxStyle.Render(welcome))
 		}
 
@@ -85,15 +85,34 @@ var statusCmd = &cobra.Command{
 					statsLine += dimStyle.Render(")")
 				}
 
+				// Build schedule line for scheduled rules
+				var scheduleLine string
+				if rule.Schedule != "" {
+					scheduleLine = "  schedule: " + rule.Schedule
+					if rule.NextRunAt != nil {
+						scheduleLine += " (next " + formatTimeUntil(*rule.NextRunAt) + ")"
+					}
+					scheduleLine = dimStyle.Render(scheduleLine)
+				}
+
 				ruleLine := fmt.Sprintf("%s %s", icon, rule.Name)
 				if statsLine != "" {
 					ruleLine += "\n" + statsLine
 				}
+				if scheduleLine != "" {
+					ruleLine += "\n" + scheduleLine
+				}
 				ruleLines = append(ruleLines, ruleLine)
 			}
 			rulesValue = strings.Join(ruleLines, "\n")
 		}
 
+		// Build config value
+		configValue := dimStyle.Render(status.ConfigPath)
+		if !status.ConfigValid {
+			configValue += "\n" + strings.Repeat(" ", 12) + "⚠️ invalid, still running the previous rules: " + status.ConfigError
+		}
+
 		// Build watching value
 		var watchingValue string
 		if status.Enabled && status.WatchCount > 0 {
@@ -104,7 +123,7 @@ var statusCmd = &cobra.Command{
 
 		// Print status info
 		fmt.Println(labelStyle.Render("status") + statusValue)
-		fmt.Println(labelStyle.Render("config") + dimStyle.Render(status.ConfigPath))
+		fmt.Println(labelStyle.Render("config") + configValue)
 		fmt.Println(labelStyle.Render("watching") + watchingValue)
 		fmt.Println(labelStyle.Render("rules"))
 		if rulesValue != "" {
@@ -148,6 +167,33 @@ func formatTimeAgo(t time.Time) string {
 	}
 }
 
+// formatTimeUntil formats a future time as a human-readable relative time.
+func formatTimeUntil(t time.Time) string {
+	d := time.Until(t)
+	switch {
+	case d < time.Minute:
+		return "in under a minute"
+	case d < time.Hour:
+		mins := int(d.Minutes())
+		if mins == 1 {
+			return "in 1 min"
+		}
+		return fmt.Sprintf("in %d mins", mins)
+	case d < 24*time.Hour:
+		hours := int(d.Hours())
+		if hours == 1 {
+			return "in 1 hour"
+		}
+		return fmt.Sprintf("in %d hours", hours)
+	default:
+		days := int(d.Hours() / 24)
+		if days == 1 {
+			return "in 1 day"
+		}
+		return fmt.Sprintf("in %d days", days)
+	}
+}
+
 // formatDuration formats a duration in a human-readable way.
 func formatDuration(d time.Duration) string {
 	switch {
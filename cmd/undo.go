@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoDryRun bool
+	undoCount  int
+)
+
+var (
+	undoOkStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	undoFailStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert actions recorded in the action journal",
+	Long: `Revert moves, renames, copies and trashes performed by autotidy.
+
+Every action that changes a file is recorded in an append-only journal.
+Moves and renames are moved back, copies are removed, and trashed files
+are restored from the trash (Linux only). Deletes cannot be undone; they
+are reported once and not selected again.
+
+If the daemon is running, its rules may act on restored files again.
+Run ` + "`autotidy disable`" + ` first to prevent this.`,
+}
+
+var undoLastCmd = &cobra.Command{
+	Use:   "last",
+	Short: "Revert every action from the most recent rule run",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runUndo(journal.LastRun)
+	},
+}
+
+var undoRuleCmd = &cobra.Command{
+	Use:   "rule <name>",
+	Short: "Revert the last N actions of a rule",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if undoCount < 1 {
+			return fmt.Errorf("--count must be at least 1")
+		}
+		return runUndo(func(entries []journal.Entry) []journal.Entry {
+			return journal.ForRule(entries, args[0], undoCount)
+		})
+	},
+}
+
+var undoSinceCmd = &cobra.Command{
+	Use:   "since <time>",
+	Short: "Revert every action since a time (e.g. 2024-05-01T12:00:00 or 2h)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := parseUndoTime(args[0], time.Now())
+		if err != nil {
+			return err
+		}
+		return runUndo(func(entries []journal.Entry) []journal.Entry {
+			return journal.Since(entries, since)
+		})
+	},
+}
+
+// runUndo selects entries from the journal and reverts them.
+func runUndo(selectEntries func([]journal.Entry) []journal.Entry) error {
+	jrnl, err := journal.Open()
+	if err != nil {
+		return err
+	}
+
+	entries, err := jrnl.Entries()
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	selected := selectEntries(entries)
+	if len(selected) == 0 {
+		fmt.Println("Nothing to undo")
+		return nil
+	}
+
+	if undoDryRun {
+		fmt.Println("Dry-run mode enabled - the following actions would be reverted:")
+		for _, e := range selected {
+			fmt.Println("  " + formatJournalEntry(e))
+		}
+		return nil
+	}
+
+	failed := 0
+	for _, result := range jrnl.Undo(fs.NewReal(), selected) {
+		if result.Err != nil {
+			failed++
+			fmt.Printf("%s %s %s\n", undoFailStyle.Render("✗"), formatJournalEntry(result.Entry), dimStyle.Render("("+result.Err.Error()+")"))
+			continue
+		}
+		fmt.Printf("%s %s\n", undoOkStyle.Render("✓"), formatJournalEntry(result.Entry))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d actions could not be reverted", failed, len(selected))
+	}
+	return nil
+}
+
+// formatJournalEntry formats an entry as a single human-readable line.
+func formatJournalEntry(e journal.Entry) string {
+	line := fmt.Sprintf("%s %s: %s", e.Rule, e.Action, e.Src)
+	if e.Dest != "" {
+		line += " → " + e.Dest
+	}
+	return line + " " + dimStyle.Render(formatTimeAgo(e.Time))
+}
+
+// parseUndoTime parses an absolute local time or a duration before now.
+func parseUndoTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected a duration (e.g. 2h), YYYY-MM-DD, or YYYY-MM-DDTHH:MM:SS", s)
+}
+
+func init() {
+	undoCmd.PersistentFlags().BoolVar(&undoDryRun, "dry-run", false, "list the actions that would be reverted without changing anything")
+	undoRuleCmd.Flags().IntVarP(&undoCount, "count", "n", 1, "number of most recent actions to revert")
+	undoCmd.AddCommand(undoLastCmd, undoRuleCmd, undoSinceCmd)
+	rootCmd.AddCommand(undoCmd)
+}
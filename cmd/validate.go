@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/prettymuchbryce/autotidy/internal/config"
+	"github.com/prettymuchbryce/autotidy/internal/pathutil"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var validateConfigPath string
+
+var validateCmd = &cobra.Command{
+	Use:          "validate",
+	Short:        "Check the configuration file for errors without running any rules",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath := pathutil.ExpandTilde(validateConfigPath)
+
+		problems, err := config.Validate(configPath, afero.NewOsFs())
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+
+		errorCount := 0
+		for _, p := range problems {
+			fmt.Println(formatProblem(configPath, p))
+			if !p.Warning {
+				errorCount++
+			}
+		}
+
+		if errorCount > 0 {
+			return fmt.Errorf("found %d errors in %s", errorCount, configPath)
+		}
+		fmt.Printf("%s is valid\n", configPath)
+		return nil
+	},
+}
+
+func init() {
+	validateCmd.Flags().StringVarP(&validateConfigPath, "config", "c", pathutil.MustDefaultConfigPath(), "path to config file")
+	rootCmd.AddCommand(validateCmd)
+}
+
+// formatProblem formats a problem as "path:line:column: message", leaving
+// out the parts of the position that are unknown.
+func formatProblem(path string, p config.Problem) string {
+	location := path
+	if p.Line > 0 {
+		location += fmt.Sprintf(":%d", p.Line)
+		if p.Column > 0 {
+			location += fmt.Sprintf(":%d", p.Column)
+		}
+	}
+
+	severity := "error"
+	if p.Warning {
+		severity = "warning"
+	}
+	return fmt.Sprintf("%s: %s: %s", location, severity, p.Message)
+}
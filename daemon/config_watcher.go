@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configReloadDelay is how long the config file must go without changes
+// before it is reloaded, since editors often save in several steps.
+const configReloadDelay = 500 * time.Millisecond
+
+// WatchConfig reloads the configuration whenever the config file changes,
+// until ctx is cancelled. An invalid edit leaves the current rules running.
+//
+// The directories containing the file are watched rather than the file
+// itself, since editors often save by replacing the file, which ends a watch
+// on it. If the config is a symlink, its target's directory is watched too.
+func (c *Controller) WatchConfig(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsw.Close()
+
+	paths := configPaths(c.configPath)
+	for _, path := range paths {
+		if err := fsw.Add(filepath.Dir(path)); err != nil {
+			return err
+		}
+	}
+
+	timer := time.NewTimer(configReloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if isConfigEvent(event, paths) {
+				timer.Reset(configReloadDelay)
+			}
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Warn("config watcher error", "error", err)
+		case <-timer.C:
+			c.reloadOnChange()
+		}
+	}
+}
+
+// reloadOnChange reloads the configuration after the file changed.
+func (c *Controller) reloadOnChange() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	slog.Info("config file changed, reloading", "path", c.configPath)
+	if _, err := c.reload(); err != nil {
+		slog.Error("invalid config, keeping the current rules", "path", c.configPath, "error", err)
+	}
+}
+
+// configPaths returns the path of the config file, and the path it links to
+// if it is a symlink.
+func configPaths(configPath string) []string {
+	paths := []string{filepath.Clean(configPath)}
+	if target, err := filepath.EvalSymlinks(configPath); err == nil && target != paths[0] {
+		paths = append(paths, target)
+	}
+	return paths
+}
+
+// isConfigEvent reports whether the event changed the config file's content.
+func isConfigEvent(event fsnotify.Event, paths []string) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	for _, path := range paths {
+		if filepath.Clean(event.Name) == path {
+			return true
+		}
+	}
+	return false
+}
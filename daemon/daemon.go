@@ -4,10 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"time"
+	"sync"
 
 	"github.com/prettymuchbryce/autotidy/internal/config"
 	"github.com/prettymuchbryce/autotidy/internal/ipc"
+	"github.com/prettymuchbryce/autotidy/internal/journal"
 	"github.com/prettymuchbryce/autotidy/internal/rules"
 	"github.com/prettymuchbryce/autotidy/internal/state"
 	"github.com/prettymuchbryce/autotidy/internal/watcher"
@@ -17,13 +18,21 @@ import (
 )
 
 // Controller manages the daemon lifecycle and implements ipc.Handler.
-// All methods are called serially by the IPC server, so no locking is needed.
+// The IPC server calls the handlers serially, but the config watcher reloads
+// from its own goroutine, so every handler holds mu.
 type Controller struct {
+	mu sync.Mutex
+
 	configPath string
 	fs         afero.Fs
 	state      *state.State
+	journal    *journal.Journal
 	rules      []rules.Rule
-	debounce   time.Duration
+	daemon     config.DaemonConfig
+
+	// configErr is why the last reload failed, or nil if it succeeded. The
+	// rules of the last valid configuration keep running meanwhile.
+	configErr error
 
 	watcher            *watcher.Watcher
 	stopWatcher        context.CancelFunc
@@ -31,19 +40,20 @@ type Controller struct {
 }
 
 // NewController creates a new daemon controller.
-func NewController(configPath string, fs afero.Fs, st *state.State, rules []rules.Rule, debounce time.Duration) *Controller {
+func NewController(configPath string, fs afero.Fs, st *state.State, jrnl *journal.Journal, rules []rules.Rule, daemonConfig config.DaemonConfig) *Controller {
 	return &Controller{
 		configPath: configPath,
 		fs:         fs,
 		state:      st,
+		journal:    jrnl,
 		rules:      rules,
-		debounce:   debounce,
+		daemon:     daemonConfig,
 	}
 }
 
 // StartWatcher creates and starts a new watcher.
 func (c *Controller) StartWatcher() error {
-	w, err := watcher.New(c.rules, c.debounce, c.state)
+	w, err := watcher.New(c.rules, c.daemon, c.state, c.journal)
 	if err != nil {
 		return err
 	}
@@ -95,6 +105,12 @@ func Run(ctx context.Context, configPath string, fs afero.Fs, setupLogging func(
 		st, _ = state.LoadFrom("")
 	}
 
+	// Open the action journal used by `autotidy undo`
+	jrnl, err := journal.Open()
+	if err != nil {
+		slog.Warn("failed to open journal, actions will not be recorded", "error", err)
+	}
+
 	enabledRules := cfg.CountEnabledRules()
 	slog.Info("loaded config", "rules", len(cfg.Rules), "enabled", enabledRules, "debounce", cfg.Daemon.Debounce)
 
@@ -104,13 +120,20 @@ func Run(ctx context.Context, configPath string, fs afero.Fs, setupLogging func(
 	}
 
 	// Create daemon controller
-	controller := NewController(configPath, fs, st, cfg.Rules, cfg.Daemon.Debounce)
+	controller := NewController(configPath, fs, st, jrnl, cfg.Rules, cfg.Daemon)
 
 	// Start the watcher
 	if err := controller.StartWatcher(); err != nil {
 		return fmt.Errorf("failed to start watcher: %w", err)
 	}
 
+	// Reload the config whenever it is edited
+	go func() {
+		if err := controller.WatchConfig(ctx); err != nil {
+			slog.Warn("failed to watch config, run `autotidy reload` after editing it", "path", configPath, "error", err)
+		}
+	}()
+
 	// Start IPC server
 	ipcServer, err := ipc.NewServer(controller)
 	if err != nil {
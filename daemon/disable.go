@@ -4,6 +4,9 @@ import "log/slog"
 
 // HandleDisable stops the watcher if running.
 func (c *Controller) HandleDisable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.watcher == nil {
 		return
 	}
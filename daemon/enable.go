@@ -4,6 +4,9 @@ import "log/slog"
 
 // HandleEnable starts the watcher if not running.
 func (c *Controller) HandleEnable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.watcher != nil {
 		return
 	}
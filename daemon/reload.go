@@ -10,15 +10,27 @@ import (
 
 // HandleReload reloads the configuration file.
 func (c *Controller) HandleReload() (ipc.ReloadResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reload()
+}
+
+// reload loads the configuration file and restarts the watcher with its
+// rules. If the file can't be loaded, the current rules keep running and the
+// error is reported by status until a later reload succeeds.
+// Must be called with mu held.
+func (c *Controller) reload() (ipc.ReloadResult, error) {
 	cfg, err := config.LoadWithFs(c.configPath, c.fs)
 	if err != nil {
+		c.configErr = err
 		return ipc.ReloadResult{}, fmt.Errorf("failed to load config: %w", err)
 	}
+	c.configErr = nil
 
 	wasEnabled := c.watcher != nil
 
 	c.rules = cfg.Rules
-	c.debounce = cfg.Daemon.Debounce
+	c.daemon = cfg.Daemon
 
 	// Restart watcher with new config if it was running
 	if wasEnabled {
@@ -4,6 +4,9 @@ import "github.com/prettymuchbryce/autotidy/internal/ipc"
 
 // HandleStatus returns the current daemon status.
 func (c *Controller) HandleStatus() ipc.StatusData {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	ruleStatuses := make([]ipc.RuleStatus, len(c.rules))
 	for i, rule := range c.rules {
 		rs := ipc.RuleStatus{
@@ -11,12 +14,18 @@ func (c *Controller) HandleStatus() ipc.StatusData {
 			Enabled:   rule.IsEnabled(),
 			Locations: rule.Locations,
 		}
-		if ruleState := c.state.GetRuleState(rule.Name); ruleState != nil {
+		if ruleState := c.state.GetRuleState(rule.Name); ruleState != nil && !ruleState.LastRunAt.IsZero() {
 			rs.LastRunAt = &ruleState.LastRunAt
 			rs.LastDuration = &ruleState.LastDuration
 			rs.FilesProcessed = &ruleState.FilesProcessed
 			rs.ErrorCount = &ruleState.ErrorCount
 		}
+		if rule.Schedule != nil {
+			rs.Schedule = rule.Schedule.String()
+			if next, ok := c.state.NextRun(rule.Name, rs.Schedule); ok && rule.IsEnabled() && c.watcher != nil {
+				rs.NextRunAt = &next
+			}
+		}
 		ruleStatuses[i] = rs
 	}
 
@@ -25,9 +34,15 @@ func (c *Controller) HandleStatus() ipc.StatusData {
 		watchCount = c.watcher.WatchCount()
 	}
 
+	var configError string
+	if c.configErr != nil {
+		configError = c.configErr.Error()
+	}
+
 	return ipc.StatusData{
 		ConfigPath:  c.configPath,
-		ConfigValid: true,
+		ConfigValid: c.configErr == nil,
+		ConfigError: configError,
 		Enabled:     c.watcher != nil,
 		WatchCount:  watchCount,
 		Rules:       ruleStatuses,
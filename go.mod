@@ -14,6 +14,7 @@ require (
 	github.com/itchyny/timefmt-go v0.1.7
 	github.com/spf13/afero v1.15.0
 	github.com/spf13/cobra v1.10.2
+	github.com/ulikunitz/xz v0.5.15
 	github.com/xlab/treeprint v1.2.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -1,6 +1,11 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/spf13/afero"
@@ -18,7 +23,12 @@ type Config struct {
 
 // DaemonConfig represents daemon-specific configuration.
 type DaemonConfig struct {
-	Debounce time.Duration `yaml:"debounce"`
+	Debounce        time.Duration `yaml:"debounce"`
+	WaitUntilStable time.Duration `yaml:"wait_until_stable"` // Default for rules, 0 disables
+	Incremental     bool          `yaml:"incremental"`       // Only evaluate changed paths between full sweeps
+	RescanInterval  time.Duration `yaml:"rescan_interval"`   // Time between full sweeps, 0 disables
+	PollInterval    time.Duration `yaml:"poll_interval"`     // Default for rules with watch: poll
+	PollPaths       []string      `yaml:"poll_paths"`        // Directories polled for every rule
 }
 
 // LoggingConfig represents logging configuration.
@@ -29,7 +39,10 @@ type LoggingConfig struct {
 // DefaultDaemonConfig returns the default daemon configuration.
 func DefaultDaemonConfig() DaemonConfig {
 	return DaemonConfig{
-		Debounce: 500 * time.Millisecond,
+		Debounce:       500 * time.Millisecond,
+		Incremental:    true,
+		RescanInterval: time.Hour,
+		PollInterval:   10 * time.Second,
 	}
 }
 
@@ -62,9 +75,99 @@ func LoadWithFs(path string, afs afero.Fs) (*Config, error) {
 		Logging: DefaultLoggingConfig(),
 	}
 
-	if err := yaml.Unmarshal(data, config); err != nil {
+	if err := decodeConfig(data, config); err != nil {
+		var perr *rules.PositionError
+		if errors.As(err, &perr) {
+			return nil, fmt.Errorf("line %d, column %d: %w", perr.Line, perr.Column, err)
+		}
+		return nil, err
+	}
+
+	if err := config.Daemon.normalize(); err != nil {
 		return nil, err
 	}
 
 	return config, nil
 }
+
+// decodeConfig decodes the YAML in data into config. Unknown top-level keys
+// and unknown keys in the daemon and logging sections are logged and skipped
+// instead of failing the load, so a typo in a setting doesn't stop the daemon;
+// `autotidy validate` reports them as errors. Unknown keys in rules still fail,
+// since a rule running with a misspelled option could move files the wrong
+// way. An empty document leaves config unchanged.
+func decodeConfig(data []byte, config *Config) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+
+	// Decoding stops at the first unknown key, so drop it and start over
+	for {
+		decoded := *config
+		err := rules.DecodeKnownFields(root, &decoded)
+		var unknown *rules.UnknownFieldError
+		var perr *rules.PositionError
+		if err == nil {
+			*config = decoded
+			return nil
+		}
+		if !errors.As(err, &unknown) || !errors.As(err, &perr) || !removeSettingAt(root, perr.Line, perr.Column) {
+			return err
+		}
+		slog.Warn("ignoring unknown config key", "line", perr.Line, "column", perr.Column, "error", err)
+	}
+}
+
+// removeSettingAt removes the entry whose key is at line and column from the
+// top level of the config or one of its sections other than rules, and
+// reports whether it found one.
+func removeSettingAt(root *yaml.Node, line, column int) bool {
+	if root.Kind != yaml.MappingNode {
+		return false
+	}
+	if removeKeyAt(root, line, column) {
+		return true
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "rules" && removeKeyAt(root.Content[i+1], line, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeKeyAt removes the entry whose key is at line and column from the
+// mapping node, and reports whether it found one.
+func removeKeyAt(node *yaml.Node, line, column int) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if key.Line == line && key.Column == column {
+			node.Content = slices.Delete(node.Content, i, i+2)
+			return true
+		}
+	}
+	return false
+}
+
+// normalize expands and validates the daemon's paths and intervals.
+func (d *DaemonConfig) normalize() error {
+	if d.PollInterval < rules.MinPollInterval {
+		return fmt.Errorf("daemon.poll_interval must be at least %s, got %s", rules.MinPollInterval, d.PollInterval)
+	}
+	for i, path := range d.PollPaths {
+		path = pathutil.ExpandTilde(path)
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("daemon.poll_paths must be absolute paths: %s", path)
+		}
+		d.PollPaths[i] = filepath.Clean(path)
+	}
+	return nil
+}
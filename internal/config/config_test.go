@@ -2,6 +2,8 @@ package config
 
 import (
 	"bytes"
+	"slices"
+	"strings"
 	"testing"
 	"text/template"
 	"time"
@@ -32,6 +34,7 @@ rules:
       - {{.DownloadsPath}}
 daemon:
   debounce: 1s
+  wait_until_stable: 5s
 logging:
   level: debug
 `, map[string]string{"DownloadsPath": downloadsPath})
@@ -54,6 +57,10 @@ logging:
 		t.Errorf("expected debounce 1s, got %v", cfg.Daemon.Debounce)
 	}
 
+	if cfg.Daemon.WaitUntilStable != 5*time.Second {
+		t.Errorf("expected wait_until_stable 5s, got %v", cfg.Daemon.WaitUntilStable)
+	}
+
 	if cfg.Logging.Level != "debug" {
 		t.Errorf("expected logging level 'debug', got %q", cfg.Logging.Level)
 	}
@@ -114,6 +121,135 @@ rules:
 	}
 }
 
+func TestLoadWithFs_UnknownSetting(t *testing.T) {
+	configPath := testutil.Path("/", "config.yaml")
+	downloadsPath := testutil.Path("/", "downloads")
+
+	fs := afero.NewMemMapFs()
+	configYAML := renderYAML(t, `
+rule:
+  - name: typo
+rules:
+  - name: test
+    locations: [{{.DownloadsPath}}]
+daemon:
+  debonce: 1s
+  wait_until_stable: 5s
+logging:
+  levle: debug
+`, map[string]string{"DownloadsPath": downloadsPath})
+	afero.WriteFile(fs, configPath, []byte(configYAML), 0644)
+
+	// Unknown settings are skipped, so the rest of the config still loads
+	cfg, err := LoadWithFs(configPath, fs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cfg.Rules) != 1 || cfg.Rules[0].Name != "test" {
+		t.Fatalf("expected rule 'test', got %+v", cfg.Rules)
+	}
+	if cfg.Daemon.Debounce != DefaultDaemonConfig().Debounce {
+		t.Errorf("expected default debounce, got %v", cfg.Daemon.Debounce)
+	}
+	if cfg.Daemon.WaitUntilStable != 5*time.Second {
+		t.Errorf("expected wait_until_stable 5s, got %v", cfg.Daemon.WaitUntilStable)
+	}
+}
+
+func TestLoadWithFs_UnknownRuleField(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		errContains string
+	}{
+		{
+			name:        "rule",
+			config:      "rules:\n  - name: test\n    recursve: true\n",
+			errContains: `line 3, column 5: unknown field "recursve"`,
+		},
+		{
+			name:        "action",
+			config:      "rules:\n  - name: test\n    actions:\n      - move: {dest: /sorted, on_conflic: skip}\n",
+			errContains: `unknown field "on_conflic"`,
+		},
+		{
+			name:        "filter",
+			config:      "rules:\n  - name: test\n    filters:\n      - name: {glob: '*.pdf', regx: '^a'}\n",
+			errContains: `unknown field "regx"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := testutil.Path("/", "config.yaml")
+			fs := afero.NewMemMapFs()
+			afero.WriteFile(fs, configPath, []byte(tt.config), 0644)
+
+			// A rule running with a misspelled option could act on the wrong files
+			_, err := LoadWithFs(configPath, fs)
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", tt.errContains)
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %v, want containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestLoadWithFs_PollPaths(t *testing.T) {
+	configPath := testutil.Path("/", "config.yaml")
+	scansPath := testutil.Path("/", "mnt", "scans")
+
+	tests := []struct {
+		name          string
+		daemon        string
+		expectedPaths []string
+		errContains   string
+	}{
+		{
+			name:          "paths are cleaned",
+			daemon:        "poll_paths: ['" + scansPath + "/']\n  poll_interval: 30s",
+			expectedPaths: []string{scansPath},
+		},
+		{
+			name:        "relative path",
+			daemon:      "poll_paths: [scans]",
+			errContains: "daemon.poll_paths must be absolute paths: scans",
+		},
+		{
+			name:        "interval too short",
+			daemon:      "poll_interval: 100ms",
+			errContains: "daemon.poll_interval must be at least 1s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			afero.WriteFile(fs, configPath, []byte("daemon:\n  "+tt.daemon+"\n"), 0644)
+
+			cfg, err := LoadWithFs(configPath, fs)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(cfg.Daemon.PollPaths, tt.expectedPaths) {
+				t.Errorf("poll_paths = %v, want %v", cfg.Daemon.PollPaths, tt.expectedPaths)
+			}
+			if cfg.Daemon.PollInterval != 30*time.Second {
+				t.Errorf("poll_interval = %v, want 30s", cfg.Daemon.PollInterval)
+			}
+		})
+	}
+}
+
 // Note: Fs is no longer set by LoadWithFs - it's set by cmd/run.go
 // based on whether --dry-run is specified.
 
@@ -198,6 +334,15 @@ func TestDefaultDaemonConfig(t *testing.T) {
 	if cfg.Debounce != 500*time.Millisecond {
 		t.Errorf("expected debounce 500ms, got %v", cfg.Debounce)
 	}
+	if !cfg.Incremental {
+		t.Error("expected incremental to default to true")
+	}
+	if cfg.RescanInterval != time.Hour {
+		t.Errorf("expected rescan interval 1h, got %v", cfg.RescanInterval)
+	}
+	if cfg.PollInterval != 10*time.Second {
+		t.Errorf("expected poll interval 10s, got %v", cfg.PollInterval)
+	}
 }
 
 func TestDefaultLoggingConfig(t *testing.T) {
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+
+	"github.com/prettymuchbryce/autotidy/internal/pathutil"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+)
+
+// Problem is an issue Validate found in a config file. Line and Column are 0
+// when the YAML parser doesn't report them.
+type Problem struct {
+	Line    int
+	Column  int
+	Message string
+	Warning bool // The config loads, but likely doesn't do what was intended
+}
+
+// linePattern matches the line yaml.v3 includes in its error messages.
+var linePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Validate checks the config file at path the way LoadWithFs does, except
+// that it reports the problems of every rule instead of stopping at the first
+// one. It also checks for mistakes that load fine, like duplicate rule names.
+// The returned error is only set if the file can't be read.
+func Validate(path string, afs afero.Fs) ([]Problem, error) {
+	data, err := afero.ReadFile(afs, pathutil.ExpandTilde(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return problemsFrom(&doc, err), nil
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return []Problem{{Line: root.Line, Column: root.Column, Message: "config must be a mapping"}}, nil
+	}
+
+	problems := validateSections(root)
+
+	rulesNode := mappingValue(root, "rules")
+	if rulesNode == nil {
+		return problems, nil
+	}
+	if rulesNode.Kind != yaml.SequenceNode {
+		return append(problems, Problem{Line: rulesNode.Line, Column: rulesNode.Column, Message: "rules must be a list"}), nil
+	}
+
+	problems = append(problems, checkRuleNames(rulesNode.Content)...)
+
+	var loaded []loadedRule
+	for _, node := range rulesNode.Content {
+		var rule rules.Rule
+		if err := node.Decode(&rule); err != nil {
+			problems = append(problems, problemsFrom(node, err)...)
+			continue
+		}
+		loaded = append(loaded, loadedRule{rule: &rule, node: node})
+	}
+
+	problems = append(problems, checkDestinations(loaded)...)
+	problems = append(problems, checkOverlaps(loaded)...)
+
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return problems, nil
+}
+
+// loadedRule is a rule that decoded without errors, with the node it came
+// from for reporting positions.
+type loadedRule struct {
+	rule *rules.Rule
+	node *yaml.Node
+}
+
+// validateSections decodes the top-level sections other than rules.
+func validateSections(root *yaml.Node) []Problem {
+	sections := *root
+	sections.Content = nil
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "rules" {
+			sections.Content = append(sections.Content, root.Content[i], root.Content[i+1])
+		}
+	}
+
+	config := Config{
+		Daemon:  DefaultDaemonConfig(),
+		Logging: DefaultLoggingConfig(),
+	}
+	if err := rules.DecodeKnownFields(&sections, &config); err != nil {
+		return problemsFrom(root, err)
+	}
+	if err := config.Daemon.normalize(); err != nil {
+		node := mappingValue(root, "daemon")
+		if node == nil {
+			node = root
+		}
+		return problemsFrom(node, err)
+	}
+	return nil
+}
+
+// checkRuleNames reports rules sharing a name, since rules are told apart
+// by name in the daemon's state and commands. Rules that fail to decode are
+// checked too, so fixing them doesn't reveal another error.
+func checkRuleNames(ruleNodes []*yaml.Node) []Problem {
+	var problems []Problem
+	seen := make(map[string]*yaml.Node)
+	for _, ruleNode := range ruleNodes {
+		node := mappingValue(ruleNode, "name")
+		if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" {
+			continue
+		}
+		if first, ok := seen[node.Value]; ok {
+			problems = append(problems, Problem{
+				Line:    node.Line,
+				Column:  node.Column,
+				Message: fmt.Sprintf("duplicate rule name %q, also used on line %d", node.Value, first.Line),
+			})
+			continue
+		}
+		seen[node.Value] = node
+	}
+	return problems
+}
+
+// checkDestinations reports actions with a relative destination, which would
+// be resolved against the daemon's working directory. Destinations starting
+// with a variable are skipped, since it may expand to an absolute path.
+func checkDestinations(loaded []loadedRule) []Problem {
+	var problems []Problem
+	for _, l := range loaded {
+		actionsNode := mappingValue(l.node, "actions")
+		for i, a := range l.rule.Actions {
+			d, ok := a.Inner.(rules.Destined)
+			if !ok {
+				continue
+			}
+			dest := d.Destination().ExpandTilde().String()
+			if dest == "" || strings.HasPrefix(dest, "${") || filepath.IsAbs(dest) {
+				continue
+			}
+			node := actionsNode.Content[i]
+			problems = append(problems, Problem{
+				Line:    node.Line,
+				Column:  node.Column,
+				Message: fmt.Sprintf("action %q: destination %q must be an absolute path or start with ~", a.Name, d.Destination()),
+			})
+		}
+	}
+	return problems
+}
+
+// checkOverlaps warns about enabled rules that move files from the same
+// location with the same filters, or where either has no filters, so files
+// both match are only ever seen by the first to run.
+func checkOverlaps(loaded []loadedRule) []Problem {
+	var problems []Problem
+	for i, a := range loaded {
+		if !a.rule.IsEnabled() || !hasAction(a.rule, "move") {
+			continue
+		}
+		for _, b := range loaded[:i] {
+			if !b.rule.IsEnabled() || !hasAction(b.rule, "move") {
+				continue
+			}
+			if hasFilters(a.node) && hasFilters(b.node) && !sameFilters(a.node, b.node) {
+				continue
+			}
+			if loc, ok := sharedLocation(a.rule, b.rule); ok {
+				problems = append(problems, Problem{
+					Line:    a.node.Line,
+					Column:  a.node.Column,
+					Message: fmt.Sprintf("rules %q and %q move the same files in %s", b.rule.Name, a.rule.Name, loc),
+					Warning: true,
+				})
+				break
+			}
+		}
+	}
+	return problems
+}
+
+// hasAction reports whether the rule has an action with the given name.
+func hasAction(rule *rules.Rule, name string) bool {
+	for _, a := range rule.Actions {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFilters reports whether the rule node has any filters.
+func hasFilters(node *yaml.Node) bool {
+	filters := mappingValue(node, "filters")
+	return filters != nil && len(filters.Content) > 0
+}
+
+// sameFilters reports whether two rule nodes have identical filters, or none.
+func sameFilters(a, b *yaml.Node) bool {
+	encode := func(node *yaml.Node) string {
+		filters := mappingValue(node, "filters")
+		if filters == nil {
+			return ""
+		}
+		data, err := yaml.Marshal(filters)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+	return encode(a) == encode(b)
+}
+
+// sharedLocation returns a directory whose files both rules act on.
+func sharedLocation(a, b *rules.Rule) (string, bool) {
+	for _, la := range a.Locations {
+		for _, lb := range b.Locations {
+			switch {
+			case la == lb:
+				return la, true
+			case a.IsRecursive() && pathutil.IsBelow(la, lb):
+				return lb, true
+			case b.IsRecursive() && pathutil.IsBelow(lb, la):
+				return la, true
+			}
+		}
+	}
+	return "", false
+}
+
+// mappingValue returns the value of key in the mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// problemsFrom converts a decoding error into problems, using the position
+// the error carries, or node's if it has none.
+func problemsFrom(node *yaml.Node, err error) []Problem {
+	var perr *rules.PositionError
+	if errors.As(err, &perr) {
+		return []Problem{{Line: perr.Line, Column: perr.Column, Message: err.Error()}}
+	}
+
+	// yaml.v3 reports type errors for several fields at once, with lines only
+	var terr *yaml.TypeError
+	if errors.As(err, &terr) {
+		var problems []Problem
+		for _, msg := range terr.Errors {
+			problems = append(problems, problemAtLine(node, msg))
+		}
+		return problems
+	}
+	return []Problem{problemAtLine(node, err.Error())}
+}
+
+// problemAtLine returns a problem for a yaml.v3 message, using the line it
+// starts with, or node's position if it has none.
+func problemAtLine(node *yaml.Node, msg string) Problem {
+	if m := linePattern.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return Problem{Line: line, Message: m[2]}
+	}
+	return Problem{Line: node.Line, Column: node.Column, Message: msg}
+}
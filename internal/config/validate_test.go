@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+
+	// Import for side effects (filter/action registration)
+	_ "github.com/prettymuchbryce/autotidy/internal/rules/actions"
+	_ "github.com/prettymuchbryce/autotidy/internal/rules/filters"
+)
+
+// formatProblems formats problems as "line:column: message" strings, with
+// warnings marked, for comparison.
+func formatProblems(problems []Problem) []string {
+	var result []string
+	for _, p := range problems {
+		s := fmt.Sprintf("%d:%d: %s", p.Line, p.Column, p.Message)
+		if p.Warning {
+			s = "warning " + s
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name:     "empty",
+			config:   "",
+			expected: nil,
+		},
+		{
+			name: "valid",
+			config: `
+rules:
+  - name: pdfs
+    locations: /downloads
+    filters:
+      - extension: pdf
+    actions:
+      - move: /documents/${ext}
+  - name: images
+    locations: /downloads
+    filters:
+      - extension: jpg
+    actions:
+      - move: ~/Pictures
+`,
+			expected: nil,
+		},
+		{
+			// yaml.v3 reports syntax errors with a line but no column
+			name:     "syntax error",
+			config:   "rules:\n  - name: [unclosed\n",
+			expected: []string{"1:0: did not find expected ',' or ']'"},
+		},
+		{
+			name: "every rule is checked",
+			config: `
+rules:
+  - name: first
+    locations: /downloads
+    actions:
+      - move:
+          dest: /documents
+          on_conflit: skip
+  - name: second
+    locations: /downloads
+    actions:
+      - move: {dest: /documents, on_conflict: replace}
+  - name: third
+    locations: /downloads
+    actions:
+      - rename: ${nam}${ext}
+daemon:
+  debonce: 1s
+`,
+			expected: []string{
+				`8:11: failed to deserialize action "move": unknown field "on_conflit", available: [dest on_conflict on_identical time_source]`,
+				`12:15: failed to deserialize action "move": invalid on_conflict "replace": must be rename_with_suffix, skip, overwrite, trash, skip_if_identical, keep_newer or keep_larger`,
+				`16:9: action "rename": unknown variable ${nam} in template "${nam}${ext}"`,
+				`18:3: unknown field "debonce", available: [debounce incremental poll_interval poll_paths rescan_interval wait_until_stable]`,
+			},
+		},
+		{
+			name: "duplicate names",
+			config: `
+rules:
+  - name: tidy
+    locations: /downloads
+    actions:
+      - delete
+  - name: tidy
+    locations: /desktop
+    actions:
+      - delete
+`,
+			expected: []string{`7:11: duplicate rule name "tidy", also used on line 3`},
+		},
+		{
+			name: "relative destinations",
+			config: `
+rules:
+  - name: tidy
+    locations: /downloads
+    actions:
+      - move: sorted/${ext}
+      - move: ${parent}/sorted
+      - archive: backups/%Y.zip
+`,
+			expected: []string{
+				`6:9: action "move": destination "sorted/${ext}" must be an absolute path or start with ~`,
+				`8:9: action "archive": destination "backups/%Y.zip" must be an absolute path or start with ~`,
+			},
+		},
+		{
+			name: "overlapping rules",
+			config: `
+rules:
+  - name: everything
+    locations: /downloads
+    recursive: true
+    actions:
+      - move: /archive
+  - name: sub
+    locations: /downloads/sub
+    actions:
+      - move: /sorted
+  - name: disabled
+    enabled: false
+    locations: /downloads
+    actions:
+      - move: /sorted
+  - name: filtered
+    locations: /downloads
+    filters:
+      - extension: pdf
+    actions:
+      - move: /sorted
+`,
+			expected: []string{
+				`warning 8:5: rules "everything" and "sub" move the same files in /downloads/sub`,
+				`warning 17:5: rules "everything" and "filtered" move the same files in /downloads`,
+			},
+		},
+		{
+			name: "rules with different filters",
+			config: `
+rules:
+  - name: documents
+    locations: /downloads
+    filters:
+      - extension: pdf
+    actions:
+      - move: /documents
+  - name: images
+    locations: /downloads
+    filters:
+      - extension: jpg
+    actions:
+      - move: /images
+`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := testutil.Path("/", "config.yaml")
+			fs := afero.NewMemMapFs()
+			afero.WriteFile(fs, configPath, []byte(tt.config), 0644)
+
+			problems, err := Validate(configPath, fs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := formatProblems(problems); !slices.Equal(got, tt.expected) {
+				t.Errorf("problems =\n  %q\nwant\n  %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidate_FileNotFound(t *testing.T) {
+	if _, err := Validate(testutil.Path("/", "nonexistent.yaml"), afero.NewMemMapFs()); err == nil {
+		t.Error("expected error for non-existent file")
+	}
+}
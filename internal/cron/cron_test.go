@@ -0,0 +1,95 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		expr        string
+		errContains string
+	}{
+		{name: "too few fields", expr: "0 3 * *", errContains: "must have 5 fields"},
+		{name: "too many fields", expr: "0 0 3 * * *", errContains: "must have 5 fields"},
+		{name: "value out of range", expr: "60 * * * *", errContains: "minute must be between 0 and 59"},
+		{name: "invalid value", expr: "* * * foo *", errContains: `invalid value "foo" in month field`},
+		{name: "reversed range", expr: "* 5-2 * * *", errContains: `invalid range "5-2"`},
+		{name: "zero step", expr: "*/0 * * * *", errContains: "invalid step"},
+		{name: "never matches", expr: "0 0 30 2 *", errContains: "never matches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if err == nil {
+				t.Fatalf("Parse(%q) expected error", tt.expr)
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Parse(%q) error = %v, want containing %q", tt.expr, err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2024, 1, 10, 14, 30, 20, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{name: "every minute", expr: "* * * * *", from: from, expected: time.Date(2024, 1, 10, 14, 31, 0, 0, time.UTC)},
+		{name: "later today", expr: "0 18 * * *", from: from, expected: time.Date(2024, 1, 10, 18, 0, 0, 0, time.UTC)},
+		{name: "tomorrow", expr: "0 3 * * *", from: from, expected: time.Date(2024, 1, 11, 3, 0, 0, 0, time.UTC)},
+		{name: "strictly after", expr: "30 14 * * *", from: time.Date(2024, 1, 10, 14, 30, 0, 0, time.UTC), expected: time.Date(2024, 1, 11, 14, 30, 0, 0, time.UTC)},
+		{name: "step", expr: "*/15 * * * *", from: from, expected: time.Date(2024, 1, 10, 14, 45, 0, 0, time.UTC)},
+		{name: "range with step", expr: "0 9-17/4 * * *", from: from, expected: time.Date(2024, 1, 10, 17, 0, 0, 0, time.UTC)},
+		{name: "list", expr: "0 1,13 * * *", from: from, expected: time.Date(2024, 1, 11, 1, 0, 0, 0, time.UTC)},
+		{name: "day of week name", expr: "0 0 * * sat", from: from, expected: time.Date(2024, 1, 13, 0, 0, 0, 0, time.UTC)},
+		{name: "sunday as 7", expr: "0 0 * * 7", from: from, expected: time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{name: "month name", expr: "0 0 1 mar *", from: from, expected: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "day of month or day of week", expr: "0 0 20 * mon", from: from, expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 0 29 2 *", from: from, expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "next year", expr: "@yearly", from: from, expected: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "hourly macro", expr: "@hourly", from: from, expected: time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.expr, err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.expected) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next_DaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	s, err := Parse("30 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clocks go back from 2:00 to 1:00 on this day, so 1:30 happens twice
+	from := time.Date(2024, 11, 3, 1, 45, 0, 0, loc)
+	for i := 0; i < 4; i++ {
+		next := s.Next(from)
+		if !next.After(from) {
+			t.Fatalf("Next(%s) = %s, want a later time", from, next)
+		}
+		from = next
+	}
+}
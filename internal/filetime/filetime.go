@@ -0,0 +1,54 @@
+// Package filetime looks up file timestamps, including the access, change
+// and creation times that os.FileInfo doesn't expose portably.
+package filetime
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/djherbis/times"
+)
+
+// Kind names one of a file's timestamps.
+type Kind string
+
+const (
+	Modified Kind = "modified" // Last content modification (mtime)
+	Accessed Kind = "accessed" // Last access (atime)
+	Changed  Kind = "changed"  // Last metadata change (ctime), not available on Windows
+	Created  Kind = "created"  // Creation or birth time, not recorded by every filesystem
+)
+
+// ErrUnavailable is returned by Stat when the platform or filesystem doesn't
+// record the requested timestamp.
+var ErrUnavailable = errors.New("not available")
+
+// Stat returns the timestamp of the given kind for the file at path.
+// Returns an error wrapping ErrUnavailable if the platform or filesystem
+// doesn't record it.
+func Stat(path string, kind Kind) (time.Time, error) {
+	t, err := times.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch kind {
+	case Modified:
+		return t.ModTime(), nil
+	case Accessed:
+		return t.AccessTime(), nil
+	case Changed:
+		if !t.HasChangeTime() {
+			return time.Time{}, fmt.Errorf("change time is %w for %s", ErrUnavailable, path)
+		}
+		return t.ChangeTime(), nil
+	case Created:
+		if !t.HasBirthTime() {
+			return time.Time{}, fmt.Errorf("creation time is %w for %s", ErrUnavailable, path)
+		}
+		return t.BirthTime(), nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown file time %q", kind)
+	}
+}
@@ -0,0 +1,70 @@
+package filetime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	mtime := time.Date(2019, time.July, 14, 9, 30, 0, 0, time.UTC)
+	atime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, atime, mtime); err != nil {
+		t.Fatalf("failed to set times: %v", err)
+	}
+
+	tests := []struct {
+		kind     Kind
+		expected time.Time
+	}{
+		{Modified, mtime},
+		{Accessed, atime},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			got, err := Stat(path, tt.kind)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Stat(%s) = %v, want %v", tt.kind, got, tt.expected)
+			}
+		})
+	}
+
+	// Change and creation times are set by the filesystem, when recorded at all
+	for _, kind := range []Kind{Changed, Created} {
+		t.Run(string(kind), func(t *testing.T) {
+			got, err := Stat(path, kind)
+			if err != nil {
+				if !errors.Is(err, ErrUnavailable) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if time.Since(got) > time.Hour {
+				t.Errorf("Stat(%s) = %v, expected a recent time", kind, got)
+			}
+		})
+	}
+}
+
+func TestStat_Errors(t *testing.T) {
+	if _, err := Stat(filepath.Join(t.TempDir(), "missing.txt"), Modified); !os.IsNotExist(err) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "file.txt")
+	os.WriteFile(path, []byte("test"), 0644)
+	if _, err := Stat(path, "taken"); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+}
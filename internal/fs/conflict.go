@@ -0,0 +1,113 @@
+package fs
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/spf13/afero"
+)
+
+// DecideConflict resolves a content-aware conflict mode into one of the basic
+// modes (skip, overwrite, rename_with_suffix) by comparing source and destination.
+// Returns the mode to apply and a human-readable reason for the decision.
+// Basic modes are returned unchanged with an empty reason.
+func DecideConflict(afs afero.Fs, mode ConflictMode, srcPath, destPath string) (ConflictMode, string, error) {
+	switch mode {
+	case ConflictSkipIfIdentical:
+		identical, err := SameContent(afs, srcPath, destPath)
+		if err != nil {
+			return "", "", err
+		}
+		if identical {
+			return ConflictSkip, "identical to destination", nil
+		}
+		return ConflictRenameWithSuffix, "", nil
+
+	case ConflictKeepNewer:
+		srcInfo, destInfo, err := statPair(afs, srcPath, destPath)
+		if err != nil {
+			return "", "", err
+		}
+		if srcInfo.ModTime().After(destInfo.ModTime()) {
+			return ConflictOverwrite, "replaced older destination", nil
+		}
+		return ConflictSkip, "destination is newer", nil
+
+	case ConflictKeepLarger:
+		srcInfo, destInfo, err := statPair(afs, srcPath, destPath)
+		if err != nil {
+			return "", "", err
+		}
+		if srcInfo.Size() > destInfo.Size() {
+			return ConflictOverwrite, "replaced smaller destination", nil
+		}
+		return ConflictSkip, "destination is larger", nil
+
+	default:
+		return mode, "", nil
+	}
+}
+
+// SameContent reports whether two regular files have identical contents.
+// Sizes are compared first so differing files are usually rejected without reading.
+// Directories are never considered identical.
+func SameContent(afs afero.Fs, a, b string) (bool, error) {
+	aInfo, bInfo, err := statPair(afs, a, b)
+	if err != nil {
+		return false, err
+	}
+	if aInfo.IsDir() || bInfo.IsDir() || aInfo.Size() != bInfo.Size() {
+		return false, nil
+	}
+
+	aFile, err := afs.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer aFile.Close()
+
+	bFile, err := afs.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer bFile.Close()
+
+	const chunkSize = 64 * 1024
+	aBuf := make([]byte, chunkSize)
+	bBuf := make([]byte, chunkSize)
+	for {
+		aN, aErr := io.ReadFull(aFile, aBuf)
+		bN, bErr := io.ReadFull(bFile, bBuf)
+		if !bytes.Equal(aBuf[:aN], bBuf[:bN]) {
+			return false, nil
+		}
+		aDone := aErr == io.EOF || aErr == io.ErrUnexpectedEOF
+		bDone := bErr == io.EOF || bErr == io.ErrUnexpectedEOF
+		if aDone && bDone {
+			return true, nil
+		}
+		if aErr != nil && !aDone {
+			return false, aErr
+		}
+		if bErr != nil && !bDone {
+			return false, bErr
+		}
+		if aDone != bDone {
+			return false, nil
+		}
+	}
+}
+
+// statPair stats two paths, returning the first error encountered.
+func statPair(afs afero.Fs, a, b string) (os.FileInfo, os.FileInfo, error) {
+	aInfo, err := afs.Stat(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	bInfo, err := afs.Stat(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aInfo, bInfo, nil
+}
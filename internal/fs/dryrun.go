@@ -3,6 +3,9 @@ package fs
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/spf13/afero"
 )
@@ -11,6 +14,9 @@ import (
 // Uses CopyOnWriteFs so operations work correctly in memory.
 type DryRunFileSystem struct {
 	afero.Fs
+
+	mu      sync.Mutex
+	renamed map[string]string // New path to old path, for Origin
 }
 
 // MkdirAll delegates to the CoW filesystem.
@@ -46,9 +52,54 @@ func (d *DryRunFileSystem) Rename(oldname, newname string) error {
 	}
 
 	if srcInfo.IsDir() {
-		return copyDir(d.Fs, oldname, newname)
+		err = copyDir(d.Fs, oldname, newname)
+	} else {
+		err = copyFile(d.Fs, oldname, newname, srcInfo.Mode())
+	}
+	if err != nil {
+		return err
+	}
+
+	// Record where everything that was copied came from
+	oldname, newname = filepath.Clean(oldname), filepath.Clean(newname)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.renamed == nil {
+		d.renamed = map[string]string{}
 	}
-	return copyFile(d.Fs, oldname, newname, srcInfo.Mode())
+	return afero.Walk(d.Fs, newname, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		d.renamed[path] = d.origin(oldname + strings.TrimPrefix(path, newname))
+		return nil
+	})
+}
+
+// Origin returns the path a file has on disk, before it was renamed in the
+// dry run, on its own or with a directory containing it.
+func (d *DryRunFileSystem) Origin(path string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.origin(filepath.Clean(path))
+}
+
+// origin implements Origin. Renames are recorded with the origin of their
+// source, so a single lookup covers a chain of renames.
+func (d *DryRunFileSystem) origin(path string) string {
+	if old, ok := d.renamed[path]; ok {
+		return old
+	}
+	return path
+}
+
+// Origin returns the path a file has on disk. It differs from path only for
+// files renamed in a dry run, which are still at their original path.
+func Origin(afs afero.Fs, path string) string {
+	if d, ok := afs.(*DryRunFileSystem); ok {
+		return d.Origin(path)
+	}
+	return path
 }
 
 // Copy performs the copy in memory so subsequent actions work.
@@ -82,6 +133,11 @@ func (d *DryRunFileSystem) Trash(path string) error {
 
 // ResolveConflict handles destination file conflicts in dry-run mode.
 func (d *DryRunFileSystem) ResolveConflict(mode ConflictMode, srcPath, destPath string) (string, bool, error) {
+	mode, _, err := DecideConflict(d.Fs, mode, srcPath, destPath)
+	if err != nil {
+		return "", false, err
+	}
+
 	switch mode {
 	case ConflictRenameWithSuffix:
 		newPath := d.findAvailableSuffixedPath(destPath)
@@ -95,6 +151,10 @@ func (d *DryRunFileSystem) ResolveConflict(mode ConflictMode, srcPath, destPath
 		return destPath, true, nil
 
 	case ConflictTrash:
+		// Trash is a no-op, so the destination is simply overwritten in memory
+		if err := d.Trash(destPath); err != nil {
+			return "", false, err
+		}
 		return destPath, true, nil
 
 	default:
@@ -16,6 +16,9 @@ const (
 	ConflictSkip             ConflictMode = "skip"               // Skip the file, don't proceed
 	ConflictOverwrite        ConflictMode = "overwrite"          // Overwrite the destination file
 	ConflictTrash            ConflictMode = "trash"              // Move destination file to trash
+	ConflictSkipIfIdentical  ConflictMode = "skip_if_identical"  // Skip if contents match, otherwise rename with suffix
+	ConflictKeepNewer        ConflictMode = "keep_newer"         // Overwrite if the source is newer, otherwise skip
+	ConflictKeepLarger       ConflictMode = "keep_larger"        // Overwrite if the source is larger, otherwise skip
 )
 
 // FileSystem extends afero.Fs with autotidy-specific operations.
@@ -36,6 +39,7 @@ type FileSystem interface {
 	// Returns (newDestPath, proceed, err) - if proceed is true, caller should continue
 	// using newDestPath as the destination. For most modes newDestPath equals destPath,
 	// but for RenameWithSuffix it may be different (e.g., file_2.txt).
+	// For Trash, the existing destination is moved to the trash before proceeding.
 	ResolveConflict(mode ConflictMode, srcPath, destPath string) (string, bool, error)
 }
 
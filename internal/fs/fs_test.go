@@ -1,7 +1,10 @@
 package fs
 
 import (
+	"errors"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/prettymuchbryce/autotidy/internal/testutil"
 	"github.com/spf13/afero"
@@ -207,12 +210,30 @@ func TestResolveConflict_Overwrite(t *testing.T) {
 
 func TestResolveConflict_Trash(t *testing.T) {
 	filesystem := NewMem()
+	dir := testutil.Path("/", "dest")
+	filesystem.MkdirAll(dir, 0755)
+
 	srcFile := testutil.Path("/", "src", "file.txt")
-	destFile := testutil.Path("/", "dest", "file.txt")
+	destFile := testutil.Path(dir, "file.txt")
+	afero.WriteFile(filesystem, destFile, []byte("old content"), 0644)
 
-	_, _, err := filesystem.ResolveConflict(ConflictTrash, srcFile, destFile)
-	if err == nil {
-		t.Error("expected error for trash mode (not implemented)")
+	newPath, proceed, err := filesystem.ResolveConflict(ConflictTrash, srcFile, destFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !proceed {
+		t.Error("expected proceed=true for trash mode")
+	}
+
+	if newPath != destFile {
+		t.Errorf("newPath = %q, want %q", newPath, destFile)
+	}
+
+	// Destination file should be trashed (removed in MemFileSystem)
+	exists, _ := afero.Exists(filesystem, destFile)
+	if exists {
+		t.Error("destination file should be trashed after trash resolve")
 	}
 }
 
@@ -226,3 +247,281 @@ func TestResolveConflict_UnknownMode(t *testing.T) {
 		t.Error("expected error for unknown mode")
 	}
 }
+
+func TestTrashLinux_Restore(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testutil.Path("/", "data"))
+
+	filesystem := NewMem()
+	dir := testutil.Path("/", "home", "downloads")
+	path := testutil.Path(dir, "file.txt")
+	filesystem.MkdirAll(dir, 0755)
+	afero.WriteFile(filesystem, path, []byte("content"), 0644)
+
+	if err := trashLinux(filesystem, path); err != nil {
+		t.Fatalf("trash failed: %v", err)
+	}
+	if exists, _ := afero.Exists(filesystem, path); exists {
+		t.Fatal("file should be in trash")
+	}
+
+	if err := restoreLinux(filesystem, path); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	content, err := afero.ReadFile(filesystem, path)
+	if err != nil {
+		t.Fatalf("restored file missing: %v", err)
+	}
+	if string(content) != "content" {
+		t.Errorf("content = %q, want %q", content, "content")
+	}
+
+	infoPath := testutil.Path("/", "data", "Trash", "info", "file.txt.trashinfo")
+	if exists, _ := afero.Exists(filesystem, infoPath); exists {
+		t.Error("trashinfo file should be removed after restore")
+	}
+
+	// Nothing left to restore
+	filesystem.Remove(path)
+	if err := restoreLinux(filesystem, path); err == nil {
+		t.Error("expected error when no trash entry exists")
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	filesystem := NewMem()
+	dir := testutil.Path("/", "dir")
+	filesystem.MkdirAll(dir, 0755)
+
+	a := testutil.Path(dir, "a.txt")
+	b := testutil.Path(dir, "b.txt")
+	c := testutil.Path(dir, "c.txt")
+	d := testutil.Path(dir, "d.txt")
+	afero.WriteFile(filesystem, a, []byte("same content"), 0644)
+	afero.WriteFile(filesystem, b, []byte("same content"), 0644)
+	afero.WriteFile(filesystem, c, []byte("diff content"), 0644)
+	afero.WriteFile(filesystem, d, []byte("longer content"), 0644)
+
+	tests := []struct {
+		name     string
+		a, b     string
+		expected bool
+	}{
+		{"identical files", a, b, true},
+		{"same size different content", a, c, false},
+		{"different size", a, d, false},
+		{"directory", dir, a, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			same, err := SameContent(filesystem, tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if same != tt.expected {
+				t.Errorf("SameContent = %v, want %v", same, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecideConflict(t *testing.T) {
+	dir := testutil.Path("/", "dir")
+	src := testutil.Path(dir, "src.txt")
+	dest := testutil.Path(dir, "dest.txt")
+	now := time.Now()
+
+	tests := []struct {
+		name         string
+		mode         ConflictMode
+		srcContent   string
+		destContent  string
+		srcAge       time.Duration
+		destAge      time.Duration
+		expectedMode ConflictMode
+	}{
+		{"identical skips", ConflictSkipIfIdentical, "same", "same", 0, 0, ConflictSkip},
+		{"different renames", ConflictSkipIfIdentical, "one", "two", 0, 0, ConflictRenameWithSuffix},
+		{"newer source overwrites", ConflictKeepNewer, "a", "b", time.Hour, 2 * time.Hour, ConflictOverwrite},
+		{"older source skips", ConflictKeepNewer, "a", "b", 2 * time.Hour, time.Hour, ConflictSkip},
+		{"larger source overwrites", ConflictKeepLarger, "larger", "small", 0, 0, ConflictOverwrite},
+		{"smaller source skips", ConflictKeepLarger, "small", "larger", 0, 0, ConflictSkip},
+		{"equal size skips", ConflictKeepLarger, "same", "size", 0, 0, ConflictSkip},
+		{"basic mode unchanged", ConflictOverwrite, "a", "b", 0, 0, ConflictOverwrite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filesystem := NewMem()
+			filesystem.MkdirAll(dir, 0755)
+			afero.WriteFile(filesystem, src, []byte(tt.srcContent), 0644)
+			afero.WriteFile(filesystem, dest, []byte(tt.destContent), 0644)
+			filesystem.Chtimes(src, now, now.Add(-tt.srcAge))
+			filesystem.Chtimes(dest, now, now.Add(-tt.destAge))
+
+			mode, _, err := DecideConflict(filesystem, tt.mode, src, dest)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mode != tt.expectedMode {
+				t.Errorf("mode = %q, want %q", mode, tt.expectedMode)
+			}
+		})
+	}
+}
+
+func TestResolveConflict_SkipIfIdenticalDiffers(t *testing.T) {
+	filesystem := NewMem()
+	dir := testutil.Path("/", "dest")
+	filesystem.MkdirAll(dir, 0755)
+
+	srcFile := testutil.Path("/", "src", "file.txt")
+	destFile := testutil.Path(dir, "file.txt")
+	filesystem.MkdirAll(testutil.Path("/", "src"), 0755)
+	afero.WriteFile(filesystem, srcFile, []byte("new"), 0644)
+	afero.WriteFile(filesystem, destFile, []byte("old"), 0644)
+
+	newPath, proceed, err := filesystem.ResolveConflict(ConflictSkipIfIdentical, srcFile, destFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !proceed {
+		t.Error("expected proceed=true when contents differ")
+	}
+
+	expected := testutil.Path(dir, "file_2.txt")
+	if newPath != expected {
+		t.Errorf("newPath = %q, want %q", newPath, expected)
+	}
+}
+
+func TestMoveAcrossDevices_File(t *testing.T) {
+	filesystem := NewMem()
+	src := testutil.Path("/", "src", "file.txt")
+	dest := testutil.Path("/", "dest", "file.txt")
+	filesystem.MkdirAll(testutil.Path("/", "src"), 0755)
+	filesystem.MkdirAll(testutil.Path("/", "dest"), 0755)
+	afero.WriteFile(filesystem, src, []byte("content"), 0600)
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	filesystem.Chtimes(src, mtime, mtime)
+
+	if err := moveAcrossDevices(filesystem, src, dest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if exists, _ := afero.Exists(filesystem, src); exists {
+		t.Error("source should be removed")
+	}
+	content, _ := afero.ReadFile(filesystem, dest)
+	if string(content) != "content" {
+		t.Errorf("content = %q, want %q", content, "content")
+	}
+
+	info, err := filesystem.Stat(dest)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), mtime)
+	}
+
+	partial := testutil.Path("/", "dest", ".file.txt"+partialSuffix)
+	if exists, _ := afero.Exists(filesystem, partial); exists {
+		t.Error("partial copy should not remain")
+	}
+}
+
+func TestMoveAcrossDevices_Directory(t *testing.T) {
+	filesystem := NewMem()
+	src := testutil.Path("/", "src", "photos")
+	dest := testutil.Path("/", "dest", "photos")
+	filesystem.MkdirAll(testutil.Path(src, "2024"), 0755)
+	filesystem.MkdirAll(testutil.Path("/", "dest"), 0755)
+	afero.WriteFile(filesystem, testutil.Path(src, "a.jpg"), []byte("a"), 0644)
+	afero.WriteFile(filesystem, testutil.Path(src, "2024", "b.jpg"), []byte("b"), 0644)
+
+	if err := moveAcrossDevices(filesystem, src, dest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if exists, _ := afero.Exists(filesystem, src); exists {
+		t.Error("source directory should be removed")
+	}
+	for _, p := range []string{testutil.Path(dest, "a.jpg"), testutil.Path(dest, "2024", "b.jpg")} {
+		if exists, _ := afero.Exists(filesystem, p); !exists {
+			t.Errorf("expected %s to exist", p)
+		}
+	}
+}
+
+// failingChtimesFs fails metadata updates to simulate an error mid-copy.
+type failingChtimesFs struct {
+	afero.Fs
+}
+
+func (f failingChtimesFs) Chtimes(name string, atime, mtime time.Time) error {
+	return errors.New("chtimes failed")
+}
+
+func TestMoveAcrossDevices_CleansUpOnFailure(t *testing.T) {
+	mem := NewMem()
+	filesystem := failingChtimesFs{Fs: mem}
+	src := testutil.Path("/", "src", "file.txt")
+	dest := testutil.Path("/", "dest", "file.txt")
+	mem.MkdirAll(testutil.Path("/", "src"), 0755)
+	mem.MkdirAll(testutil.Path("/", "dest"), 0755)
+	afero.WriteFile(mem, src, []byte("content"), 0644)
+
+	if err := moveAcrossDevices(filesystem, src, dest); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if exists, _ := afero.Exists(mem, src); !exists {
+		t.Error("source should be left in place")
+	}
+	entries, _ := afero.ReadDir(mem, testutil.Path("/", "dest"))
+	if len(entries) != 0 {
+		t.Errorf("expected destination directory to be empty, got %d entries", len(entries))
+	}
+}
+
+func TestDryRun_Origin(t *testing.T) {
+	dir := testutil.Path("/", "downloads")
+	d := &DryRunFileSystem{Fs: afero.NewMemMapFs()}
+	d.MkdirAll(testutil.Path(dir, "photos"), 0755)
+	afero.WriteFile(d, testutil.Path(dir, "photos", "a.jpg"), []byte("a"), 0644)
+	afero.WriteFile(d, testutil.Path(dir, "b.txt"), []byte("b"), 0644)
+
+	// A file renamed twice, and a directory renamed with a file inside
+	d.Rename(testutil.Path(dir, "b.txt"), testutil.Path(dir, "c.txt"))
+	d.Rename(testutil.Path(dir, "c.txt"), testutil.Path(dir, "d.txt"))
+	d.Rename(testutil.Path(dir, "photos"), testutil.Path(dir, "pictures"))
+	// Renaming back and forth still leads to the original
+	d.Rename(testutil.Path(dir, "d.txt"), testutil.Path(dir, "e.txt"))
+	d.Rename(testutil.Path(dir, "e.txt"), testutil.Path(dir, "d.txt"))
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{testutil.Path(dir, "d.txt"), testutil.Path(dir, "b.txt")},
+		{testutil.Path(dir, "pictures", "a.jpg"), testutil.Path(dir, "photos", "a.jpg")},
+		{testutil.Path(dir, "pictures"), testutil.Path(dir, "photos")},
+		{testutil.Path(dir, "other.txt"), testutil.Path(dir, "other.txt")},
+	}
+	for _, tt := range tests {
+		if got := Origin(d, tt.path); got != tt.expected {
+			t.Errorf("Origin(%s) = %s, want %s", tt.path, got, tt.expected)
+		}
+	}
+
+	if got := Origin(NewMem(), dir); got != dir {
+		t.Errorf("Origin on other filesystems = %s, want the path unchanged", got)
+	}
+}
@@ -0,0 +1,34 @@
+package fs
+
+import (
+	"os"
+
+	"github.com/spf13/afero"
+)
+
+// Lstat returns the FileInfo for path without following a final symlink,
+// when the filesystem supports symlinks.
+func Lstat(afs afero.Fs, path string) (os.FileInfo, error) {
+	return lstat(unwrap(afs), path)
+}
+
+// Readlink returns the target of the symlink at path.
+func Readlink(afs afero.Fs, path string) (string, error) {
+	return readlink(unwrap(afs), path)
+}
+
+// unwrap returns the afero filesystem behind one of the FileSystem
+// implementations, since embedding it doesn't expose its optional interfaces
+// like afero.Lstater.
+func unwrap(afs afero.Fs) afero.Fs {
+	switch f := afs.(type) {
+	case *RealFileSystem:
+		return f.Fs
+	case *DryRunFileSystem:
+		return f.Fs
+	case *MemFileSystem:
+		return f.Fs
+	default:
+		return afs
+	}
+}
@@ -33,6 +33,11 @@ func (m *MemFileSystem) Trash(path string) error {
 
 // ResolveConflict handles destination file conflicts.
 func (m *MemFileSystem) ResolveConflict(mode ConflictMode, srcPath, destPath string) (string, bool, error) {
+	mode, _, err := DecideConflict(m.Fs, mode, srcPath, destPath)
+	if err != nil {
+		return "", false, err
+	}
+
 	switch mode {
 	case ConflictRenameWithSuffix:
 		newPath := m.findAvailableSuffixedPath(destPath)
@@ -48,7 +53,10 @@ func (m *MemFileSystem) ResolveConflict(mode ConflictMode, srcPath, destPath str
 		return destPath, true, nil
 
 	case ConflictTrash:
-		return "", false, fmt.Errorf("trash conflict mode is not yet implemented")
+		if err := m.Trash(destPath); err != nil {
+			return "", false, err
+		}
+		return destPath, true, nil
 
 	default:
 		return "", false, fmt.Errorf("unknown conflict mode: %s", mode)
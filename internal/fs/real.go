@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -20,9 +21,16 @@ type RealFileSystem struct {
 }
 
 // Rename performs the rename operation.
+// Falls back to copy-verify-delete when the paths are on different filesystems.
 func (r *RealFileSystem) Rename(oldname, newname string) error {
 	slog.Debug("renaming", "from", oldname, "to", newname)
-	return r.Fs.Rename(oldname, newname)
+	err := r.Fs.Rename(oldname, newname)
+	if err == nil || !isCrossDeviceError(err) {
+		return err
+	}
+
+	slog.Debug("rename crosses filesystems, copying instead", "from", oldname, "to", newname)
+	return moveAcrossDevices(r.Fs, oldname, newname)
 }
 
 // Remove performs the remove operation.
@@ -68,6 +76,11 @@ func (r *RealFileSystem) Trash(path string) error {
 
 // ResolveConflict handles destination file conflicts.
 func (r *RealFileSystem) ResolveConflict(mode ConflictMode, srcPath, destPath string) (string, bool, error) {
+	mode, _, err := DecideConflict(r.Fs, mode, srcPath, destPath)
+	if err != nil {
+		return "", false, err
+	}
+
 	switch mode {
 	case ConflictRenameWithSuffix:
 		newPath := r.findAvailableSuffixedPath(destPath)
@@ -86,7 +99,11 @@ func (r *RealFileSystem) ResolveConflict(mode ConflictMode, srcPath, destPath st
 		return destPath, true, nil
 
 	case ConflictTrash:
-		return "", false, fmt.Errorf("trash conflict mode is not yet implemented")
+		slog.Debug("trashing destination file", "src", srcPath, "dest", destPath)
+		if err := r.Trash(destPath); err != nil {
+			return "", false, err
+		}
+		return destPath, true, nil
 
 	default:
 		return "", false, fmt.Errorf("unknown conflict mode: %s", mode)
@@ -194,6 +211,92 @@ func trashLinux(fs afero.Fs, path string) error {
 	return nil
 }
 
+// RestoreFromTrash moves a previously trashed file back to its original path.
+// Only the FreeDesktop.org trash used on Linux is supported.
+func RestoreFromTrash(afs afero.Fs, path string) error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("restoring from trash not supported on %s", runtime.GOOS)
+	}
+	return restoreLinux(afs, path)
+}
+
+// restoreLinux restores the most recently trashed copy of path from the
+// FreeDesktop.org trash, removing its .trashinfo file.
+func restoreLinux(fs afero.Fs, path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	trashDir, err := getLinuxTrashDir()
+	if err != nil {
+		return err
+	}
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+
+	infos, err := afero.ReadDir(fs, infoDir)
+	if err != nil {
+		return err
+	}
+
+	// Find the newest trash entry whose original path matches
+	var trashedName, deletionDate string
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".trashinfo") {
+			continue
+		}
+		content, err := afero.ReadFile(fs, filepath.Join(infoDir, info.Name()))
+		if err != nil {
+			continue
+		}
+		origPath, date := parseTrashInfo(string(content))
+		if origPath != absPath {
+			// The spec allows URL-encoded paths
+			unescaped, err := url.PathUnescape(origPath)
+			if err != nil || unescaped != absPath {
+				continue
+			}
+		}
+		// DeletionDate uses a fixed-width format, so string comparison orders it
+		if trashedName == "" || date > deletionDate {
+			trashedName = strings.TrimSuffix(info.Name(), ".trashinfo")
+			deletionDate = date
+		}
+	}
+
+	if trashedName == "" {
+		return &os.PathError{Op: "restore", Path: absPath, Err: os.ErrNotExist}
+	}
+
+	if _, err := fs.Stat(absPath); err == nil {
+		return &os.PathError{Op: "restore", Path: absPath, Err: os.ErrExist}
+	}
+
+	if err := fs.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return err
+	}
+
+	if err := fs.Rename(filepath.Join(filesDir, trashedName), absPath); err != nil {
+		return err
+	}
+
+	return fs.Remove(filepath.Join(infoDir, trashedName+".trashinfo"))
+}
+
+// parseTrashInfo extracts the original path and deletion date from a .trashinfo file.
+func parseTrashInfo(content string) (path, deletionDate string) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if value, ok := strings.CutPrefix(line, "Path="); ok {
+			path = value
+		} else if value, ok := strings.CutPrefix(line, "DeletionDate="); ok {
+			deletionDate = value
+		}
+	}
+	return path, deletionDate
+}
+
 // getLinuxTrashDir returns the path to the user's trash directory.
 func getLinuxTrashDir() (string, error) {
 	// First try XDG_DATA_HOME
@@ -0,0 +1,208 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// partialSuffix marks in-progress copies made by moveAcrossDevices.
+const partialSuffix = ".autotidy-partial"
+
+// moveAcrossDevices moves src to dst when a plain rename is impossible because
+// they are on different filesystems. The source is copied next to the
+// destination under a temporary name, verified, renamed into place, and only
+// then removed. Mode and modification time are preserved, symlinks are
+// recreated rather than followed, and the partial copy is cleaned up if any
+// step fails. Special files like FIFOs are refused before anything is copied.
+// Failures are returned as *os.LinkError, like a failed rename, so callers
+// skip just this file.
+func moveAcrossDevices(afs afero.Fs, src, dst string) error {
+	err := copyAndReplace(afs, src, dst)
+	var pathErr *os.PathError
+	var linkErr *os.LinkError
+	if err == nil || errors.As(err, &pathErr) || errors.As(err, &linkErr) {
+		return err
+	}
+	return &os.LinkError{Op: "rename", Old: src, New: dst, Err: err}
+}
+
+// copyAndReplace performs the steps of moveAcrossDevices.
+func copyAndReplace(afs afero.Fs, src, dst string) error {
+	srcInfo, err := lstat(afs, src)
+	if err != nil {
+		return err
+	}
+	if err := checkCopyable(afs, src, srcInfo); err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+partialSuffix)
+	if err := afs.RemoveAll(tmp); err != nil {
+		return err
+	}
+
+	if err := copyPreserving(afs, src, tmp, srcInfo); err != nil {
+		afs.RemoveAll(tmp)
+		return err
+	}
+
+	if err := verifyCopy(afs, src, tmp); err != nil {
+		afs.RemoveAll(tmp)
+		return err
+	}
+
+	// Same filesystem now, so this rename is atomic
+	if err := afs.Rename(tmp, dst); err != nil {
+		afs.RemoveAll(tmp)
+		return err
+	}
+
+	return afs.RemoveAll(src)
+}
+
+// lstat returns the FileInfo for path without following a final symlink,
+// when the filesystem supports it.
+func lstat(afs afero.Fs, path string) (os.FileInfo, error) {
+	if l, ok := afs.(afero.Lstater); ok {
+		info, _, err := l.LstatIfPossible(path)
+		return info, err
+	}
+	return afs.Stat(path)
+}
+
+// checkCopyable returns an error if the tree at path contains an entry that
+// can't be copied, like a FIFO or device, which would block or lose data.
+func checkCopyable(afs afero.Fs, path string, info os.FileInfo) error {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return nil
+	case info.IsDir():
+		entries, err := afero.ReadDir(afs, path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := checkCopyable(afs, filepath.Join(path, entry.Name()), entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("cannot move special file across filesystems: %s", path)
+	}
+	return nil
+}
+
+// readlink returns the target of the symlink at path.
+func readlink(afs afero.Fs, path string) (string, error) {
+	reader, ok := afs.(afero.LinkReader)
+	if !ok {
+		return "", fmt.Errorf("cannot read symlink on this filesystem: %s", path)
+	}
+	return reader.ReadlinkIfPossible(path)
+}
+
+// copySymlink recreates the symlink at src at dst, pointing at the same target.
+func copySymlink(afs afero.Fs, src, dst string) error {
+	target, err := readlink(afs, src)
+	if err != nil {
+		return err
+	}
+	linker, ok := afs.(afero.Linker)
+	if !ok {
+		return fmt.Errorf("cannot create symlink on this filesystem: %s", dst)
+	}
+	return linker.SymlinkIfPossible(target, dst)
+}
+
+// copyPreserving copies a file or directory tree, keeping mode and modification
+// times. Symlinks are recreated with the same target, without following them.
+func copyPreserving(afs afero.Fs, src, dst string, info os.FileInfo) error {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return copySymlink(afs, src, dst)
+	}
+
+	if info.IsDir() {
+		if err := afs.MkdirAll(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+
+		entries, err := afero.ReadDir(afs, src)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := copyPreserving(afs, filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), entry); err != nil {
+				return err
+			}
+		}
+	} else if err := copyFile(afs, src, dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// Set metadata last, since writing directory entries updates the mtime
+	if err := afs.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return afs.Chtimes(dst, time.Now(), info.ModTime())
+}
+
+// verifyCopy checks that dst has the same structure, file contents and
+// symlink targets as src.
+func verifyCopy(afs afero.Fs, src, dst string) error {
+	srcInfo, err := lstat(afs, src)
+	if err != nil {
+		return err
+	}
+	dstInfo, err := lstat(afs, dst)
+	if err != nil {
+		return err
+	}
+
+	if srcInfo.IsDir() != dstInfo.IsDir() || srcInfo.Mode().Type() != dstInfo.Mode().Type() {
+		return fmt.Errorf("copy verification failed: %s", dst)
+	}
+
+	if srcInfo.Mode()&os.ModeSymlink != 0 {
+		srcTarget, err := readlink(afs, src)
+		if err != nil {
+			return err
+		}
+		dstTarget, err := readlink(afs, dst)
+		if err != nil {
+			return err
+		}
+		if srcTarget != dstTarget {
+			return fmt.Errorf("copy verification failed: %s links to %s, want %s", dst, dstTarget, srcTarget)
+		}
+		return nil
+	}
+
+	if !srcInfo.IsDir() {
+		same, err := SameContent(afs, src, dst)
+		if err != nil {
+			return err
+		}
+		if !same {
+			return fmt.Errorf("copy verification failed: contents of %s differ from %s", dst, src)
+		}
+		return nil
+	}
+
+	entries, err := afero.ReadDir(afs, src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := verifyCopy(afs, filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package fs
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isCrossDeviceError reports whether a rename failed because the source and
+// destination are on different filesystems.
+// On Unix systems, this is EXDEV.
+func isCrossDeviceError(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
@@ -0,0 +1,91 @@
+//go:build !windows
+
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// crossDeviceFs fails renames like a move between two filesystems would,
+// except for the final rename of the partial copy into place.
+type crossDeviceFs struct {
+	*afero.OsFs
+}
+
+func (f crossDeviceFs) Rename(oldname, newname string) error {
+	if strings.HasSuffix(oldname, partialSuffix) {
+		return f.OsFs.Rename(oldname, newname)
+	}
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: syscall.EXDEV}
+}
+
+func TestRealFileSystem_RenameAcrossDevices(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src", "project")
+	dest := filepath.Join(dir, "dest", "project")
+	os.MkdirAll(filepath.Join(src, "docs"), 0755)
+	os.MkdirAll(filepath.Dir(dest), 0755)
+	os.WriteFile(filepath.Join(src, "readme.txt"), []byte("hello"), 0644)
+	os.Symlink("readme.txt", filepath.Join(src, "file-link"))
+	os.Symlink("docs", filepath.Join(src, "dir-link"))
+	os.Symlink("missing", filepath.Join(src, "dangling-link"))
+
+	filesystem := &RealFileSystem{Fs: crossDeviceFs{OsFs: &afero.OsFs{}}}
+	if err := filesystem.Rename(src, dest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Error("source should be removed")
+	}
+	if content, _ := os.ReadFile(filepath.Join(dest, "readme.txt")); string(content) != "hello" {
+		t.Errorf("content = %q, want %q", content, "hello")
+	}
+	for link, target := range map[string]string{"file-link": "readme.txt", "dir-link": "docs", "dangling-link": "missing"} {
+		got, err := os.Readlink(filepath.Join(dest, link))
+		if err != nil {
+			t.Errorf("expected %s to be a symlink: %v", link, err)
+			continue
+		}
+		if got != target {
+			t.Errorf("%s links to %q, want %q", link, got, target)
+		}
+	}
+}
+
+func TestRealFileSystem_RenameAcrossDevices_RefusesSpecialFiles(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src", "project")
+	dest := filepath.Join(dir, "dest", "project")
+	os.MkdirAll(src, 0755)
+	os.MkdirAll(filepath.Dir(dest), 0755)
+	os.WriteFile(filepath.Join(src, "readme.txt"), []byte("hello"), 0644)
+	if err := syscall.Mkfifo(filepath.Join(src, "pipe"), 0644); err != nil {
+		t.Skipf("cannot create FIFO: %v", err)
+	}
+
+	filesystem := &RealFileSystem{Fs: crossDeviceFs{OsFs: &afero.OsFs{}}}
+	err := filesystem.Rename(src, dest)
+	if err == nil || !strings.Contains(err.Error(), "special file") {
+		t.Fatalf("expected special file error, got %v", err)
+	}
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) {
+		t.Errorf("expected a *os.LinkError so only this file is skipped, got %T", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(src, "readme.txt")); err != nil {
+		t.Errorf("source should be left in place: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(dest))
+	if len(entries) != 0 {
+		t.Errorf("expected destination directory to be empty, got %d entries", len(entries))
+	}
+}
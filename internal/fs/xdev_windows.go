@@ -0,0 +1,19 @@
+//go:build windows
+
+package fs
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, returned by MoveFileEx
+// when moving between volumes without MOVEFILE_COPY_ALLOWED.
+const errorNotSameDevice syscall.Errno = 17
+
+// isCrossDeviceError reports whether a rename failed because the source and
+// destination are on different filesystems.
+// On Windows, this is ERROR_NOT_SAME_DEVICE.
+func isCrossDeviceError(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
@@ -0,0 +1,102 @@
+// Package ignore matches paths against ignore files written in gitignore
+// syntax, like .autotidyignore and .gitignore.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// pattern is a single rule from an ignore file.
+type pattern struct {
+	glob    string // doublestar pattern matched against the relative path
+	negate  bool   // "!pattern" re-includes matching paths
+	dirOnly bool   // "pattern/" only matches directories
+}
+
+// File holds the patterns of one ignore file. Patterns are matched against
+// slash-separated paths relative to the directory containing the file.
+type File struct {
+	patterns []pattern
+}
+
+// Parse parses the contents of an ignore file. Lines that are not valid
+// patterns are skipped, like git does.
+func Parse(content []byte) *File {
+	f := &File{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if p, ok := parseLine(scanner.Text()); ok {
+			f.patterns = append(f.patterns, p)
+		}
+	}
+	return f
+}
+
+// parseLine converts one line of an ignore file into a pattern.
+func parseLine(line string) (pattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false
+	}
+
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return pattern{}, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to the
+	// ignore file's directory, otherwise it matches at any depth
+	if strings.HasPrefix(line, "/") {
+		line = line[1:]
+	} else if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+
+	// Braces are literal in gitignore, but alternatives in doublestar
+	line = strings.NewReplacer("{", `\{`, "}", `\}`).Replace(line)
+	if !doublestar.ValidatePattern(line) {
+		return pattern{}, false
+	}
+	p.glob = line
+	return p, true
+}
+
+// trimTrailingSpaces removes trailing spaces, except ones escaped with a backslash.
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// Match reports whether the file decides on the path, and if so, whether the
+// path is ignored. The last matching pattern wins, so a later "!pattern" can
+// re-include a path an earlier pattern ignored.
+func (f *File) Match(rel string, isDir bool) (matched bool, ignored bool) {
+	for i := len(f.patterns) - 1; i >= 0; i-- {
+		p := f.patterns[i]
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if ok, _ := doublestar.Match(p.glob, rel); ok {
+			return true, !p.negate
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,56 @@
+package ignore
+
+import "testing"
+
+func TestFile_Match(t *testing.T) {
+	content := `
+# Comments and blank lines are skipped
+
+*.log
+!important.log
+build/
+/todo.txt
+docs/*.pdf
+**/cache/**
+\#notes
+trailing   
+{braces}
+`
+	f := Parse([]byte(content))
+
+	tests := []struct {
+		name            string
+		rel             string
+		isDir           bool
+		expectedMatched bool
+		expectedIgnored bool
+	}{
+		{name: "basename pattern at top level", rel: "debug.log", expectedMatched: true, expectedIgnored: true},
+		{name: "basename pattern at any depth", rel: "a/b/debug.log", expectedMatched: true, expectedIgnored: true},
+		{name: "negated pattern re-includes", rel: "logs/important.log", expectedMatched: true, expectedIgnored: false},
+		{name: "directory pattern matches directory", rel: "app/build", isDir: true, expectedMatched: true, expectedIgnored: true},
+		{name: "directory pattern skips files", rel: "app/build", expectedMatched: false},
+		{name: "leading slash anchors", rel: "todo.txt", expectedMatched: true, expectedIgnored: true},
+		{name: "anchored pattern not matched deeper", rel: "sub/todo.txt", expectedMatched: false},
+		{name: "middle slash anchors", rel: "docs/manual.pdf", expectedMatched: true, expectedIgnored: true},
+		{name: "middle slash anchored pattern not matched deeper", rel: "sub/docs/manual.pdf", expectedMatched: false},
+		{name: "double star", rel: "a/cache/b/c.bin", expectedMatched: true, expectedIgnored: true},
+		{name: "escaped hash", rel: "#notes", expectedMatched: true, expectedIgnored: true},
+		{name: "trailing spaces trimmed", rel: "trailing", expectedMatched: true, expectedIgnored: true},
+		{name: "braces are literal", rel: "{braces}", expectedMatched: true, expectedIgnored: true},
+		{name: "braces are not alternatives", rel: "braces", expectedMatched: false},
+		{name: "unmatched", rel: "readme.md", expectedMatched: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, ignored := f.Match(tt.rel, tt.isDir)
+			if matched != tt.expectedMatched {
+				t.Errorf("Match(%q) matched = %v, want %v", tt.rel, matched, tt.expectedMatched)
+			}
+			if matched && ignored != tt.expectedIgnored {
+				t.Errorf("Match(%q) ignored = %v, want %v", tt.rel, ignored, tt.expectedIgnored)
+			}
+		})
+	}
+}
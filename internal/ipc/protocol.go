@@ -25,6 +25,8 @@ type RuleStatus struct {
 	LastDuration   *time.Duration `json:"last_duration,omitempty"`
 	FilesProcessed *int           `json:"files_processed,omitempty"`
 	ErrorCount     *int           `json:"error_count,omitempty"`
+	Schedule       string         `json:"schedule,omitempty"`
+	NextRunAt      *time.Time     `json:"next_run_at,omitempty"`
 }
 
 // ReloadResult is returned by Daemon.Reload.
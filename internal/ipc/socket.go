@@ -52,3 +52,23 @@ func StatePath() (string, error) {
 		return filepath.Join(home, ".config", "autotidy", "state.json"), nil
 	}
 }
+
+// JournalPath returns the platform-appropriate action journal path.
+// The journal is stored next to the state file.
+func JournalPath() (string, error) {
+	statePath, err := StatePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(statePath), "journal.jsonl"), nil
+}
+
+// HashCachePath returns the platform-appropriate file hash cache path.
+// The cache is stored next to the state file.
+func HashCachePath() (string, error) {
+	statePath, err := StatePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(statePath), "hashes.json"), nil
+}
@@ -0,0 +1,162 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/ipc"
+)
+
+// Entry records a single action that modified the filesystem.
+type Entry struct {
+	ID     string    `json:"id"`
+	Run    string    `json:"run,omitempty"`
+	Time   time.Time `json:"time"`
+	Rule   string    `json:"rule,omitempty"`
+	Action string    `json:"action"`
+	Src    string    `json:"src,omitempty"`
+	Dest   string    `json:"dest,omitempty"`
+
+	// Reverts is set on undo records to the ID of the entry that was undone.
+	Reverts string `json:"reverts,omitempty"`
+}
+
+// actionUndo is the action name used for undo records.
+const actionUndo = "undo"
+
+// idCounter disambiguates entries recorded within the same nanosecond.
+var idCounter atomic.Uint64
+
+// Journal is an append-only log of executed actions, stored as JSON lines.
+// All methods are nil-safe - a nil Journal records nothing.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Open opens the journal at the default journal path.
+func Open() (*Journal, error) {
+	path, err := ipc.JournalPath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenAt(path), nil
+}
+
+// OpenAt opens the journal at the specified path.
+// The file is created on the first append.
+func OpenAt(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// NewRunID returns an identifier grouping all entries of one rule execution.
+func NewRunID(start time.Time) string {
+	return fmt.Sprintf("%x", start.UnixNano())
+}
+
+// Record appends an entry for an executed action.
+// The entry's ID and Time are filled in if unset.
+func (j *Journal) Record(e Entry) error {
+	if j == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.ID == "" {
+		e.ID = fmt.Sprintf("%x-%d", e.Time.UnixNano(), idCounter.Add(1))
+	}
+	return j.append(e)
+}
+
+// markReverted appends an undo record for the given entry.
+func (j *Journal) markReverted(e Entry) error {
+	return j.Record(Entry{
+		Action:  actionUndo,
+		Reverts: e.ID,
+	})
+}
+
+// append writes a single entry as one JSON line.
+func (j *Journal) append(e Entry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Entries returns all entries that have not been undone, oldest first.
+// Undo records themselves are not returned.
+func (j *Journal) Entries() ([]Entry, error) {
+	if j == nil {
+		return nil, nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var all []Entry
+	reverted := make(map[string]bool)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// A partially written line should not make the whole journal unusable
+			slog.Warn("skipping malformed journal entry", "path", j.path, "line", line, "error", err)
+			continue
+		}
+		if e.Action == actionUndo {
+			reverted[e.Reverts] = true
+			continue
+		}
+		all = append(all, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(all))
+	for _, e := range all {
+		if !reverted[e.ID] {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,271 @@
+package journal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+
+	"github.com/spf13/afero"
+)
+
+func TestJournal_RecordAndEntries(t *testing.T) {
+	j := OpenAt(filepath.Join(t.TempDir(), "journal.jsonl"))
+
+	if err := j.Record(Entry{Run: "r1", Rule: "rule", Action: "move", Src: "/a", Dest: "/b"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.Record(Entry{Run: "r1", Rule: "rule", Action: "trash", Src: "/c"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].ID == "" || entries[0].ID == entries[1].ID {
+		t.Errorf("expected unique IDs, got %q and %q", entries[0].ID, entries[1].ID)
+	}
+	if entries[0].Time.IsZero() {
+		t.Error("expected time to be set")
+	}
+	if entries[1].Action != "trash" {
+		t.Errorf("second entry action = %q, want %q", entries[1].Action, "trash")
+	}
+}
+
+func TestJournal_EntriesMissingFile(t *testing.T) {
+	j := OpenAt(filepath.Join(t.TempDir(), "missing.jsonl"))
+
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(entries))
+	}
+}
+
+func TestJournal_NilIsNoop(t *testing.T) {
+	var j *Journal
+	if err := j.Record(Entry{Action: "move"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{ID: "1", Run: "a", Rule: "photos", Action: "copy", Time: base},
+		{ID: "2", Run: "a", Rule: "photos", Action: "copy", Time: base.Add(time.Minute)},
+		{ID: "3", Run: "b", Rule: "docs", Action: "copy", Time: base.Add(2 * time.Minute)},
+		{ID: "4", Run: "c", Rule: "photos", Action: "copy", Time: base.Add(3 * time.Minute)},
+		{ID: "5", Run: "c", Rule: "photos", Action: "delete", Time: base.Add(4 * time.Minute)},
+		{ID: "6", Run: "d", Rule: "cleanup", Action: "delete", Time: base.Add(5 * time.Minute)},
+	}
+
+	ids := func(es []Entry) []string {
+		var out []string
+		for _, e := range es {
+			out = append(out, e.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		selected []Entry
+		expected []string
+	}{
+		{"last run newest first, skipping runs with only deletes", LastRun(entries), []string{"5", "4"}},
+		{"rule limited to n", ForRule(entries, "photos", 3), []string{"5", "4", "2"}},
+		{"rule with fewer entries than n", ForRule(entries, "docs", 5), []string{"3"}},
+		{"since is inclusive", Since(entries, base.Add(2*time.Minute)), []string{"6", "5", "4", "3"}},
+		{"last run of empty journal", LastRun(nil), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(tt.selected)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("got %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("got %v, want %v", got, tt.expected)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestJournal_Undo(t *testing.T) {
+	src := testutil.Path("/", "downloads", "report.pdf")
+	moved := testutil.Path("/", "docs", "report.pdf")
+	renamed := testutil.Path("/", "docs", "final.pdf")
+	copied := testutil.Path("/", "docs", "final_backup.pdf")
+
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll(filepath.Dir(renamed), 0755)
+	afero.WriteFile(filesystem, renamed, []byte("pdf"), 0644)
+	afero.WriteFile(filesystem, copied, []byte("pdf"), 0644)
+
+	j := OpenAt(filepath.Join(t.TempDir(), "journal.jsonl"))
+	j.Record(Entry{Run: "r", Rule: "docs", Action: "move", Src: src, Dest: moved})
+	j.Record(Entry{Run: "r", Rule: "docs", Action: "rename", Src: moved, Dest: renamed})
+	j.Record(Entry{Run: "r", Rule: "docs", Action: "copy", Src: renamed, Dest: copied})
+
+	entries, _ := j.Entries()
+	for _, result := range j.Undo(filesystem, LastRun(entries)) {
+		if result.Err != nil {
+			t.Errorf("undo %s failed: %v", result.Entry.Action, result.Err)
+		}
+	}
+
+	if exists, _ := afero.Exists(filesystem, src); !exists {
+		t.Error("expected original file to be restored")
+	}
+	for _, p := range []string{moved, renamed, copied} {
+		if exists, _ := afero.Exists(filesystem, p); exists {
+			t.Errorf("expected %s to no longer exist", p)
+		}
+	}
+
+	// Reverted entries are no longer returned
+	remaining, err := j.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("expected no remaining entries, got %d", len(remaining))
+	}
+}
+
+func TestJournal_UndoRefusesToOverwrite(t *testing.T) {
+	src := testutil.Path("/", "src", "file.txt")
+	dest := testutil.Path("/", "dest", "file.txt")
+
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll(filepath.Dir(src), 0755)
+	filesystem.MkdirAll(filepath.Dir(dest), 0755)
+	afero.WriteFile(filesystem, src, []byte("new"), 0644)
+	afero.WriteFile(filesystem, dest, []byte("moved"), 0644)
+
+	j := OpenAt(filepath.Join(t.TempDir(), "journal.jsonl"))
+	j.Record(Entry{Run: "r", Rule: "rule", Action: "move", Src: src, Dest: dest})
+
+	entries, _ := j.Entries()
+	results := j.Undo(filesystem, entries)
+	if len(results) != 1 || results[0].Err == nil {
+		t.Fatal("expected undo to fail when the original path is occupied")
+	}
+
+	// Failed reverts stay in the journal
+	remaining, _ := j.Entries()
+	if len(remaining) != 1 {
+		t.Errorf("expected 1 remaining entry, got %d", len(remaining))
+	}
+}
+
+func TestJournal_UndoDelete(t *testing.T) {
+	j := OpenAt(filepath.Join(t.TempDir(), "journal.jsonl"))
+	results := j.Undo(fs.NewMem(), []Entry{{ID: "1", Action: "delete", Src: "/gone"}})
+	if results[0].Err == nil {
+		t.Error("expected error when undoing a delete")
+	}
+}
+
+func TestJournal_UndoPastDelete(t *testing.T) {
+	older := testutil.Path("/", "downloads", "older.txt")
+	olderMoved := testutil.Path("/", "sorted", "older.txt")
+	newer := testutil.Path("/", "downloads", "newer.txt")
+	newerMoved := testutil.Path("/", "sorted", "newer.txt")
+
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll(filepath.Dir(olderMoved), 0755)
+	afero.WriteFile(filesystem, olderMoved, []byte("older"), 0644)
+	afero.WriteFile(filesystem, newerMoved, []byte("newer"), 0644)
+
+	j := OpenAt(filepath.Join(t.TempDir(), "journal.jsonl"))
+	j.Record(Entry{Run: "r1", Rule: "sort", Action: "move", Src: older, Dest: olderMoved})
+	j.Record(Entry{Run: "r2", Rule: "sort", Action: "move", Src: newer, Dest: newerMoved})
+	j.Record(Entry{Run: "r2", Rule: "cleanup", Action: "delete", Src: testutil.Path("/", "downloads", "gone.tmp")})
+
+	// The first undo reverts the newer run and reports the delete
+	entries, _ := j.Entries()
+	var failed int
+	for _, result := range j.Undo(filesystem, LastRun(entries)) {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected only the delete to fail, got %d failures", failed)
+	}
+	if exists, _ := afero.Exists(filesystem, newer); !exists {
+		t.Error("expected newer file to be restored")
+	}
+
+	// The second undo moves on to the older run
+	entries, _ = j.Entries()
+	for _, result := range j.Undo(filesystem, LastRun(entries)) {
+		if result.Err != nil {
+			t.Errorf("undo %s failed: %v", result.Entry.Action, result.Err)
+		}
+	}
+	if exists, _ := afero.Exists(filesystem, older); !exists {
+		t.Error("expected older file to be restored")
+	}
+
+	entries, _ = j.Entries()
+	if len(entries) != 0 {
+		t.Errorf("expected no remaining entries, got %d", len(entries))
+	}
+}
+
+func TestSupports(t *testing.T) {
+	for _, action := range []string{"move", "rename", "copy", "extract", "trash", "delete"} {
+		if !Supports(action) {
+			t.Errorf("expected undo to support %q", action)
+		}
+	}
+	for _, action := range []string{"archive", "exec", "log"} {
+		if Supports(action) {
+			t.Errorf("expected undo not to support %q", action)
+		}
+	}
+}
+
+func TestJournal_UndoExtract(t *testing.T) {
+	archive := testutil.Path("/", "downloads", "docs.zip")
+	extracted := testutil.Path("/", "downloads", "docs", "readme.txt")
+	existing := testutil.Path("/", "downloads", "docs", "notes.txt")
+
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll(filepath.Dir(extracted), 0755)
+	afero.WriteFile(filesystem, extracted, []byte("hello"), 0644)
+	afero.WriteFile(filesystem, existing, []byte("mine"), 0644)
+
+	j := OpenAt(filepath.Join(t.TempDir(), "journal.jsonl"))
+	j.Record(Entry{Run: "r", Rule: "unpack", Action: "extract", Src: archive, Dest: extracted})
+
+	entries, _ := j.Entries()
+	for _, result := range j.Undo(filesystem, entries) {
+		if result.Err != nil {
+			t.Errorf("undo failed: %v", result.Err)
+		}
+	}
+
+	if exists, _ := afero.Exists(filesystem, extracted); exists {
+		t.Error("expected extracted file to be removed")
+	}
+	if exists, _ := afero.Exists(filesystem, existing); !exists {
+		t.Error("files that were not extracted should be left alone")
+	}
+}
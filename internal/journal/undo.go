@@ -0,0 +1,157 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+)
+
+// UndoResult describes the outcome of reverting a single entry.
+type UndoResult struct {
+	Entry Entry
+	Err   error
+}
+
+// LastRun returns the entries of the most recent run that undo can still
+// revert something from, newest first. Runs with only deletes left are
+// skipped, so they don't hide the runs before them.
+func LastRun(entries []Entry) []Entry {
+	// Entries are appended in order, so the last revertable entry belongs to
+	// the last run with anything to undo
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !revertable(entries[i]) {
+			continue
+		}
+		run := entries[i].Run
+		var selected []Entry
+		for j := len(entries) - 1; j >= 0; j-- {
+			if entries[j].Run == run {
+				selected = append(selected, entries[j])
+			}
+		}
+		return selected
+	}
+	return nil
+}
+
+// ForRule returns the last n entries recorded for a rule, newest first.
+func ForRule(entries []Entry, rule string, n int) []Entry {
+	var selected []Entry
+	for i := len(entries) - 1; i >= 0 && len(selected) < n; i-- {
+		if entries[i].Rule == rule {
+			selected = append(selected, entries[i])
+		}
+	}
+	return selected
+}
+
+// Since returns all entries recorded at or after t, newest first.
+func Since(entries []Entry, t time.Time) []Entry {
+	var selected []Entry
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].Time.Before(t) {
+			selected = append(selected, entries[i])
+		}
+	}
+	return selected
+}
+
+// Undo reverts the given entries in order and records each successful revert.
+// Entries should be ordered newest first so chained actions unwind correctly.
+// Failures do not stop the remaining entries from being reverted. Deletes are
+// reported as failures once, and then marked reverted, since no later undo
+// can restore them either.
+func (j *Journal) Undo(filesystem fs.FileSystem, entries []Entry) []UndoResult {
+	results := make([]UndoResult, 0, len(entries))
+	for _, e := range entries {
+		err := revert(filesystem, e)
+		if err == nil {
+			err = j.markReverted(e)
+		} else if errors.Is(err, errNotRestorable) {
+			if markErr := j.markReverted(e); markErr != nil {
+				err = errors.Join(err, markErr)
+			}
+		}
+		results = append(results, UndoResult{Entry: e, Err: err})
+	}
+	return results
+}
+
+// Supports reports whether undo handles entries of the action. Deletes are
+// supported so undo can report the files that are gone for good; actions
+// like exec and archive are not journaled, since undo can't revert them.
+func Supports(action string) bool {
+	switch action {
+	case "move", "rename", "copy", "extract", "trash", "delete":
+		return true
+	default:
+		return false
+	}
+}
+
+// errNotRestorable is returned for entries whose files are gone for good.
+var errNotRestorable = errors.New("deleted files cannot be restored")
+
+// revertable reports whether undo can restore the change an entry records.
+func revertable(e Entry) bool {
+	switch e.Action {
+	case "delete":
+		return false
+	case "move", "rename":
+		return e.Dest != ""
+	default:
+		return Supports(e.Action)
+	}
+}
+
+// revert reverses the filesystem change described by a single entry.
+func revert(filesystem fs.FileSystem, e Entry) error {
+	switch e.Action {
+	case "move", "rename":
+		if e.Dest == "" {
+			// The source was deleted instead, e.g. by on_identical: delete
+			return fmt.Errorf("%w: %s", errNotRestorable, e.Src)
+		}
+		return moveBack(filesystem, e.Dest, e.Src)
+
+	case "copy":
+		// Copies are undone by removing the copy
+		return filesystem.RemoveAll(e.Dest)
+
+	case "extract":
+		// Each extracted file and created directory has its own entry, and
+		// directories come first, so they are empty by the time they're reverted
+		return filesystem.Remove(e.Dest)
+
+	case "trash":
+		return fs.RestoreFromTrash(filesystem, e.Src)
+
+	case "delete":
+		return fmt.Errorf("%w: %s", errNotRestorable, e.Src)
+
+	default:
+		return fmt.Errorf("undo is not supported for action %q", e.Action)
+	}
+}
+
+// moveBack moves a file from its current location back to its original path.
+// Refuses to overwrite anything that now occupies the original path.
+func moveBack(filesystem fs.FileSystem, current, original string) error {
+	if _, err := filesystem.Stat(current); err != nil {
+		return err
+	}
+
+	if _, err := filesystem.Stat(original); err == nil {
+		return &os.PathError{Op: "undo", Path: original, Err: os.ErrExist}
+	}
+
+	if err := filesystem.MkdirAll(filepath.Dir(original), 0755); err != nil {
+		return err
+	}
+
+	return filesystem.Rename(current, original)
+}
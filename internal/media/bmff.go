@@ -0,0 +1,304 @@
+package media
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// maxBoxes guards against corrupt files with endless tiny boxes.
+const maxBoxes = 10000
+
+// quickTimeEpoch is the epoch of MP4/MOV timestamps.
+var quickTimeEpoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// box is an ISO base media file format box (an "atom" in QuickTime).
+type box struct {
+	typ   string
+	start int64 // Start of the payload, after the header
+	end   int64
+}
+
+// isBMFFHeader reports whether b starts with a box typically found first in
+// MP4, MOV and HEIF files.
+func isBMFFHeader(b []byte) bool {
+	if len(b) < 8 {
+		return false
+	}
+	switch string(b[4:8]) {
+	case "ftyp", "moov", "mdat", "wide", "free", "skip":
+		return true
+	}
+	return false
+}
+
+// readBoxes returns the boxes between start and end.
+func readBoxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var boxes []box
+	header := make([]byte, 16)
+
+	for pos := start; pos+8 <= end; {
+		if len(boxes) >= maxBoxes {
+			return nil, errMalformed
+		}
+		if _, err := r.ReadAt(header[:8], pos); err != nil {
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		headerLen := int64(8)
+		switch size {
+		case 0:
+			// Box extends to the end
+			size = end - pos
+		case 1:
+			// 64-bit size follows the type
+			if _, err := r.ReadAt(header[8:16], pos+8); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerLen = 16
+		}
+		if size < headerLen || pos+size > end {
+			return nil, errMalformed
+		}
+
+		boxes = append(boxes, box{typ: string(header[4:8]), start: pos + headerLen, end: pos + size})
+		pos += size
+	}
+
+	return boxes, nil
+}
+
+// findBox returns the first box of the given type.
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// bmffDateTaken reads the creation time of a movie, or the EXIF date of a HEIF image.
+func bmffDateTaken(r io.ReaderAt, size int64) (time.Time, error) {
+	boxes, err := readBoxes(r, 0, size)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if moov, ok := findBox(boxes, "moov"); ok {
+		return movieCreationTime(r, moov)
+	}
+	if meta, ok := findBox(boxes, "meta"); ok {
+		return heifDateTaken(r, meta)
+	}
+	return time.Time{}, ErrNoDateTaken
+}
+
+// movieCreationTime reads the creation time from the movie header (mvhd).
+func movieCreationTime(r io.ReaderAt, moov box) (time.Time, error) {
+	children, err := readBoxes(r, moov.start, moov.end)
+	if err != nil {
+		return time.Time{}, err
+	}
+	mvhd, ok := findBox(children, "mvhd")
+	if !ok {
+		return time.Time{}, ErrNoDateTaken
+	}
+
+	// Version 0 stores 32-bit times, version 1 stores 64-bit times
+	buf := make([]byte, 12)
+	if _, err := r.ReadAt(buf, mvhd.start); err != nil {
+		return time.Time{}, err
+	}
+	var seconds uint64
+	if buf[0] == 1 {
+		seconds = binary.BigEndian.Uint64(buf[4:12])
+	} else {
+		seconds = uint64(binary.BigEndian.Uint32(buf[4:8]))
+	}
+
+	// Many tools leave the creation time unset
+	if seconds == 0 {
+		return time.Time{}, ErrNoDateTaken
+	}
+	return quickTimeEpoch.Add(time.Duration(seconds) * time.Second), nil
+}
+
+// heifDateTaken finds the Exif item of a HEIF image and parses it.
+// The meta box lists items in iinf and their file locations in iloc.
+func heifDateTaken(r io.ReaderAt, meta box) (time.Time, error) {
+	// meta is a full box, children follow the version and flags
+	children, err := readBoxes(r, meta.start+4, meta.end)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	iinf, ok := findBox(children, "iinf")
+	if !ok {
+		return time.Time{}, ErrNoDateTaken
+	}
+	itemID, err := findExifItem(r, iinf)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	iloc, ok := findBox(children, "iloc")
+	if !ok {
+		return time.Time{}, ErrNoDateTaken
+	}
+	offset, length, err := findItemLocation(r, iloc, itemID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// The item starts with the offset of the TIFF header, usually past "Exif\0\0"
+	buf := make([]byte, 4)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return time.Time{}, err
+	}
+	tiffOffset := int64(binary.BigEndian.Uint32(buf)) + 4
+	if tiffOffset >= length {
+		return time.Time{}, errMalformed
+	}
+	return exifDateTaken(io.NewSectionReader(r, offset+tiffOffset, length-tiffOffset))
+}
+
+// findExifItem returns the ID of the item with type "Exif" from an iinf box.
+func findExifItem(r io.ReaderAt, iinf box) (uint32, error) {
+	buf := make([]byte, 4)
+	if _, err := r.ReadAt(buf[:1], iinf.start); err != nil {
+		return 0, err
+	}
+	// Entry count is 16-bit in version 0, 32-bit otherwise
+	entriesStart := iinf.start + 4 + 2
+	if buf[0] != 0 {
+		entriesStart = iinf.start + 4 + 4
+	}
+
+	entries, err := readBoxes(r, entriesStart, iinf.end)
+	if err != nil {
+		return 0, err
+	}
+
+	entry := make([]byte, 12)
+	for _, infe := range entries {
+		if infe.typ != "infe" || infe.end-infe.start < 12 {
+			continue
+		}
+		if _, err := r.ReadAt(entry, infe.start); err != nil {
+			return 0, err
+		}
+
+		// Item types only exist from version 2, with 16-bit IDs in version 2
+		// and 32-bit IDs in version 3
+		switch entry[0] {
+		case 2:
+			if string(entry[8:12]) == "Exif" {
+				return uint32(binary.BigEndian.Uint16(entry[4:6])), nil
+			}
+		case 3:
+			if infe.end-infe.start >= 14 {
+				typ := make([]byte, 4)
+				if _, err := r.ReadAt(typ, infe.start+10); err != nil {
+					return 0, err
+				}
+				if string(typ) == "Exif" {
+					return binary.BigEndian.Uint32(entry[4:8]), nil
+				}
+			}
+		}
+	}
+
+	return 0, ErrNoDateTaken
+}
+
+// findItemLocation returns the file offset and length of an item's first
+// extent from an iloc box.
+func findItemLocation(r io.ReaderAt, iloc box, itemID uint32) (int64, int64, error) {
+	data := make([]byte, min(iloc.end-iloc.start, 1<<20))
+	if _, err := r.ReadAt(data, iloc.start); err != nil {
+		return 0, 0, err
+	}
+	p := &byteParser{data: data}
+
+	version := p.uint(1)
+	p.skip(3) // flags
+	sizes := p.uint(1)
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0xF)
+	sizes = p.uint(1)
+	baseOffsetSize, indexSize := int(sizes>>4), int(sizes&0xF)
+	if version == 0 {
+		indexSize = 0
+	}
+
+	idSize := 2
+	if version == 2 {
+		idSize = 4
+	}
+	itemCount := p.uint(idSize)
+
+	for i := uint64(0); i < itemCount && p.err == nil; i++ {
+		id := p.uint(idSize)
+		constructionMethod := uint64(0)
+		if version == 1 || version == 2 {
+			constructionMethod = p.uint(2) & 0xF
+		}
+		p.skip(2) // data reference index
+		baseOffset := p.uint(baseOffsetSize)
+		extentCount := p.uint(2)
+
+		for j := uint64(0); j < extentCount && p.err == nil; j++ {
+			p.skip(indexSize)
+			extentOffset := p.uint(offsetSize)
+			extentLength := p.uint(lengthSize)
+
+			// Only items stored directly in the file are supported
+			if id == uint64(itemID) && j == 0 && p.err == nil {
+				if constructionMethod != 0 {
+					return 0, 0, ErrNoDateTaken
+				}
+				return int64(baseOffset + extentOffset), int64(extentLength), nil
+			}
+		}
+	}
+
+	if p.err != nil {
+		return 0, 0, p.err
+	}
+	return 0, 0, ErrNoDateTaken
+}
+
+// byteParser reads big-endian integers of varying sizes, recording the first
+// out-of-bounds read as an error.
+type byteParser struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// uint reads an unsigned integer of n bytes (0, 1, 2, 4 or 8).
+func (p *byteParser) uint(n int) uint64 {
+	if p.err != nil {
+		return 0
+	}
+	if p.pos+n > len(p.data) {
+		p.err = errMalformed
+		return 0
+	}
+	var v uint64
+	for _, b := range p.data[p.pos : p.pos+n] {
+		v = v<<8 | uint64(b)
+	}
+	p.pos += n
+	return v
+}
+
+// skip advances past n bytes.
+func (p *byteParser) skip(n int) {
+	if p.err == nil && p.pos+n > len(p.data) {
+		p.err = errMalformed
+	}
+	p.pos += n
+}
@@ -0,0 +1,149 @@
+package media
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+)
+
+// EXIF tags used to find the capture date.
+const (
+	tagExifIFD             = 0x8769
+	tagDateTimeOriginal    = 0x9003
+	tagDateTimeDigitized   = 0x9004
+	tagOffsetTimeOriginal  = 0x9011
+	tagOffsetTimeDigitized = 0x9012
+)
+
+// exifTypeASCII is the TIFF field type for NUL-terminated strings.
+const exifTypeASCII = 2
+
+// maxIFDEntries guards against corrupt entry counts.
+const maxIFDEntries = 1000
+
+// exifDateLayout is the EXIF date format, in local time without a zone.
+const exifDateLayout = "2006:01:02 15:04:05"
+
+// ifdEntry is a raw TIFF image file directory entry.
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte // The 4-byte value or offset field
+}
+
+// tiffReader reads values from TIFF-structured data in its byte order.
+type tiffReader struct {
+	r     *io.SectionReader
+	order binary.ByteOrder
+}
+
+// isTIFFHeader reports whether b starts with a little or big endian TIFF header.
+func isTIFFHeader(b []byte) bool {
+	return len(b) >= 4 && (string(b[:4]) == "II*\x00" || string(b[:4]) == "MM\x00*")
+}
+
+// exifDateTaken parses TIFF-structured EXIF data and returns DateTimeOriginal,
+// falling back to DateTimeDigitized.
+func exifDateTaken(r *io.SectionReader) (time.Time, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return time.Time{}, err
+	}
+	if !isTIFFHeader(header) {
+		return time.Time{}, errMalformed
+	}
+
+	tr := &tiffReader{r: r, order: binary.BigEndian}
+	if header[0] == 'I' {
+		tr.order = binary.LittleEndian
+	}
+
+	ifd0, err := tr.readIFD(int64(tr.order.Uint32(header[4:8])))
+	if err != nil {
+		return time.Time{}, err
+	}
+	ptr, ok := ifd0[tagExifIFD]
+	if !ok {
+		return time.Time{}, ErrNoDateTaken
+	}
+
+	exif, err := tr.readIFD(int64(tr.order.Uint32(ptr.value)))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, tags := range [][2]uint16{
+		{tagDateTimeOriginal, tagOffsetTimeOriginal},
+		{tagDateTimeDigitized, tagOffsetTimeDigitized},
+	} {
+		entry, ok := exif[tags[0]]
+		if !ok {
+			continue
+		}
+		date, err := tr.readString(entry)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		loc := time.Local
+		if offsetEntry, ok := exif[tags[1]]; ok {
+			if offset, err := tr.readString(offsetEntry); err == nil {
+				if zone, err := time.Parse("-07:00", offset); err == nil {
+					loc = zone.Location()
+				}
+			}
+		}
+
+		// Cameras without a clock write zeros or blanks, try the next tag
+		if t, err := time.ParseInLocation(exifDateLayout, date, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, ErrNoDateTaken
+}
+
+// readIFD reads the entries of the image file directory at offset.
+func (tr *tiffReader) readIFD(offset int64) (map[uint16]ifdEntry, error) {
+	countBuf := make([]byte, 2)
+	if _, err := tr.r.ReadAt(countBuf, offset); err != nil {
+		return nil, err
+	}
+	count := int(tr.order.Uint16(countBuf))
+	if count > maxIFDEntries {
+		return nil, errMalformed
+	}
+
+	buf := make([]byte, count*12)
+	if _, err := tr.r.ReadAt(buf, offset+2); err != nil {
+		return nil, err
+	}
+
+	entries := make(map[uint16]ifdEntry, count)
+	for i := 0; i < count; i++ {
+		e := buf[i*12 : (i+1)*12]
+		entries[tr.order.Uint16(e[0:2])] = ifdEntry{
+			typ:   tr.order.Uint16(e[2:4]),
+			count: tr.order.Uint32(e[4:8]),
+			value: e[8:12],
+		}
+	}
+	return entries, nil
+}
+
+// readString reads an ASCII entry, stored inline when it fits in 4 bytes.
+func (tr *tiffReader) readString(e ifdEntry) (string, error) {
+	if e.typ != exifTypeASCII || e.count > 256 {
+		return "", errMalformed
+	}
+
+	data := e.value[:min(e.count, 4)]
+	if e.count > 4 {
+		data = make([]byte, e.count)
+		if _, err := tr.r.ReadAt(data, int64(tr.order.Uint32(e.value))); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(strings.TrimRight(string(data), "\x00")), nil
+}
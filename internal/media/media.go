@@ -0,0 +1,122 @@
+// Package media reads capture dates embedded in photo and video files.
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ErrNoDateTaken is returned when a file has no readable capture date,
+// either because its format is not supported or its metadata lacks one.
+var ErrNoDateTaken = errors.New("no date taken in file metadata")
+
+// DateTaken returns when a photo or video was captured.
+// The format is detected from the file's contents:
+//   - JPEG and TIFF (including TIFF-based raw formats): EXIF DateTimeOriginal
+//   - HEIC/HEIF: EXIF DateTimeOriginal from the Exif item
+//   - MP4/MOV: the movie header's creation time
+//
+// Returns ErrNoDateTaken if the file has no date or is not a supported format.
+// Only errors opening or reading the file are returned as-is.
+func DateTaken(fs afero.Fs, path string) (time.Time, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if info.IsDir() {
+		return time.Time{}, ErrNoDateTaken
+	}
+	size := info.Size()
+
+	header := make([]byte, 12)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return time.Time{}, ErrNoDateTaken
+		}
+		return time.Time{}, err
+	}
+
+	var t time.Time
+	switch {
+	case header[0] == 0xFF && header[1] == 0xD8:
+		t, err = jpegDateTaken(f, size)
+	case isTIFFHeader(header):
+		t, err = exifDateTaken(io.NewSectionReader(f, 0, size))
+	case isBMFFHeader(header):
+		t, err = bmffDateTaken(f, size)
+	default:
+		return time.Time{}, ErrNoDateTaken
+	}
+
+	// Truncated or malformed metadata is treated as missing
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, errMalformed) {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrNoDateTaken, err)
+	}
+	return t, err
+}
+
+// errMalformed is returned by parsers for structurally invalid metadata.
+var errMalformed = errors.New("malformed metadata")
+
+// jpegDateTaken finds the EXIF APP1 segment of a JPEG and parses it.
+func jpegDateTaken(r io.ReaderAt, size int64) (time.Time, error) {
+	pos := int64(2) // Skip the SOI marker
+	buf := make([]byte, 4)
+
+	for pos+4 <= size {
+		if _, err := r.ReadAt(buf[:2], pos); err != nil {
+			return time.Time{}, err
+		}
+		if buf[0] != 0xFF {
+			return time.Time{}, errMalformed
+		}
+		marker := buf[1]
+
+		switch {
+		case marker == 0xFF:
+			// Fill byte before a marker
+			pos++
+			continue
+		case marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			// Markers without a length
+			pos += 2
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			// Image data starts, metadata segments come before it
+			return time.Time{}, ErrNoDateTaken
+		}
+
+		if _, err := r.ReadAt(buf[2:4], pos+2); err != nil {
+			return time.Time{}, err
+		}
+		length := int64(buf[2])<<8 | int64(buf[3])
+		if length < 2 {
+			return time.Time{}, errMalformed
+		}
+
+		// APP1 segments holding EXIF start with "Exif\0\0", followed by a TIFF header
+		if marker == 0xE1 && length >= 8 {
+			ident := make([]byte, 6)
+			if _, err := r.ReadAt(ident, pos+4); err != nil {
+				return time.Time{}, err
+			}
+			if string(ident) == "Exif\x00\x00" {
+				return exifDateTaken(io.NewSectionReader(r, pos+10, length-8))
+			}
+		}
+
+		pos += 2 + length
+	}
+
+	return time.Time{}, ErrNoDateTaken
+}
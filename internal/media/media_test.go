@@ -0,0 +1,215 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+
+	"github.com/spf13/afero"
+)
+
+// buildExif returns TIFF-structured EXIF data with the given ASCII tags in
+// the EXIF sub-IFD.
+func buildExif(order binary.ByteOrder, tags map[uint16]string) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II*\x00")
+	} else {
+		buf.WriteString("MM\x00*")
+	}
+	binary.Write(&buf, order, uint32(8))
+
+	// IFD0 with a single pointer to the EXIF IFD at offset 26
+	binary.Write(&buf, order, uint16(1))
+	binary.Write(&buf, order, []uint16{tagExifIFD, 4})
+	binary.Write(&buf, order, []uint32{1, 26})
+	binary.Write(&buf, order, uint32(0))
+
+	ids := make([]uint16, 0, len(tags))
+	for id := range tags {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	// String values longer than 4 bytes follow the IFD
+	dataOffset := uint32(26 + 2 + len(ids)*12 + 4)
+	var data bytes.Buffer
+	binary.Write(&buf, order, uint16(len(ids)))
+	for _, id := range ids {
+		value := []byte(tags[id] + "\x00")
+		binary.Write(&buf, order, []uint16{id, exifTypeASCII})
+		binary.Write(&buf, order, uint32(len(value)))
+		if len(value) <= 4 {
+			buf.Write(append(value, make([]byte, 4-len(value))...))
+		} else {
+			binary.Write(&buf, order, dataOffset+uint32(data.Len()))
+			data.Write(value)
+		}
+	}
+	binary.Write(&buf, order, uint32(0))
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+// buildJPEG wraps EXIF data in a JPEG APP1 segment, after an unrelated APP0 segment.
+func buildJPEG(exif []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8})
+	buf.Write([]byte{0xFF, 0xE0, 0x00, 0x07})
+	buf.WriteString("JFIF\x00")
+	if exif != nil {
+		buf.Write([]byte{0xFF, 0xE1})
+		binary.Write(&buf, binary.BigEndian, uint16(len(exif)+8))
+		buf.WriteString("Exif\x00\x00")
+		buf.Write(exif)
+	}
+	buf.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0x12, 0x34, 0xFF, 0xD9})
+	return buf.Bytes()
+}
+
+// buildBox returns an ISO base media box.
+func buildBox(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(8+len(body)))
+	buf.WriteString(typ)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// buildMovie returns an MP4 whose movie header has the given creation time.
+func buildMovie(version byte, created time.Time) []byte {
+	seconds := uint64(created.Sub(quickTimeEpoch) / time.Second)
+	if created.IsZero() {
+		seconds = 0
+	}
+
+	var mvhd bytes.Buffer
+	mvhd.Write([]byte{version, 0, 0, 0})
+	if version == 1 {
+		binary.Write(&mvhd, binary.BigEndian, []uint64{seconds, seconds})
+	} else {
+		binary.Write(&mvhd, binary.BigEndian, []uint32{uint32(seconds), uint32(seconds)})
+	}
+	mvhd.Write(make([]byte, 80))
+
+	return bytes.Join([][]byte{
+		buildBox("ftyp", []byte("isom\x00\x00\x02\x00isommp41")),
+		buildBox("mdat", []byte("video data")),
+		buildBox("moov", buildBox("mvhd", mvhd.Bytes())),
+	}, nil)
+}
+
+// buildHEIF returns a HEIF image whose Exif item holds the given EXIF data.
+func buildHEIF(exif []byte) []byte {
+	ftyp := buildBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+
+	var infe bytes.Buffer
+	infe.Write([]byte{2, 0, 0, 0})
+	binary.Write(&infe, binary.BigEndian, []uint16{1, 0})
+	infe.WriteString("hvc1")
+	infe.WriteString("\x00")
+	image := buildBox("infe", infe.Bytes())
+
+	infe.Reset()
+	infe.Write([]byte{2, 0, 0, 0})
+	binary.Write(&infe, binary.BigEndian, []uint16{2, 0})
+	infe.WriteString("Exif")
+	infe.WriteString("\x00")
+	exifItem := buildBox("infe", infe.Bytes())
+
+	iinf := buildBox("iinf", []byte{0, 0, 0, 0, 0, 2}, image, exifItem)
+
+	item := append([]byte("\x00\x00\x00\x06Exif\x00\x00"), exif...)
+
+	// iloc version 1, 4-byte offsets and lengths, no base offset
+	iloc := func(exifOffset uint32) []byte {
+		var b bytes.Buffer
+		b.Write([]byte{1, 0, 0, 0, 0x44, 0x00})
+		binary.Write(&b, binary.BigEndian, uint16(2))
+		for _, entry := range []struct {
+			id             uint16
+			offset, length uint32
+		}{{1, 0, 0}, {2, exifOffset, uint32(len(item))}} {
+			binary.Write(&b, binary.BigEndian, []uint16{entry.id, 0, 0, 1})
+			binary.Write(&b, binary.BigEndian, []uint32{entry.offset, entry.length})
+		}
+		return buildBox("iloc", b.Bytes())
+	}
+
+	// The Exif item is stored in mdat, after ftyp and meta
+	meta := buildBox("meta", []byte{0, 0, 0, 0}, iinf, iloc(0))
+	exifOffset := uint32(len(ftyp) + len(meta) + 8)
+	meta = buildBox("meta", []byte{0, 0, 0, 0}, iinf, iloc(exifOffset))
+
+	return bytes.Join([][]byte{ftyp, meta, buildBox("mdat", item)}, nil)
+}
+
+func TestDateTaken(t *testing.T) {
+	taken := time.Date(2019, 7, 14, 9, 30, 15, 0, time.Local)
+	created := time.Date(2021, 3, 2, 18, 4, 5, 0, time.UTC)
+
+	original := map[uint16]string{tagDateTimeOriginal: "2019:07:14 09:30:15"}
+	withOffset := map[uint16]string{
+		tagDateTimeOriginal:   "2019:07:14 09:30:15",
+		tagOffsetTimeOriginal: "+02:00",
+	}
+	digitizedOnly := map[uint16]string{tagDateTimeDigitized: "2019:07:14 09:30:15"}
+	blank := map[uint16]string{tagDateTimeOriginal: "    :  :     :  :  "}
+
+	tests := []struct {
+		name     string
+		content  []byte
+		expected time.Time
+		wantNone bool
+	}{
+		{"jpeg little endian", buildJPEG(buildExif(binary.LittleEndian, original)), taken, false},
+		{"jpeg big endian", buildJPEG(buildExif(binary.BigEndian, original)), taken, false},
+		{"jpeg with offset time", buildJPEG(buildExif(binary.BigEndian, withOffset)), time.Date(2019, 7, 14, 7, 30, 15, 0, time.UTC), false},
+		{"jpeg falls back to digitized", buildJPEG(buildExif(binary.LittleEndian, digitizedOnly)), taken, false},
+		{"jpeg with blank date", buildJPEG(buildExif(binary.LittleEndian, blank)), time.Time{}, true},
+		{"jpeg without exif", buildJPEG(nil), time.Time{}, true},
+		{"tiff", buildExif(binary.LittleEndian, original), taken, false},
+		{"heic", buildHEIF(buildExif(binary.BigEndian, original)), taken, false},
+		{"mp4 version 0", buildMovie(0, created), created, false},
+		{"mp4 version 1", buildMovie(1, created), created, false},
+		{"mp4 without creation time", buildMovie(0, time.Time{}), time.Time{}, true},
+		{"truncated jpeg", buildJPEG(buildExif(binary.LittleEndian, original))[:30], time.Time{}, true},
+		{"text file", []byte("just some text"), time.Time{}, true},
+		{"empty file", nil, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			path := testutil.Path("/", "media", "file")
+			afero.WriteFile(fs, path, tt.content, 0644)
+
+			got, err := DateTaken(fs, path)
+			if tt.wantNone {
+				if !errors.Is(err, ErrNoDateTaken) {
+					t.Errorf("expected ErrNoDateTaken, got %v (%v)", err, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("DateTaken() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDateTaken_NonExistent(t *testing.T) {
+	_, err := DateTaken(afero.NewMemMapFs(), testutil.Path("/", "missing.jpg"))
+	if err == nil || errors.Is(err, ErrNoDateTaken) {
+		t.Errorf("expected a file error, got %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+package pathutil
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IsBelow reports whether path is inside the directory dir, and not dir
+// itself. Both paths must be clean.
+func IsBelow(dir, path string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -0,0 +1,61 @@
+package pathutil
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsBelow(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		path     string
+		expected bool
+	}{
+		{
+			name:     "child",
+			dir:      "/downloads",
+			path:     "/downloads/file.txt",
+			expected: true,
+		},
+		{
+			name:     "nested",
+			dir:      "/downloads",
+			path:     "/downloads/sub/file.txt",
+			expected: true,
+		},
+		{
+			name:     "same directory",
+			dir:      "/downloads",
+			path:     "/downloads",
+			expected: false,
+		},
+		{
+			name:     "sibling with shared prefix",
+			dir:      "/downloads",
+			path:     "/downloads-old/file.txt",
+			expected: false,
+		},
+		{
+			name:     "parent",
+			dir:      "/downloads/sub",
+			path:     "/downloads",
+			expected: false,
+		},
+		{
+			name:     "root",
+			dir:      "/",
+			path:     "/downloads",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, path := filepath.FromSlash(tt.dir), filepath.FromSlash(tt.path)
+			if got := IsBelow(dir, path); got != tt.expected {
+				t.Errorf("IsBelow(%q, %q) = %v, want %v", dir, path, got, tt.expected)
+			}
+		})
+	}
+}
@@ -51,8 +51,10 @@ const (
 // ActionResult describes the outcome of an action execution.
 type ActionResult struct {
 	Outcome ActionOutcome
-	NewPath string // Destination path for move/copy/rename
-	Error   string // Error message for failed actions
+	NewPath string   // Destination path for move/copy/rename
+	Detail  string   // Additional context, e.g. how a conflict was resolved
+	Error   string   // Error message for failed actions
+	Files   []string // Files produced by the action, e.g. extracted from an archive
 }
 
 // FilterDetail describes a filter or operator result with optional children.
@@ -19,6 +19,9 @@ var (
 	detailStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8")) // Gray
 )
 
+// maxListedFiles limits how many produced files are listed per action outside verbose mode.
+const maxListedFiles = 10
+
 const (
 	passIcon = "✓"
 	failIcon = "✗"
@@ -201,13 +204,33 @@ func (r *StructuredReporter) printFile() {
 		// Actions are at depth 0, add padding to align with deepest filters
 		extraPadding := maxDepth * 4
 		for _, a := range r.actions {
-			actionsBranch.AddNode(r.formatActionWithPadding(a, maxWidth, extraPadding))
+			line := r.formatActionWithPadding(a, maxWidth, extraPadding)
+			if len(a.result.Files) == 0 {
+				actionsBranch.AddNode(line)
+				continue
+			}
+			r.addFiles(actionsBranch.AddBranch(line), a.result.Files)
 		}
 	}
 
 	fmt.Fprint(r.w, tree.String())
 }
 
+// addFiles lists files produced by an action under its node.
+// Long lists are truncated unless verbose.
+func (r *StructuredReporter) addFiles(branch treeprint.Tree, files []string) {
+	shown := files
+	if !r.verbose && len(files) > maxListedFiles {
+		shown = files[:maxListedFiles]
+	}
+	for _, f := range shown {
+		branch.AddNode(detailStyle.Render(f))
+	}
+	if len(shown) < len(files) {
+		branch.AddNode(detailStyle.Render(fmt.Sprintf("… %d more", len(files)-len(shown))))
+	}
+}
+
 // calculateMaxWidth calculates the maximum name width for alignment.
 func (r *StructuredReporter) calculateMaxWidth() int {
 	maxWidth := 0
@@ -312,7 +335,11 @@ func (r *StructuredReporter) formatActionWithPadding(a actionEntry, maxWidth int
 
 	case OutcomeSkipped:
 		icon = skipStyle.Render(skipIcon)
-		status = "skipped " + detailStyle.Render("(destination exists)")
+		reason := "destination exists"
+		if a.result.Detail != "" {
+			reason = a.result.Detail
+		}
+		status = "skipped " + detailStyle.Render("("+reason+")")
 
 	case OutcomeFailed:
 		icon = failStyle.Render(failIcon)
@@ -322,6 +349,11 @@ func (r *StructuredReporter) formatActionWithPadding(a actionEntry, maxWidth int
 		}
 	}
 
+	// Skipped outcomes already include the detail as their reason
+	if a.result.Detail != "" && a.result.Outcome != OutcomeSkipped {
+		status += " " + detailStyle.Render("("+a.result.Detail+")")
+	}
+
 	totalWidth := maxWidth + 1 + extraPadding
 	return fmt.Sprintf("%-*s %s %s", totalWidth, a.name+":", icon, status)
 }
@@ -5,6 +5,7 @@ import (
 
 	"gopkg.in/yaml.v3"
 	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
 )
 
 // ExecutionResult represents the outcome of executing an action.
@@ -13,6 +14,25 @@ type ExecutionResult struct {
 	NewPath string // New path if file was moved/renamed
 	Deleted bool   // True if file was deleted (stop processing actions)
 	ConflictAlreadyExists bool // True if skipped because destination already exists
+	Trashed []string // Existing files that were trashed, e.g. to resolve a conflict
+	Detail  string // Optional context for reporting, e.g. how a conflict was resolved
+	Created []string // Files created by the action, e.g. extracted from an archive
+	Pending bool // The outcome is decided when the run finishes, see RunFinisher
+}
+
+// ItemError is an action failure that only affects the current item, such as
+// an external command exiting with a non-zero status. The runner reports it
+// and continues with the next item instead of stopping the rule.
+type ItemError struct {
+	Err error
+}
+
+func (e *ItemError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
 }
 
 // Executable is the interface that actions implement.
@@ -21,6 +41,50 @@ type Executable interface {
 	Execute(path string, filesystem fs.FileSystem) (*ExecutionResult, error)
 }
 
+// MatchExecutable is implemented by actions that use what the rule's filters
+// captured about the file, like named regex groups in templates.
+type MatchExecutable interface {
+	ExecuteMatch(path string, filesystem fs.FileSystem, match *Match) (*ExecutionResult, error)
+}
+
+// Templated is implemented by actions and filters with template parameters.
+// The templates are validated when the rule is loaded, so a typo in a
+// variable name fails early instead of ending up in a path.
+type Templated interface {
+	Templates() []utils.Template
+}
+
+// Destined is implemented by actions that put files at a path given by a
+// template, like move's dest. Validation reports relative destinations, which
+// would be resolved against the daemon's working directory.
+type Destined interface {
+	Destination() utils.Template
+}
+
+// RunScoped is implemented by filters and actions with state that lasts for
+// a single rule execution, such as the ${counter} sequence. RuleRunner.Execute
+// calls StartRun before traversing.
+type RunScoped interface {
+	StartRun()
+}
+
+// RunFinisher is implemented by actions that defer work to the end of a rule
+// execution, such as writing an archive once for every file added to it.
+// RuleRunner.Execute calls FinishRun after traversing, and reports the
+// returned outcomes: one for every file that got a Pending result, and one for
+// any other file whose deferred work failed.
+type RunFinisher interface {
+	FinishRun() []FinishedItem
+}
+
+// FinishedItem is the outcome of work deferred to the end of a run for the
+// file at Path. Exactly one of Result and Err is set.
+type FinishedItem struct {
+	Path   string
+	Result *ExecutionResult
+	Err    error
+}
+
 // ActionDeserializer is a function that creates an Executable from a YAML value.
 type ActionDeserializer func(value yaml.Node) (Executable, error)
 
@@ -43,6 +107,15 @@ func (a *Action) Execute(path string, filesystem fs.FileSystem) (*ExecutionResul
 	return a.Inner.Execute(path, filesystem)
 }
 
+// ExecuteMatch executes the action with the file's match context, when the
+// inner Executable uses one.
+func (a *Action) ExecuteMatch(path string, filesystem fs.FileSystem, match *Match) (*ExecutionResult, error) {
+	if m, ok := a.Inner.(MatchExecutable); ok {
+		return m.ExecuteMatch(path, filesystem, match)
+	}
+	return a.Inner.Execute(path, filesystem)
+}
+
 // UnmarshalYAML implements custom YAML unmarshaling for Action.
 // It supports two formats:
 //   - Scalar: "delete" or "trash" (for actions with no arguments)
@@ -58,11 +131,11 @@ func (a *Action) UnmarshalYAML(node *yaml.Node) error {
 			return fmt.Errorf("failed to decode action name: %w", err)
 		}
 		// Create a null node as the value
-		valueNode = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+		valueNode = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: node.Line, Column: node.Column}
 
 	case yaml.MappingNode:
 		if len(node.Content) != 2 {
-			return fmt.Errorf("action must have exactly one key, got %d", len(node.Content)/2)
+			return errorAt(node, fmt.Errorf("action must have exactly one key, got %d", len(node.Content)/2))
 		}
 		if err := node.Content[0].Decode(&name); err != nil {
 			return fmt.Errorf("failed to decode action name: %w", err)
@@ -70,7 +143,7 @@ func (a *Action) UnmarshalYAML(node *yaml.Node) error {
 		valueNode = *node.Content[1]
 
 	default:
-		return fmt.Errorf("action must be a string or mapping, got %v", node.Kind)
+		return errorAt(node, fmt.Errorf("action must be a string or mapping, got %v", node.Kind))
 	}
 
 	deserializer, ok := actionRegistry[name]
@@ -79,12 +152,12 @@ func (a *Action) UnmarshalYAML(node *yaml.Node) error {
 		for k := range actionRegistry {
 			available = append(available, k)
 		}
-		return fmt.Errorf("unknown action %q, available: %v", name, available)
+		return errorAt(node, fmt.Errorf("unknown action %q, available: %v", name, available))
 	}
 
 	inner, err := deserializer(valueNode)
 	if err != nil {
-		return fmt.Errorf("failed to deserialize action %q: %w", name, err)
+		return errorAt(&valueNode, fmt.Errorf("failed to deserialize action %q: %w", name, err))
 	}
 
 	a.Name = name
@@ -0,0 +1,557 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rules.RegisterAction("archive", deserializeArchive)
+}
+
+// Archive is an action that adds files to a zip or tar archive.
+// Existing archives are appended to; a new archive is created otherwise.
+// During a rule execution, the files added to an archive are collected in a
+// single rewrite of it, which is put in place when the run finishes. Removing
+// originals is reported as pending until then.
+type Archive struct {
+	Path            utils.Template
+	Format          ArchiveFormat   // Inferred from Path when empty
+	RemoveOriginals bool            // Remove files once they are verified in the archive
+	OnConflict      fs.ConflictMode // Handles entries with the same name, defaults to rename_with_suffix
+
+	templateScope
+
+	batchMu  sync.Mutex
+	inRun    bool                     // Whether batches are kept open until FinishRun
+	batches  map[string]*archiveBatch // Keyed by archive path
+	finished []rules.FinishedItem     // Outcomes of batches finished during the run
+}
+
+// Templates implements rules.Templated.
+func (a *Archive) Templates() []utils.Template {
+	return []utils.Template{a.Path}
+}
+
+// Destination implements rules.Destined.
+func (a *Archive) Destination() utils.Template {
+	return a.Path
+}
+
+// StartRun implements rules.RunScoped, batching additions until FinishRun.
+func (a *Archive) StartRun() {
+	a.templateScope.StartRun()
+
+	a.batchMu.Lock()
+	defer a.batchMu.Unlock()
+	for _, b := range a.batches {
+		b.abort()
+	}
+	a.batches = nil
+	a.finished = nil
+	a.inRun = true
+}
+
+// FinishRun implements rules.RunFinisher, putting every archive written
+// during the run in place and removing the originals added to it.
+func (a *Archive) FinishRun() []rules.FinishedItem {
+	a.batchMu.Lock()
+	defer a.batchMu.Unlock()
+
+	finished := a.finished
+	for _, path := range slices.Sorted(maps.Keys(a.batches)) {
+		finished = append(finished, a.batches[path].finish()...)
+	}
+	a.batches = nil
+	a.finished = nil
+	a.inRun = false
+	return finished
+}
+
+// getConflictMode returns the conflict mode, defaulting to rename_with_suffix.
+func (a *Archive) getConflictMode() fs.ConflictMode {
+	if a.OnConflict == "" {
+		return fs.ConflictRenameWithSuffix
+	}
+	return a.OnConflict
+}
+
+// archiveSource is a file, directory or symlink to be written into an archive.
+type archiveSource struct {
+	name string // Entry name inside the archive
+	path string
+	info os.FileInfo
+	link string // Target of symlinks
+}
+
+// Execute adds the file (or directory tree) to the archive.
+func (a *Archive) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionResult, error) {
+	return a.ExecuteMatch(path, filesystem, nil)
+}
+
+// ExecuteMatch implements rules.MatchExecutable, expanding variables captured by filters.
+func (a *Archive) ExecuteMatch(path string, filesystem fs.FileSystem, match *rules.Match) (*rules.ExecutionResult, error) {
+	expanded, err := a.Path.ExpandTilde().Expand(a.fileContext(filesystem, path, match))
+	if err != nil {
+		return nil, err
+	}
+	archivePath := expanded.ExpandWithTime().String()
+
+	// Skip the archive itself if the rule matches it
+	if path == archivePath {
+		return nil, nil
+	}
+	if strings.HasPrefix(archivePath, path+string(filepath.Separator)) {
+		return nil, fmt.Errorf("archive must not be inside the directory being archived: %s", archivePath)
+	}
+
+	format := a.Format
+	if format == "" {
+		detected, ok := detectArchiveFormat(archivePath)
+		if !ok {
+			return nil, fmt.Errorf("cannot infer archive format from %s", archivePath)
+		}
+		format = detected
+	}
+
+	sources, err := collectArchiveSources(filesystem, path, filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+
+	a.batchMu.Lock()
+	defer a.batchMu.Unlock()
+
+	b := a.batches[archivePath]
+	if b == nil {
+		if b, err = startArchiveBatch(filesystem, archivePath, format, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve conflicts with the entries written so far
+	entryName := sources[0].name
+	if entryTaken(b.names, entryName) {
+		switch a.getConflictMode() {
+		case fs.ConflictSkip:
+			if a.inRun {
+				a.keepBatch(b)
+			} else {
+				b.abort()
+			}
+			return &rules.ExecutionResult{
+				ConflictAlreadyExists: true,
+				Detail:                "already in " + archivePath,
+			}, nil
+		case fs.ConflictOverwrite:
+			// Entries can't be removed from an archive being written, so
+			// finish it and start another without the one being replaced
+			if a.batches[archivePath] != b {
+				b.abort()
+			} else {
+				a.finished = append(a.finished, b.finish()...)
+			}
+			if b, err = startArchiveBatch(filesystem, archivePath, format, entryName); err != nil {
+				delete(a.batches, archivePath)
+				return nil, err
+			}
+		default:
+			for i := 2; ; i++ {
+				candidate := fs.GenerateSuffixedPath(entryName, i)
+				if !entryTaken(b.names, candidate) {
+					renameArchiveSources(sources, entryName, candidate)
+					break
+				}
+			}
+		}
+	}
+
+	if err := b.add(sources); err != nil {
+		// The files added before this one are lost with the batch
+		a.finished = append(a.finished, b.failed(fmt.Errorf("%s was not updated: %w", archivePath, err), true)...)
+		b.abort()
+		delete(a.batches, archivePath)
+		return nil, err
+	}
+	b.items = append(b.items, archiveItem{path: path, remove: a.RemoveOriginals})
+
+	if a.inRun {
+		a.keepBatch(b)
+		if a.RemoveOriginals {
+			return &rules.ExecutionResult{Pending: true, Detail: "archiving to " + archivePath}, nil
+		}
+		return &rules.ExecutionResult{Detail: "added to " + archivePath}, nil
+	}
+
+	// Outside of a run the archive is written right away
+	if finished := b.finish(); len(finished) > 0 {
+		return finished[0].Result, finished[0].Err
+	}
+	return &rules.ExecutionResult{Detail: "added to " + archivePath}, nil
+}
+
+// keepBatch keeps b open for the rest of the run.
+func (a *Archive) keepBatch(b *archiveBatch) {
+	if a.batches == nil {
+		a.batches = map[string]*archiveBatch{}
+	}
+	a.batches[b.path] = b
+}
+
+// entryTaken reports whether name is already used by a file or directory entry.
+func entryTaken(existing map[string]bool, name string) bool {
+	if existing[name] {
+		return true
+	}
+	for e := range existing {
+		if strings.HasPrefix(e, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isEntryOrChild reports whether an archive member is name or lives under it.
+func isEntryOrChild(member, name string) bool {
+	member = strings.TrimSuffix(member, "/")
+	return member == name || strings.HasPrefix(member, name+"/")
+}
+
+// collectArchiveSources lists path and, for directories, everything beneath it.
+// Symlinks are archived as links rather than followed. Entry names always use
+// forward slashes.
+func collectArchiveSources(filesystem fs.FileSystem, path, name string) ([]archiveSource, error) {
+	info, err := fs.Lstat(filesystem, path)
+	if err != nil {
+		return nil, err
+	}
+
+	source := archiveSource{name: name, path: path, info: info}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		if source.link, err = fs.Readlink(filesystem, path); err != nil {
+			return nil, err
+		}
+		return []archiveSource{source}, nil
+	case info.IsDir():
+	case !info.Mode().IsRegular():
+		return nil, fmt.Errorf("cannot archive special file: %s", path)
+	default:
+		return []archiveSource{source}, nil
+	}
+
+	sources := []archiveSource{source}
+	entries, err := afero.ReadDir(filesystem, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		children, err := collectArchiveSources(filesystem, filepath.Join(path, entry.Name()), name+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, children...)
+	}
+	return sources, nil
+}
+
+// renameArchiveSources renames the entry from and everything beneath it to.
+func renameArchiveSources(sources []archiveSource, from, to string) {
+	for i := range sources {
+		sources[i].name = to + strings.TrimPrefix(sources[i].name, from)
+	}
+}
+
+// archiveBatch is a new copy of an archive being written, holding its
+// existing members followed by the files added to it. It is written to a
+// temporary file and renamed into place so a failure never corrupts the
+// original.
+type archiveBatch struct {
+	filesystem fs.FileSystem
+	path       string
+	format     ArchiveFormat
+	tmp        string
+	file       afero.File
+	w          archiveWriter
+	names      map[string]bool // Entries written so far, without trailing slashes
+	sources    []archiveSource // Added files, verified before originals are removed
+	items      []archiveItem   // Files added to the batch, in order
+}
+
+// archiveItem is a file added to an archive batch.
+type archiveItem struct {
+	path   string
+	remove bool // Whether the original is removed once the archive is in place
+}
+
+// startArchiveBatch starts a new copy of the archive at archivePath with its
+// existing members, leaving out the entry drop and everything beneath it.
+func startArchiveBatch(filesystem fs.FileSystem, archivePath string, format ArchiveFormat, drop string) (*archiveBatch, error) {
+	if err := filesystem.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return nil, err
+	}
+	exists, err := afero.Exists(filesystem, archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp := filepath.Join(filepath.Dir(archivePath), "."+filepath.Base(archivePath)+".partial")
+	f, err := filesystem.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	b := &archiveBatch{
+		filesystem: filesystem,
+		path:       archivePath,
+		format:     format,
+		tmp:        tmp,
+		file:       f,
+		names:      map[string]bool{},
+	}
+	if b.w, err = newArchiveWriter(f, format); err != nil {
+		b.abort()
+		return nil, err
+	}
+
+	if exists {
+		err := walkArchive(filesystem, archivePath, format, func(m archiveMember) error {
+			if drop != "" && isEntryOrChild(m.Name, drop) {
+				return nil
+			}
+			b.names[strings.TrimSuffix(m.Name, "/")] = true
+			return b.w.copy(m)
+		})
+		if err != nil {
+			b.abort()
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// add writes sources to the archive.
+func (b *archiveBatch) add(sources []archiveSource) error {
+	for _, s := range sources {
+		if err := addArchiveSource(b.filesystem, b.w, s); err != nil {
+			return err
+		}
+		b.names[s.name] = true
+	}
+	b.sources = append(b.sources, sources...)
+	return nil
+}
+
+// finish puts the archive in place, then removes the originals once every
+// added file is verified in it. It returns the outcome of each original to
+// remove, and of every added file if the archive couldn't be written.
+func (b *archiveBatch) finish() []rules.FinishedItem {
+	err := b.w.Close()
+	if closeErr := b.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = b.filesystem.Rename(b.tmp, b.path)
+	}
+	if err != nil {
+		b.filesystem.Remove(b.tmp)
+		return b.failed(fmt.Errorf("%s was not updated: %w", b.path, err), true)
+	}
+
+	if !slices.ContainsFunc(b.items, func(item archiveItem) bool { return item.remove }) {
+		return nil
+	}
+	if err := verifyArchived(b.filesystem, b.path, b.format, b.sources); err != nil {
+		return b.failed(fmt.Errorf("original kept: %w", err), false)
+	}
+
+	var finished []rules.FinishedItem
+	for _, item := range b.items {
+		if !item.remove {
+			continue
+		}
+		if err := b.filesystem.RemoveAll(item.path); err != nil {
+			finished = append(finished, rules.FinishedItem{Path: item.path, Err: err})
+			continue
+		}
+		finished = append(finished, rules.FinishedItem{
+			Path: item.path,
+			Result: &rules.ExecutionResult{
+				NewPath: b.path,
+				Deleted: true,
+				Detail:  "archived to " + b.path,
+			},
+		})
+	}
+	return finished
+}
+
+// failed returns err as the outcome of the files added to the batch, or only
+// of those whose originals were to be removed unless all is set.
+func (b *archiveBatch) failed(err error, all bool) []rules.FinishedItem {
+	var finished []rules.FinishedItem
+	for _, item := range b.items {
+		if all || item.remove {
+			finished = append(finished, rules.FinishedItem{Path: item.path, Err: err})
+		}
+	}
+	return finished
+}
+
+// abort discards the archive being written, leaving the original untouched.
+func (b *archiveBatch) abort() {
+	b.file.Close()
+	b.filesystem.Remove(b.tmp)
+}
+
+// addArchiveSource writes a file, directory or symlink from the filesystem to w.
+func addArchiveSource(filesystem fs.FileSystem, w archiveWriter, s archiveSource) error {
+	if s.info.IsDir() || s.link != "" {
+		return w.add(s.name, s.info, s.link, nil)
+	}
+	f, err := filesystem.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return w.add(s.name, s.info, "", f)
+}
+
+// verifyArchived checks that every file in sources is in the archive with
+// identical contents, and every symlink with the same target.
+func verifyArchived(filesystem fs.FileSystem, archivePath string, format ArchiveFormat, sources []archiveSource) error {
+	pending := map[string]archiveSource{}
+	for _, s := range sources {
+		if !s.info.IsDir() {
+			pending[s.name] = s
+		}
+	}
+
+	err := walkArchive(filesystem, archivePath, format, func(m archiveMember) error {
+		s, ok := pending[m.Name]
+		if !ok || m.Info.IsDir() {
+			return nil
+		}
+
+		if s.link != "" {
+			if m.Info.Mode()&os.ModeSymlink == 0 {
+				return nil
+			}
+			link, err := memberLink(m)
+			if err != nil {
+				return err
+			}
+			if link == s.link {
+				delete(pending, m.Name)
+			}
+			return nil
+		}
+
+		rc, err := m.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		archived, err := hashReader(rc)
+		if err != nil {
+			return err
+		}
+
+		f, err := filesystem.Open(s.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		original, err := hashReader(f)
+		if err != nil {
+			return err
+		}
+
+		if archived == original {
+			delete(pending, m.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for name := range pending {
+		return fmt.Errorf("archive verification failed: %s is missing or differs in %s", name, archivePath)
+	}
+	return nil
+}
+
+// hashReader returns the SHA-256 digest of everything read from r.
+func hashReader(r io.Reader) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// deserializeArchive creates an Archive action from YAML.
+// Supports both "archive: ~/Archive/%Y-%m.zip" and
+// "archive: {path: ~/Archive/%Y-%m.tar.gz, remove_originals: true}".
+func deserializeArchive(node yaml.Node) (rules.Executable, error) {
+	var a Archive
+
+	if node.Kind == yaml.ScalarNode {
+		var path string
+		if err := node.Decode(&path); err != nil {
+			return nil, err
+		}
+		a.Path = utils.Template(path)
+	} else {
+		var m struct {
+			Path            utils.Template  `yaml:"path"`
+			Format          ArchiveFormat   `yaml:"format"`
+			RemoveOriginals bool            `yaml:"remove_originals"`
+			OnConflict      fs.ConflictMode `yaml:"on_conflict"`
+		}
+		if err := rules.DecodeKnownFields(&node, &m); err != nil {
+			return nil, err
+		}
+		a = Archive{Path: m.Path, Format: m.Format, RemoveOriginals: m.RemoveOriginals, OnConflict: m.OnConflict}
+	}
+
+	if a.Path == "" {
+		return nil, fmt.Errorf("archive action requires path")
+	}
+
+	format := a.Format
+	if format == "" {
+		detected, ok := detectArchiveFormat(a.Path.String())
+		if !ok {
+			return nil, fmt.Errorf("cannot infer archive format from %q: use a .zip, .tar or .tar.gz extension or set format", a.Path)
+		}
+		format = detected
+	}
+	if err := validateArchiveFormat(format); err != nil {
+		return nil, err
+	}
+
+	switch a.OnConflict {
+	case "", fs.ConflictRenameWithSuffix, fs.ConflictSkip, fs.ConflictOverwrite:
+	default:
+		return nil, fmt.Errorf("invalid archive on_conflict %q: must be rename_with_suffix, skip or overwrite", a.OnConflict)
+	}
+
+	return &a, nil
+}
@@ -0,0 +1,294 @@
+package actions
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+
+	"github.com/ulikunitz/xz"
+)
+
+// ArchiveFormat identifies a supported archive container.
+type ArchiveFormat string
+
+const (
+	FormatZip    ArchiveFormat = "zip"
+	FormatTar    ArchiveFormat = "tar"
+	FormatTarGz  ArchiveFormat = "tar.gz"
+	FormatTarBz2 ArchiveFormat = "tar.bz2" // Read-only
+	FormatTarXz  ArchiveFormat = "tar.xz"  // Read-only
+)
+
+// archiveExtensions maps filename suffixes to formats.
+// Longer suffixes are listed first so .tar.gz wins over .gz.
+var archiveExtensions = []struct {
+	suffix string
+	format ArchiveFormat
+}{
+	{".tar.gz", FormatTarGz},
+	{".tgz", FormatTarGz},
+	{".tar.bz2", FormatTarBz2},
+	{".tbz2", FormatTarBz2},
+	{".tar.xz", FormatTarXz},
+	{".txz", FormatTarXz},
+	{".tar", FormatTar},
+	{".zip", FormatZip},
+}
+
+// detectArchiveFormat infers the archive format from a filename.
+func detectArchiveFormat(path string) (ArchiveFormat, bool) {
+	lower := strings.ToLower(path)
+	for _, e := range archiveExtensions {
+		if strings.HasSuffix(lower, e.suffix) {
+			return e.format, true
+		}
+	}
+	return "", false
+}
+
+// trimArchiveExtension removes a recognized archive extension from a filename,
+// so "photos.tar.gz" becomes "photos".
+func trimArchiveExtension(filename string) string {
+	lower := strings.ToLower(filename)
+	for _, e := range archiveExtensions {
+		if strings.HasSuffix(lower, e.suffix) {
+			return filename[:len(filename)-len(e.suffix)]
+		}
+	}
+	return filename
+}
+
+// validateArchiveFormat checks that a format value is supported for writing.
+func validateArchiveFormat(format ArchiveFormat) error {
+	switch format {
+	case FormatZip, FormatTar, FormatTarGz:
+		return nil
+	default:
+		return fmt.Errorf("invalid archive format %q: must be zip, tar or tar.gz", format)
+	}
+}
+
+// archiveMember is a single entry read from an archive.
+type archiveMember struct {
+	Name string
+	Info os.FileInfo
+	Open func() (io.ReadCloser, error)
+
+	zipFile *zip.File // Set for zip members, so they can be copied without recompressing
+}
+
+// walkArchive calls fn for every member of an archive, in order.
+func walkArchive(filesystem fs.FileSystem, path string, format ArchiveFormat, fn func(m archiveMember) error) error {
+	f, err := filesystem.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if format == FormatZip {
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return fmt.Errorf("failed to read zip archive %s: %w", path, err)
+		}
+		for _, zf := range zr.File {
+			if err := fn(archiveMember{Name: zf.Name, Info: zf.FileInfo(), Open: zf.Open, zipFile: zf}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	r, closeReader, err := decompressReader(f, format)
+	if err != nil {
+		return fmt.Errorf("failed to read archive %s: %w", path, err)
+	}
+	defer closeReader()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive %s: %w", path, err)
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+		if err := fn(archiveMember{Name: hdr.Name, Info: hdr.FileInfo(), Open: open}); err != nil {
+			return err
+		}
+	}
+}
+
+// memberLink returns the target of a symlink member. Tar keeps it in the
+// header, while zip stores it as the member's contents.
+func memberLink(m archiveMember) (string, error) {
+	if hdr, ok := m.Info.Sys().(*tar.Header); ok {
+		return hdr.Linkname, nil
+	}
+	rc, err := m.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	return string(data), err
+}
+
+// decompressReader wraps r with the decompressor for a tar-based format.
+func decompressReader(r io.Reader, format ArchiveFormat) (io.Reader, func(), error) {
+	switch format {
+	case FormatTar:
+		return r, func() {}, nil
+	case FormatTarGz:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gz, func() { gz.Close() }, nil
+	case FormatTarBz2:
+		return bzip2.NewReader(r), func() {}, nil
+	case FormatTarXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return xr, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+}
+
+// archiveWriter adds entries to a new archive.
+type archiveWriter interface {
+	// add writes an entry. link is the target of symlinks, and r the contents
+	// of regular files.
+	add(name string, info os.FileInfo, link string, r io.Reader) error
+	// copy writes a member read from an archive of the same format.
+	copy(m archiveMember) error
+	Close() error
+}
+
+// newArchiveWriter creates a writer for the given format.
+func newArchiveWriter(w io.Writer, format ArchiveFormat) (archiveWriter, error) {
+	switch format {
+	case FormatZip:
+		return &zipArchiveWriter{zw: zip.NewWriter(w)}, nil
+	case FormatTar:
+		return &tarArchiveWriter{tw: tar.NewWriter(w)}, nil
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarArchiveWriter{tw: tar.NewWriter(gz), gz: gz}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (z *zipArchiveWriter) add(name string, info os.FileInfo, link string, r io.Reader) error {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	switch {
+	case info.IsDir():
+		hdr.Name = dirEntryName(name)
+		hdr.Method = zip.Store
+	case info.Mode()&os.ModeSymlink != 0:
+		// Zip stores the link target as the member's contents
+		hdr.Method = zip.Store
+		r = strings.NewReader(link)
+	default:
+		hdr.Method = zip.Deflate
+	}
+
+	w, err := z.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() || r == nil {
+		return nil
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// copy writes the member's compressed data as is.
+func (z *zipArchiveWriter) copy(m archiveMember) error {
+	return z.zw.Copy(m.zipFile)
+}
+
+func (z *zipArchiveWriter) Close() error {
+	return z.zw.Close()
+}
+
+type tarArchiveWriter struct {
+	tw *tar.Writer
+	gz *gzip.Writer // nil for uncompressed tar
+}
+
+func (t *tarArchiveWriter) add(name string, info os.FileInfo, link string, r io.Reader) error {
+	// FileInfoHeader copies link targets and ownership when info came from a tar member
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name = dirEntryName(name)
+	}
+
+	if err := t.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if hdr.Typeflag != tar.TypeReg || r == nil {
+		return nil
+	}
+	_, err = io.Copy(t.tw, r)
+	return err
+}
+
+func (t *tarArchiveWriter) copy(m archiveMember) error {
+	if m.Info.IsDir() {
+		return t.add(m.Name, m.Info, "", nil)
+	}
+	rc, err := m.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return t.add(m.Name, m.Info, "", rc)
+}
+
+func (t *tarArchiveWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	if t.gz != nil {
+		return t.gz.Close()
+	}
+	return nil
+}
+
+// dirEntryName returns name with the trailing slash archives use for directories.
+func dirEntryName(name string) string {
+	if strings.HasSuffix(name, "/") {
+		return name
+	}
+	return name + "/"
+}
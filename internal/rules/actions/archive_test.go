@@ -0,0 +1,475 @@
+package actions
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// readArchive returns the contents of every file entry in an archive, keyed by name.
+func readArchive(t *testing.T, filesystem fs.FileSystem, path string, format ArchiveFormat) map[string]string {
+	t.Helper()
+	contents := map[string]string{}
+	err := walkArchive(filesystem, path, format, func(m archiveMember) error {
+		if m.Info.IsDir() {
+			contents[m.Name] = ""
+			return nil
+		}
+		rc, err := m.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		contents[m.Name] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	return contents
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestArchive_Execute_Appends(t *testing.T) {
+	for _, format := range []ArchiveFormat{FormatZip, FormatTar, FormatTarGz} {
+		t.Run(string(format), func(t *testing.T) {
+			dir := testutil.Path("/", "screens")
+			archivePath := testutil.Path("/", "archive", "screens."+string(format))
+
+			filesystem := fs.NewMem()
+			filesystem.MkdirAll(dir, 0755)
+			afero.WriteFile(filesystem, testutil.Path(dir, "a.png"), []byte("aaa"), 0644)
+			afero.WriteFile(filesystem, testutil.Path(dir, "b.png"), []byte("bbb"), 0644)
+
+			a := &Archive{Path: utils.Template(archivePath)}
+			for _, name := range []string{"a.png", "b.png"} {
+				result, err := a.Execute(testutil.Path(dir, name), filesystem)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if result == nil || result.Deleted || result.NewPath != "" {
+					t.Fatalf("expected originals to be kept, got %+v", result)
+				}
+			}
+
+			contents := readArchive(t, filesystem, archivePath, format)
+			if contents["a.png"] != "aaa" || contents["b.png"] != "bbb" || len(contents) != 2 {
+				t.Errorf("unexpected archive contents: %v", contents)
+			}
+
+			if exists, _ := afero.Exists(filesystem, testutil.Path(dir, "a.png")); !exists {
+				t.Error("original should be kept")
+			}
+			partial := testutil.Path("/", "archive", ".screens."+string(format)+".partial")
+			if exists, _ := afero.Exists(filesystem, partial); exists {
+				t.Error("temporary archive should not remain")
+			}
+		})
+	}
+}
+
+func TestArchive_Execute_RemoveOriginals(t *testing.T) {
+	dir := testutil.Path("/", "screens")
+	src := testutil.Path(dir, "shot.png")
+	archivePath := testutil.Path("/", "archive", "screens.zip")
+
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll(dir, 0755)
+	afero.WriteFile(filesystem, src, []byte("png"), 0644)
+
+	a := &Archive{Path: utils.Template(archivePath), RemoveOriginals: true}
+	result, err := a.Execute(src, filesystem)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result == nil || !result.Deleted || result.NewPath != archivePath {
+		t.Fatalf("expected deleted result pointing at archive, got %+v", result)
+	}
+
+	if exists, _ := afero.Exists(filesystem, src); exists {
+		t.Error("original should be removed")
+	}
+	if contents := readArchive(t, filesystem, archivePath, FormatZip); contents["shot.png"] != "png" {
+		t.Errorf("unexpected archive contents: %v", contents)
+	}
+}
+
+func TestArchive_Execute_Directory(t *testing.T) {
+	dir := testutil.Path("/", "projects", "old")
+	archivePath := testutil.Path("/", "archive", "projects.tar.gz")
+
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll(testutil.Path(dir, "src"), 0755)
+	afero.WriteFile(filesystem, testutil.Path(dir, "README"), []byte("readme"), 0644)
+	afero.WriteFile(filesystem, testutil.Path(dir, "src", "main.go"), []byte("package main"), 0644)
+
+	a := &Archive{Path: utils.Template(archivePath), RemoveOriginals: true}
+	if _, err := a.Execute(dir, filesystem); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	contents := readArchive(t, filesystem, archivePath, FormatTarGz)
+	expected := []string{"old/", "old/README", "old/src/", "old/src/main.go"}
+	if got := sortedKeys(contents); len(got) != len(expected) {
+		t.Fatalf("entries = %v, want %v", got, expected)
+	} else {
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Errorf("entries = %v, want %v", got, expected)
+				break
+			}
+		}
+	}
+	if contents["old/src/main.go"] != "package main" {
+		t.Errorf("main.go content = %q", contents["old/src/main.go"])
+	}
+	if exists, _ := afero.Exists(filesystem, dir); exists {
+		t.Error("directory should be removed")
+	}
+}
+
+func TestArchive_Run(t *testing.T) {
+	dir := testutil.Path("/", "screens")
+	archivePath := testutil.Path("/", "archive", "screens.zip")
+
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll(testutil.Path(dir, "sub"), 0755)
+	afero.WriteFile(filesystem, testutil.Path(dir, "a.png"), []byte("a"), 0644)
+	afero.WriteFile(filesystem, testutil.Path(dir, "b.png"), []byte("b"), 0644)
+	afero.WriteFile(filesystem, testutil.Path(dir, "sub", "a.png"), []byte("sub a"), 0644)
+
+	a := &Archive{Path: utils.Template(archivePath), RemoveOriginals: true}
+	a.StartRun()
+	for _, name := range []string{"a.png", "b.png", "sub/a.png"} {
+		result, err := a.Execute(testutil.Path(dir, name), filesystem)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result == nil || !result.Pending || result.Deleted {
+			t.Errorf("result = %+v, want pending until the run finishes", result)
+		}
+	}
+
+	// Nothing is put in place or removed until the run finishes
+	if exists, _ := afero.Exists(filesystem, archivePath); exists {
+		t.Error("archive should not exist before the run finishes")
+	}
+	if exists, _ := afero.Exists(filesystem, testutil.Path(dir, "a.png")); !exists {
+		t.Error("original should be kept until the run finishes")
+	}
+
+	finished := a.FinishRun()
+	if len(finished) != 3 {
+		t.Fatalf("expected 3 finished items, got %d", len(finished))
+	}
+	for _, item := range finished {
+		if item.Err != nil {
+			t.Errorf("%s: unexpected error: %v", item.Path, item.Err)
+		} else if !item.Result.Deleted || item.Result.NewPath != archivePath {
+			t.Errorf("%s: result = %+v, want deleted into the archive", item.Path, item.Result)
+		}
+	}
+
+	contents := readArchive(t, filesystem, archivePath, FormatZip)
+	expected := map[string]string{"a.png": "a", "b.png": "b", "a_2.png": "sub a"}
+	if len(contents) != len(expected) {
+		t.Errorf("entries = %v, want %v", sortedKeys(contents), expected)
+	}
+	for name, content := range expected {
+		if contents[name] != content {
+			t.Errorf("%s content = %q, want %q", name, contents[name], content)
+		}
+	}
+	for _, name := range []string{"a.png", "b.png", "sub/a.png"} {
+		if exists, _ := afero.Exists(filesystem, testutil.Path(dir, name)); exists {
+			t.Errorf("%s should be removed", name)
+		}
+	}
+	if exists, _ := afero.Exists(filesystem, testutil.Path("/", "archive", ".screens.zip.partial")); exists {
+		t.Error("partial archive should be removed")
+	}
+}
+
+// failingRenameFs fails every rename, like a full or read-only destination.
+type failingRenameFs struct {
+	fs.FileSystem
+}
+
+func (f failingRenameFs) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrPermission}
+}
+
+func TestArchive_Run_FailedFinishKeepsOriginals(t *testing.T) {
+	dir := testutil.Path("/", "screens")
+	archivePath := testutil.Path("/", "archive", "screens.zip")
+
+	mem := fs.NewMem()
+	mem.MkdirAll(dir, 0755)
+	afero.WriteFile(mem, testutil.Path(dir, "a.png"), []byte("a"), 0644)
+	afero.WriteFile(mem, testutil.Path(dir, "b.png"), []byte("b"), 0644)
+	filesystem := failingRenameFs{FileSystem: mem}
+
+	a := &Archive{Path: utils.Template(archivePath), RemoveOriginals: true}
+	a.StartRun()
+	for _, name := range []string{"a.png", "b.png"} {
+		if _, err := a.Execute(testutil.Path(dir, name), filesystem); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Every original is reported as failed, not deleted
+	finished := a.FinishRun()
+	if len(finished) != 2 {
+		t.Fatalf("expected 2 finished items, got %d", len(finished))
+	}
+	for _, item := range finished {
+		if item.Err == nil || item.Result != nil {
+			t.Errorf("%s: expected only an error, got %+v", item.Path, item)
+		}
+		if exists, _ := afero.Exists(mem, item.Path); !exists {
+			t.Errorf("%s should be kept", item.Path)
+		}
+	}
+	if exists, _ := afero.Exists(mem, archivePath); exists {
+		t.Error("archive should not be put in place")
+	}
+}
+
+func TestArchive_Execute_Symlinks(t *testing.T) {
+	for _, format := range []ArchiveFormat{FormatZip, FormatTarGz} {
+		t.Run(string(format), func(t *testing.T) {
+			tmp := t.TempDir()
+			dir := filepath.Join(tmp, "project")
+			os.MkdirAll(dir, 0755)
+			os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("hello"), 0644)
+			if err := os.Symlink("readme.txt", filepath.Join(dir, "link")); err != nil {
+				t.Skipf("cannot create symlink: %v", err)
+			}
+			archivePath := filepath.Join(tmp, "project."+string(format))
+
+			filesystem := fs.NewReal()
+			a := &Archive{Path: utils.Template(archivePath), RemoveOriginals: true}
+			if _, err := a.Execute(dir, filesystem); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var link string
+			err := walkArchive(filesystem, archivePath, format, func(m archiveMember) error {
+				if m.Name != "project/link" {
+					return nil
+				}
+				if m.Info.Mode()&os.ModeSymlink == 0 {
+					t.Errorf("link mode = %v, want a symlink", m.Info.Mode())
+				}
+				var err error
+				link, err = memberLink(m)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("failed to read archive: %v", err)
+			}
+			if link != "readme.txt" {
+				t.Errorf("link target = %q, want %q", link, "readme.txt")
+			}
+			if _, err := os.Lstat(dir); !os.IsNotExist(err) {
+				t.Error("directory should be removed")
+			}
+		})
+	}
+}
+
+func TestArchive_Execute_Conflicts(t *testing.T) {
+	tests := []struct {
+		name         string
+		onConflict   fs.ConflictMode
+		expectSkip   bool
+		expectedKeys []string
+		expectedFile string // Content of file.txt in the archive
+	}{
+		{"rename_with_suffix is default", "", false, []string{"file.txt", "file_2.txt"}, "old"},
+		{"skip", fs.ConflictSkip, true, []string{"file.txt"}, "old"},
+		{"overwrite", fs.ConflictOverwrite, false, []string{"file.txt"}, "new"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.Path("/", "dir")
+			src := testutil.Path(dir, "file.txt")
+			archivePath := testutil.Path("/", "archive.zip")
+
+			filesystem := fs.NewMem()
+			filesystem.MkdirAll(dir, 0755)
+
+			// Seed the archive with an older file.txt
+			afero.WriteFile(filesystem, src, []byte("old"), 0644)
+			a := &Archive{Path: utils.Template(archivePath), OnConflict: tt.onConflict}
+			if _, err := a.Execute(src, filesystem); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			afero.WriteFile(filesystem, src, []byte("new"), 0644)
+			result, err := a.Execute(src, filesystem)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.ConflictAlreadyExists != tt.expectSkip {
+				t.Errorf("ConflictAlreadyExists = %v, want %v", result.ConflictAlreadyExists, tt.expectSkip)
+			}
+
+			contents := readArchive(t, filesystem, archivePath, FormatZip)
+			got := sortedKeys(contents)
+			if len(got) != len(tt.expectedKeys) {
+				t.Fatalf("entries = %v, want %v", got, tt.expectedKeys)
+			}
+			for i := range got {
+				if got[i] != tt.expectedKeys[i] {
+					t.Errorf("entries = %v, want %v", got, tt.expectedKeys)
+					break
+				}
+			}
+			if contents["file.txt"] != tt.expectedFile {
+				t.Errorf("file.txt = %q, want %q", contents["file.txt"], tt.expectedFile)
+			}
+		})
+	}
+}
+
+func TestArchive_Execute_DryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "file.txt")
+	archivePath := filepath.Join(tmpDir, "archive", "files.zip")
+	os.WriteFile(src, []byte("content"), 0644)
+
+	filesystem := fs.NewDryRun()
+	a := &Archive{Path: utils.Template(archivePath), RemoveOriginals: true}
+	result, err := a.Execute(src, filesystem)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result == nil || !result.Deleted {
+		t.Fatalf("expected preview to report removal, got %+v", result)
+	}
+
+	// The preview sees the archive, but nothing on disk changes
+	if contents := readArchive(t, filesystem, archivePath, FormatZip); contents["file.txt"] != "content" {
+		t.Errorf("unexpected preview contents: %v", contents)
+	}
+	if _, err := os.Stat(archivePath); !os.IsNotExist(err) {
+		t.Error("archive should not be created on disk")
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Error("original should still exist on disk")
+	}
+}
+
+func TestArchive_Execute_SkipsArchiveItself(t *testing.T) {
+	archivePath := testutil.Path("/", "archive.zip")
+	filesystem := fs.NewMem()
+	afero.WriteFile(filesystem, archivePath, []byte{}, 0644)
+
+	a := &Archive{Path: utils.Template(archivePath)}
+	result, err := a.Execute(archivePath, filesystem)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != nil {
+		t.Errorf("expected nil result, got %+v", result)
+	}
+}
+
+func TestDeserializeArchive(t *testing.T) {
+	tests := []struct {
+		name            string
+		yaml            string
+		expectedPath    string
+		expectedFormat  ArchiveFormat
+		removeOriginals bool
+		wantErr         bool
+	}{
+		{
+			name:         "scalar string",
+			yaml:         "archive: ~/Archive/screens-%Y-%m.zip",
+			expectedPath: "~/Archive/screens-%Y-%m.zip",
+		},
+		{
+			name:            "mapping with options",
+			yaml:            "archive:\n  path: /archive/files\n  format: tar.gz\n  remove_originals: true",
+			expectedPath:    "/archive/files",
+			expectedFormat:  FormatTarGz,
+			removeOriginals: true,
+		},
+		{
+			name:    "format cannot be inferred",
+			yaml:    "archive: /archive/files.rar",
+			wantErr: true,
+		},
+		{
+			name:    "invalid format",
+			yaml:    "archive:\n  path: /archive/files\n  format: rar",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported on_conflict",
+			yaml:    "archive:\n  path: /archive/files.zip\n  on_conflict: keep_newer",
+			wantErr: true,
+		},
+		{
+			name:    "missing path",
+			yaml:    "archive:\n  remove_originals: true",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a rules.Action
+			err := yaml.Unmarshal([]byte(tt.yaml), &a)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			archive, ok := a.Inner.(*Archive)
+			if !ok {
+				t.Fatalf("inner is not *Archive, got %T", a.Inner)
+			}
+			if archive.Path.String() != tt.expectedPath {
+				t.Errorf("Path = %q, want %q", archive.Path, tt.expectedPath)
+			}
+			if archive.Format != tt.expectedFormat {
+				t.Errorf("Format = %q, want %q", archive.Format, tt.expectedFormat)
+			}
+			if archive.RemoveOriginals != tt.removeOriginals {
+				t.Errorf("RemoveOriginals = %v, want %v", archive.RemoveOriginals, tt.removeOriginals)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+)
+
+// IdenticalMode defines what happens to the source when skip_if_identical
+// finds that the destination already has the same contents.
+type IdenticalMode string
+
+const (
+	IdenticalKeep   IdenticalMode = "keep"   // Leave the source in place
+	IdenticalDelete IdenticalMode = "delete" // Delete the redundant source
+)
+
+// validateIdenticalMode checks that an on_identical value is supported.
+func validateIdenticalMode(mode IdenticalMode) error {
+	switch mode {
+	case "", IdenticalKeep, IdenticalDelete:
+		return nil
+	default:
+		return fmt.Errorf("invalid on_identical %q: must be keep or delete", mode)
+	}
+}
+
+// validateConflictMode checks that an on_conflict value is supported.
+func validateConflictMode(mode fs.ConflictMode) error {
+	switch mode {
+	case "", fs.ConflictRenameWithSuffix, fs.ConflictSkip, fs.ConflictOverwrite, fs.ConflictTrash,
+		fs.ConflictSkipIfIdentical, fs.ConflictKeepNewer, fs.ConflictKeepLarger:
+		return nil
+	default:
+		return fmt.Errorf("invalid on_conflict %q: must be rename_with_suffix, skip, overwrite, trash, skip_if_identical, keep_newer or keep_larger", mode)
+	}
+}
+
+// conflictResolution describes how an existing destination was handled.
+type conflictResolution struct {
+	destPath  string // Destination to use when proceed is true
+	proceed   bool   // Whether the action should continue
+	trashed   string // Existing destination moved to the trash
+	detail    string // Reason for the decision, for reporting
+	identical bool   // Source and destination have the same contents
+}
+
+// resolveConflict decides how to handle an existing destination file.
+// Content-aware modes are decided first so the reason can be reported.
+func resolveConflict(filesystem fs.FileSystem, mode fs.ConflictMode, path, destPath string) (*conflictResolution, error) {
+	decided, detail, err := fs.DecideConflict(filesystem, mode, path, destPath)
+	if err != nil {
+		return nil, err
+	}
+
+	newDestPath, proceed, err := filesystem.ResolveConflict(decided, path, destPath)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &conflictResolution{
+		destPath:  newDestPath,
+		proceed:   proceed,
+		detail:    detail,
+		identical: mode == fs.ConflictSkipIfIdentical && decided == fs.ConflictSkip,
+	}
+	if decided == fs.ConflictTrash && proceed {
+		res.trashed = destPath
+	}
+	return res, nil
+}
+
+// skippedResult builds the result for an action that did not proceed because
+// of a conflict. Identical sources are deleted when configured to do so.
+func skippedResult(filesystem fs.FileSystem, res *conflictResolution, onIdentical IdenticalMode, path string) (*rules.ExecutionResult, error) {
+	if res.identical && onIdentical == IdenticalDelete {
+		if err := filesystem.Remove(path); err != nil {
+			return nil, err
+		}
+		return &rules.ExecutionResult{
+			Deleted: true,
+			Detail:  res.detail + ", source deleted",
+		}, nil
+	}
+
+	return &rules.ExecutionResult{
+		ConflictAlreadyExists: true,
+		Detail:                res.detail,
+	}, nil
+}
@@ -20,6 +20,14 @@ func init() {
 type Copy struct {
 	NewName    utils.Template
 	OnConflict fs.ConflictMode // Defaults to rename_with_suffix
+	TimeSource TimeSource      // Time used for strftime tokens, defaults to now
+
+	templateScope
+}
+
+// Templates implements rules.Templated.
+func (c *Copy) Templates() []utils.Template {
+	return []utils.Template{c.NewName}
 }
 
 // getConflictMode returns the conflict mode, defaulting to rename_with_suffix.
@@ -32,7 +40,21 @@ func (c *Copy) getConflictMode() fs.ConflictMode {
 
 // Execute copies the file to a new name in the same directory.
 func (c *Copy) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionResult, error) {
-	newName := c.NewName.ExpandWithNameExt(path).ExpandWithTime().String()
+	return c.ExecuteMatch(path, filesystem, nil)
+}
+
+// ExecuteMatch implements rules.MatchExecutable, expanding variables captured by filters.
+func (c *Copy) ExecuteMatch(path string, filesystem fs.FileSystem, match *rules.Match) (*rules.ExecutionResult, error) {
+	at, err := c.TimeSource.timeFor(filesystem, path)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded, err := c.NewName.Expand(c.fileContext(filesystem, path, match))
+	if err != nil {
+		return nil, err
+	}
+	newName := expanded.ExpandWithTimeAt(at).String()
 
 	// Validate that new_name doesn't contain path separators
 	if strings.ContainsRune(newName, filepath.Separator) {
@@ -48,15 +70,20 @@ func (c *Copy) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionR
 	}
 
 	// Check if destination file already exists
+	var trashed []string
+	var detail string
 	if _, err := filesystem.Stat(destPath); err == nil {
-		newDestPath, proceed, err := filesystem.ResolveConflict(c.getConflictMode(), path, destPath)
+		res, err := resolveConflict(filesystem, c.getConflictMode(), path, destPath)
 		if err != nil {
 			return nil, err
 		}
-		if !proceed {
-			return &rules.ExecutionResult{ConflictAlreadyExists: true}, nil
+		if !res.proceed {
+			return skippedResult(filesystem, res, IdenticalKeep, path)
+		}
+		destPath, detail = res.destPath, res.detail
+		if res.trashed != "" {
+			trashed = []string{res.trashed}
 		}
-		destPath = newDestPath
 	}
 
 	if err := filesystem.Copy(path, destPath); err != nil {
@@ -65,6 +92,8 @@ func (c *Copy) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionR
 
 	return &rules.ExecutionResult{
 		NewPath: destPath,
+		Trashed: trashed,
+		Detail:  detail,
 	}, nil
 }
 
@@ -84,8 +113,9 @@ func deserializeCopy(node yaml.Node) (rules.Executable, error) {
 	var m struct {
 		NewName    utils.Template  `yaml:"new_name"`
 		OnConflict fs.ConflictMode `yaml:"on_conflict"`
+		TimeSource TimeSource      `yaml:"time_source"`
 	}
-	if err := node.Decode(&m); err != nil {
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
 		return nil, err
 	}
 
@@ -93,5 +123,12 @@ func deserializeCopy(node yaml.Node) (rules.Executable, error) {
 		return nil, fmt.Errorf("copy action requires new_name")
 	}
 
-	return &Copy{NewName: m.NewName, OnConflict: m.OnConflict}, nil
+	if err := validateConflictMode(m.OnConflict); err != nil {
+		return nil, err
+	}
+	if err := validateTimeSource(m.TimeSource); err != nil {
+		return nil, err
+	}
+
+	return &Copy{NewName: m.NewName, OnConflict: m.OnConflict, TimeSource: m.TimeSource}, nil
 }
@@ -333,6 +333,11 @@ func TestDeserializeCopy(t *testing.T) {
 			yaml:    "copy:\n  on_conflict: skip",
 			wantErr: true,
 		},
+		{
+			name:    "invalid time_source",
+			yaml:    "copy:\n  new_name: x.txt\n  time_source: yesterday",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,187 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rules.RegisterAction("exec", deserializeExec)
+}
+
+// defaultExecTimeout bounds how long a command may run when no timeout is configured.
+const defaultExecTimeout = time.Minute
+
+// maxStderrBytes limits how much stderr is included in error messages.
+const maxStderrBytes = 512
+
+// Exec is an action that runs an external command for each file.
+type Exec struct {
+	Args              []utils.Template
+	Timeout           time.Duration // Defaults to one minute
+	NewPathFromStdout bool          // Continue with the path printed on stdout
+
+	templateScope
+}
+
+// Templates implements rules.Templated.
+func (e *Exec) Templates() []utils.Template {
+	return e.Args
+}
+
+// getTimeout returns the timeout, defaulting to one minute.
+func (e *Exec) getTimeout() time.Duration {
+	if e.Timeout <= 0 {
+		return defaultExecTimeout
+	}
+	return e.Timeout
+}
+
+// expandArgs expands template variables like ${path} and ${name} in every argument.
+// Strftime tokens are deliberately not expanded, since tools like exiftool
+// take their own % format strings.
+func (e *Exec) expandArgs(filesystem fs.FileSystem, path string, match *rules.Match) ([]string, error) {
+	ctx := e.fileContext(filesystem, path, match)
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		expanded, err := arg.ExpandTilde().Expand(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = expanded.String()
+	}
+	return args, nil
+}
+
+// Execute runs the command in the file's directory.
+// Commands are not run in dry-run mode.
+func (e *Exec) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionResult, error) {
+	return e.ExecuteMatch(path, filesystem, nil)
+}
+
+// ExecuteMatch implements rules.MatchExecutable, expanding variables captured by filters.
+func (e *Exec) ExecuteMatch(path string, filesystem fs.FileSystem, match *rules.Match) (*rules.ExecutionResult, error) {
+	args, err := e.expandArgs(filesystem, path, match)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := filesystem.(*fs.DryRunFileSystem); ok {
+		return &rules.ExecutionResult{Detail: "not run in dry-run mode"}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.getTimeout())
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait forever on children that keep the output pipes open
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		return nil, &rules.ItemError{Err: commandError(args[0], err, ctx.Err(), e.getTimeout(), stderr.String())}
+	}
+
+	if !e.NewPathFromStdout {
+		return nil, nil
+	}
+
+	newPath := lastLine(stdout.String())
+	if newPath == "" {
+		return nil, &rules.ItemError{Err: fmt.Errorf("%s printed no path on stdout", args[0])}
+	}
+	if !filepath.IsAbs(newPath) {
+		newPath = filepath.Join(filepath.Dir(path), newPath)
+	}
+	newPath = filepath.Clean(newPath)
+
+	if newPath == path {
+		return nil, nil
+	}
+	if _, err := filesystem.Stat(newPath); err != nil {
+		return nil, err
+	}
+
+	return &rules.ExecutionResult{NewPath: newPath}, nil
+}
+
+// commandError describes why a command failed, including its stderr.
+func commandError(name string, err, ctxErr error, timeout time.Duration, stderr string) error {
+	var msg string
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		msg = fmt.Sprintf("%s timed out after %s", name, timeout)
+	case errors.As(err, &exitErr):
+		msg = fmt.Sprintf("%s exited with status %d", name, exitErr.ExitCode())
+	default:
+		return fmt.Errorf("failed to run %s: %w", name, err)
+	}
+
+	stderr = strings.TrimSpace(stderr)
+	if len(stderr) > maxStderrBytes {
+		stderr = "…" + stderr[len(stderr)-maxStderrBytes:]
+	}
+	if stderr != "" {
+		msg += ": " + stderr
+	}
+	return errors.New(msg)
+}
+
+// lastLine returns the last non-empty line of s, trimmed.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// deserializeExec creates an Exec action from YAML.
+// Supports both `exec: [ocrmypdf, "${path}", "${path}"]` and
+// "exec: {args: [...], timeout: 5m, new_path_from_stdout: true}".
+func deserializeExec(node yaml.Node) (rules.Executable, error) {
+	var e Exec
+
+	// Arguments are never split by a shell, so a plain string is ambiguous
+	if node.Kind == yaml.ScalarNode {
+		return nil, fmt.Errorf("exec action requires a list of arguments, e.g. [ocrmypdf, \"${path}\", \"${path}\"]")
+	}
+
+	if node.Kind == yaml.SequenceNode {
+		if err := node.Decode(&e.Args); err != nil {
+			return nil, err
+		}
+	} else {
+		var m struct {
+			Args              []utils.Template `yaml:"args"`
+			Timeout           time.Duration    `yaml:"timeout"`
+			NewPathFromStdout bool             `yaml:"new_path_from_stdout"`
+		}
+		if err := rules.DecodeKnownFields(&node, &m); err != nil {
+			return nil, err
+		}
+		if m.Timeout < 0 {
+			return nil, fmt.Errorf("exec timeout must be positive, got %s", m.Timeout)
+		}
+		e = Exec{Args: m.Args, Timeout: m.Timeout, NewPathFromStdout: m.NewPathFromStdout}
+	}
+
+	if len(e.Args) == 0 || e.Args[0] == "" {
+		return nil, fmt.Errorf("exec action requires a command")
+	}
+
+	return &e, nil
+}
@@ -0,0 +1,224 @@
+package actions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// shellExec builds an Exec that runs a POSIX shell script with the file path as $1.
+func shellExec(t *testing.T, script string) *Exec {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	return &Exec{Args: []utils.Template{"sh", "-c", utils.Template(script), "sh", "${path}"}}
+}
+
+func TestExec_Execute_ExpandsArguments(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.pdf")
+	os.WriteFile(path, []byte("pdf"), 0644)
+
+	e := &Exec{Args: []utils.Template{"sh", "-c", `printf '%s|%s|%s' "$1" "$2" "$3" > args.txt`, "sh", "${path}", "${name}", "${ext}"}}
+	result, err := e.Execute(path, fs.NewReal())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != nil {
+		t.Errorf("expected nil result, got %+v", result)
+	}
+
+	// Commands run in the file's directory
+	got, err := os.ReadFile(filepath.Join(dir, "args.txt"))
+	if err != nil {
+		t.Fatalf("command did not run: %v", err)
+	}
+	if want := path + "|report|.pdf"; string(got) != want {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestExec_Execute_Failure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	os.WriteFile(path, []byte("content"), 0644)
+
+	e := shellExec(t, `echo "bad input" >&2; exit 3`)
+	_, err := e.Execute(path, fs.NewReal())
+
+	var itemErr *rules.ItemError
+	if !errors.As(err, &itemErr) {
+		t.Fatalf("expected *rules.ItemError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "exited with status 3") || !strings.Contains(err.Error(), "bad input") {
+		t.Errorf("error = %q, want exit status and stderr", err)
+	}
+}
+
+func TestExec_Execute_Timeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	os.WriteFile(path, []byte("content"), 0644)
+
+	e := shellExec(t, `sleep 5`)
+	e.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := e.Execute(path, fs.NewReal())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("command was not stopped promptly, took %s", elapsed)
+	}
+}
+
+func TestExec_Execute_NewPathFromStdout(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scan.pdf")
+	os.WriteFile(path, []byte("pdf"), 0644)
+
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+		wantErr  bool
+	}{
+		{"absolute path", `cp "$1" "$1.ocr"; echo "$1.ocr"`, path + ".ocr", false},
+		{"relative path uses last line", `cp "$1" out.pdf; echo working; echo out.pdf`, filepath.Join(dir, "out.pdf"), false},
+		{"same path", `echo "$1"`, "", false},
+		{"missing output", `echo missing.pdf`, "", true},
+		{"no output", `true`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := shellExec(t, tt.script)
+			e.NewPathFromStdout = true
+
+			result, err := e.Execute(path, fs.NewReal())
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got string
+			if result != nil {
+				got = result.NewPath
+			}
+			if got != tt.expected {
+				t.Errorf("NewPath = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExec_Execute_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	os.WriteFile(path, []byte("content"), 0644)
+
+	e := shellExec(t, `touch ran`)
+	result, err := e.Execute(path, fs.NewDryRun())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result == nil || result.Detail == "" {
+		t.Errorf("expected dry-run detail, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); !os.IsNotExist(err) {
+		t.Error("command should not run in dry-run mode")
+	}
+}
+
+func TestDeserializeExec(t *testing.T) {
+	tests := []struct {
+		name            string
+		yaml            string
+		expectedArgs    []string
+		expectedTimeout time.Duration
+		newPath         bool
+		wantErr         bool
+	}{
+		{
+			name:         "argument list",
+			yaml:         `exec: [ocrmypdf, "${path}", "${path}"]`,
+			expectedArgs: []string{"ocrmypdf", "${path}", "${path}"},
+		},
+		{
+			name:            "mapping with options",
+			yaml:            "exec:\n  args: [exiftool, -overwrite_original, \"${path}\"]\n  timeout: 5m\n  new_path_from_stdout: true",
+			expectedArgs:    []string{"exiftool", "-overwrite_original", "${path}"},
+			expectedTimeout: 5 * time.Minute,
+			newPath:         true,
+		},
+		{
+			name:    "plain string is rejected",
+			yaml:    "exec: ocrmypdf ${path}",
+			wantErr: true,
+		},
+		{
+			name:    "empty args",
+			yaml:    "exec:\n  args: []",
+			wantErr: true,
+		},
+		{
+			name:    "negative timeout",
+			yaml:    "exec:\n  args: [true]\n  timeout: -1s",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a rules.Action
+			err := yaml.Unmarshal([]byte(tt.yaml), &a)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			e, ok := a.Inner.(*Exec)
+			if !ok {
+				t.Fatalf("inner is not *Exec, got %T", a.Inner)
+			}
+			if len(e.Args) != len(tt.expectedArgs) {
+				t.Fatalf("Args = %v, want %v", e.Args, tt.expectedArgs)
+			}
+			for i := range e.Args {
+				if e.Args[i].String() != tt.expectedArgs[i] {
+					t.Errorf("Args = %v, want %v", e.Args, tt.expectedArgs)
+					break
+				}
+			}
+			if e.Timeout != tt.expectedTimeout {
+				t.Errorf("Timeout = %s, want %s", e.Timeout, tt.expectedTimeout)
+			}
+			if e.NewPathFromStdout != tt.newPath {
+				t.Errorf("NewPathFromStdout = %v, want %v", e.NewPathFromStdout, tt.newPath)
+			}
+		})
+	}
+}
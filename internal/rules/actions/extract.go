@@ -0,0 +1,344 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rules.RegisterAction("extract", deserializeExtract)
+}
+
+// defaultExtractDest extracts into a directory named after the archive, next to it.
+const defaultExtractDest = "${name}"
+
+// maxExtractSize caps the total size of the files extracted from an archive,
+// so a zip bomb can't fill the disk.
+var maxExtractSize int64 = 16 << 30
+
+// Extract is an action that unpacks zip and tar archives.
+type Extract struct {
+	Dest         utils.Template  // Relative paths are resolved against the archive's directory
+	OnConflict   fs.ConflictMode // Applies to each extracted file, defaults to rename_with_suffix
+	TrashArchive bool            // Move the archive to the trash after extracting
+
+	templateScope
+}
+
+// Templates implements rules.Templated.
+func (e *Extract) Templates() []utils.Template {
+	return []utils.Template{e.Dest}
+}
+
+// getConflictMode returns the conflict mode, defaulting to rename_with_suffix.
+func (e *Extract) getConflictMode() fs.ConflictMode {
+	if e.OnConflict == "" {
+		return fs.ConflictRenameWithSuffix
+	}
+	return e.OnConflict
+}
+
+// destDir expands the destination template for an archive.
+// ${name} is the archive name without its archive extension, e.g. "photos" for photos.tar.gz.
+func (e *Extract) destDir(filesystem fs.FileSystem, path string, match *rules.Match) (string, error) {
+	dest := e.Dest
+	if dest == "" {
+		dest = defaultExtractDest
+	}
+
+	dest, err := dest.ExpandTilde().
+		ExpandWith(map[string]string{"name": trimArchiveExtension(filepath.Base(path))}).
+		Expand(e.fileContext(filesystem, path, match))
+	if err != nil {
+		return "", err
+	}
+	expanded := dest.ExpandWithTime().String()
+
+	if !filepath.IsAbs(expanded) {
+		expanded = filepath.Join(filepath.Dir(path), expanded)
+	}
+	return filepath.Clean(expanded), nil
+}
+
+// Execute unpacks the archive into the destination directory.
+func (e *Extract) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionResult, error) {
+	return e.ExecuteMatch(path, filesystem, nil)
+}
+
+// ExecuteMatch implements rules.MatchExecutable, expanding variables captured by filters.
+func (e *Extract) ExecuteMatch(path string, filesystem fs.FileSystem, match *rules.Match) (*rules.ExecutionResult, error) {
+	format, ok := detectArchiveFormat(path)
+	if !ok {
+		return nil, &os.PathError{Op: "extract", Path: path, Err: errors.New("not a supported archive")}
+	}
+
+	dest, err := e.destDir(filesystem, path, match)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate every entry before writing anything, so a malicious
+	// archive can't leave a partial extraction behind
+	var total int64
+	err = walkArchive(filesystem, path, format, func(m archiveMember) error {
+		if _, err := extractTarget(dest, m.Name); err != nil {
+			return err
+		}
+		if m.Info.Mode().IsRegular() {
+			total += m.Info.Size()
+		}
+		if total > maxExtractSize {
+			return errExtractTooLarge()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, extractError(path, err)
+	}
+
+	// Created lists new directories before the files in them, so undo,
+	// which reverts the newest entries first, removes the files first
+	x := &extraction{filesystem: filesystem, remaining: maxExtractSize}
+	if err := x.mkdirAll(dest); err != nil {
+		return nil, err
+	}
+
+	var extracted, skipped int
+	err = walkArchive(filesystem, path, format, func(m archiveMember) error {
+		target, err := extractTarget(dest, m.Name)
+		if err != nil {
+			return err
+		}
+
+		if m.Info.IsDir() {
+			return x.mkdirAll(target)
+		}
+		if !m.Info.Mode().IsRegular() {
+			slog.Warn("skipping archive entry that is not a regular file", "archive", path, "entry", m.Name)
+			return nil
+		}
+
+		ok, err := e.extractMember(x, m, target)
+		if err != nil {
+			return err
+		}
+		if ok {
+			extracted++
+		} else {
+			skipped++
+		}
+		return nil
+	})
+	if err != nil {
+		x.rollback()
+		return nil, extractError(path, err)
+	}
+
+	detail := fmt.Sprintf("extracted %d files to %s", extracted, dest)
+	if skipped > 0 {
+		detail += fmt.Sprintf(", skipped %d existing", skipped)
+	}
+	if len(x.trashed) > 0 {
+		detail += fmt.Sprintf(", trashed %d existing", len(x.trashed))
+	}
+
+	if !e.TrashArchive {
+		return &rules.ExecutionResult{Detail: detail, Created: x.created, Trashed: x.trashed}, nil
+	}
+
+	if err := filesystem.Trash(path); err != nil {
+		x.rollback()
+		return nil, err
+	}
+	return &rules.ExecutionResult{
+		Deleted: true,
+		Trashed: append([]string{path}, x.trashed...),
+		Detail:  detail + ", archive trashed",
+		Created: x.created,
+	}, nil
+}
+
+// errExtractTooLarge returns the error for archives that expand beyond maxExtractSize.
+func errExtractTooLarge() error {
+	return fmt.Errorf("archive expands to more than %d bytes", maxExtractSize)
+}
+
+// extraction tracks what extracting a single archive has changed.
+type extraction struct {
+	filesystem fs.FileSystem
+	created    []string // Directories and files, in the order they were created
+	trashed    []string // Existing files trashed to resolve conflicts
+	remaining  int64    // Bytes left before maxExtractSize is reached
+}
+
+// mkdirAll creates dir and any missing parents, recording the ones it creates.
+func (x *extraction) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := x.filesystem.Stat(d); err == nil || filepath.Dir(d) == d {
+			break
+		}
+		missing = append(missing, d)
+	}
+
+	if err := x.filesystem.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		x.created = append(x.created, missing[i])
+	}
+	return nil
+}
+
+// rollback undoes a failed extraction, since the runner doesn't journal what
+// a failed action created. Created files and directories are removed newest
+// first, then files trashed to make room are restored.
+func (x *extraction) rollback() {
+	for i := len(x.created) - 1; i >= 0; i-- {
+		if err := x.filesystem.Remove(x.created[i]); err != nil {
+			slog.Warn("failed to remove partially extracted file", "path", x.created[i], "error", err)
+		}
+	}
+	for i := len(x.trashed) - 1; i >= 0; i-- {
+		if err := fs.RestoreFromTrash(x.filesystem, x.trashed[i]); err != nil {
+			slog.Warn("failed to restore file trashed during extraction", "path", x.trashed[i], "error", err)
+		}
+	}
+}
+
+// extractMember writes a single file entry next to its target, then moves it
+// into place, resolving conflicts against the extracted copy.
+// Returns false if the entry was skipped.
+func (e *Extract) extractMember(x *extraction, m archiveMember, target string) (bool, error) {
+	filesystem := x.filesystem
+	if err := x.mkdirAll(filepath.Dir(target)); err != nil {
+		return false, err
+	}
+
+	tmp := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".partial")
+	if err := x.writeMember(m, tmp); err != nil {
+		filesystem.Remove(tmp)
+		return false, err
+	}
+
+	destPath := target
+	if _, err := filesystem.Stat(target); err == nil {
+		res, err := resolveConflict(filesystem, e.getConflictMode(), tmp, target)
+		if err != nil {
+			filesystem.Remove(tmp)
+			return false, err
+		}
+		if !res.proceed {
+			filesystem.Remove(tmp)
+			return false, nil
+		}
+		destPath = res.destPath
+		if res.trashed != "" {
+			x.trashed = append(x.trashed, res.trashed)
+		}
+	}
+
+	if err := filesystem.Rename(tmp, destPath); err != nil {
+		filesystem.Remove(tmp)
+		return false, err
+	}
+	x.created = append(x.created, destPath)
+	return true, nil
+}
+
+// writeMember copies an archive entry to path, keeping its mode and modification
+// time. Sizes recorded in archives can't be trusted, so the copy stops once
+// the extraction exceeds maxExtractSize.
+func (x *extraction) writeMember(m archiveMember, path string) error {
+	filesystem := x.filesystem
+	rc, err := m.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	f, err := filesystem.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, m.Info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(rc, x.remaining+1))
+	if err == nil && n > x.remaining {
+		err = errExtractTooLarge()
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	x.remaining -= n
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := filesystem.Chmod(path, m.Info.Mode().Perm()); err != nil {
+		return err
+	}
+	return filesystem.Chtimes(path, m.Info.ModTime(), m.Info.ModTime())
+}
+
+// extractTarget returns where an entry should be written inside dest.
+// Absolute names and names that escape dest (zip-slip) are rejected.
+func extractTarget(dest, name string) (string, error) {
+	clean := strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(clean, "/") || filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" {
+		return "", fmt.Errorf("archive entry has an absolute path: %s", name)
+	}
+
+	target := filepath.Join(dest, filepath.FromSlash(clean))
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry escapes the destination: %s", name)
+	}
+	return target, nil
+}
+
+// extractError wraps archive errors as path errors so a bad archive skips
+// the file instead of stopping the whole run.
+func extractError(path string, err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return err
+	}
+	return &os.PathError{Op: "extract", Path: path, Err: err}
+}
+
+// deserializeExtract creates an Extract action from YAML.
+// Supports "extract", "extract: ~/Extracted/${name}" and
+// "extract: {dest: ${name}, on_conflict: skip, trash_archive: true}".
+func deserializeExtract(node yaml.Node) (rules.Executable, error) {
+	if node.Kind == yaml.ScalarNode {
+		var dest string
+		if err := node.Decode(&dest); err != nil {
+			return nil, err
+		}
+		return &Extract{Dest: utils.Template(dest)}, nil
+	}
+
+	var m struct {
+		Dest         utils.Template  `yaml:"dest"`
+		OnConflict   fs.ConflictMode `yaml:"on_conflict"`
+		TrashArchive bool            `yaml:"trash_archive"`
+	}
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
+		return nil, err
+	}
+	if err := validateConflictMode(m.OnConflict); err != nil {
+		return nil, err
+	}
+	return &Extract{Dest: m.Dest, OnConflict: m.OnConflict, TrashArchive: m.TrashArchive}, nil
+}
@@ -0,0 +1,393 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+
+	"github.com/spf13/afero"
+	"github.com/ulikunitz/xz"
+	"gopkg.in/yaml.v3"
+)
+
+// docsTarBz2 is a tar.bz2 containing docs/readme.txt ("hello").
+// The standard library has no bzip2 writer, so it is stored pre-built.
+const docsTarBz2 = "QlpoOTFBWSZTWZuAS6MAAHL7gMkAAAJAAf+AIABuRp5ACAggAHUVpPKYgDam1A9TNQSSCAAADQfSpHIQdQQhD+75x+DRQIZMcSthkyI0OAQXOTghxKBJBDvlGIi7Q59U+orSrV75LWzLZpmAAH4u5IpwoSE3AJdG"
+
+// testFileInfo describes an archive entry written by buildArchive.
+type testFileInfo struct {
+	name string
+	size int64
+}
+
+func (i testFileInfo) Name() string       { return filepath.Base(i.name) }
+func (i testFileInfo) Size() int64        { return i.size }
+func (i testFileInfo) Mode() os.FileMode  { return 0644 }
+func (i testFileInfo) ModTime() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+func (i testFileInfo) IsDir() bool        { return false }
+func (i testFileInfo) Sys() any           { return nil }
+
+// buildArchive writes an archive with the given entries, in order.
+func buildArchive(t *testing.T, filesystem fs.FileSystem, path string, format ArchiveFormat, entries [][2]string) {
+	t.Helper()
+
+	var tarBuf bytes.Buffer
+	writeFormat := format
+	if format == FormatTarXz {
+		writeFormat = FormatTar
+	}
+
+	w, err := newArchiveWriter(&tarBuf, writeFormat)
+	if err != nil {
+		t.Fatalf("failed to create archive writer: %v", err)
+	}
+	for _, e := range entries {
+		info := testFileInfo{name: e[0], size: int64(len(e[1]))}
+		if err := w.add(e[0], info, "", strings.NewReader(e[1])); err != nil {
+			t.Fatalf("failed to add %s: %v", e[0], err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	data := tarBuf.Bytes()
+	if format == FormatTarXz {
+		var xzBuf bytes.Buffer
+		xw, err := xz.NewWriter(&xzBuf)
+		if err != nil {
+			t.Fatalf("failed to create xz writer: %v", err)
+		}
+		xw.Write(data)
+		xw.Close()
+		data = xzBuf.Bytes()
+	}
+
+	filesystem.MkdirAll(filepath.Dir(path), 0755)
+	if err := afero.WriteFile(filesystem, path, data, 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+}
+
+func TestExtract_Execute_Formats(t *testing.T) {
+	dir := testutil.Path("/", "downloads")
+	entries := [][2]string{{"docs/readme.txt", "hello"}}
+
+	tests := []struct {
+		name     string
+		filename string
+		format   ArchiveFormat
+	}{
+		{"zip", "docs.zip", FormatZip},
+		{"tar", "docs.tar", FormatTar},
+		{"tar.gz", "docs.tar.gz", FormatTarGz},
+		{"tgz", "docs.tgz", FormatTarGz},
+		{"tar.xz", "docs.tar.xz", FormatTarXz},
+		{"tar.bz2", "docs.tar.bz2", FormatTarBz2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filesystem := fs.NewMem()
+			archivePath := testutil.Path(dir, tt.filename)
+			if tt.format == FormatTarBz2 {
+				data, _ := base64.StdEncoding.DecodeString(docsTarBz2)
+				filesystem.MkdirAll(dir, 0755)
+				afero.WriteFile(filesystem, archivePath, data, 0644)
+			} else {
+				buildArchive(t, filesystem, archivePath, tt.format, entries)
+			}
+
+			result, err := (&Extract{}).Execute(archivePath, filesystem)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Default destination is the archive name without its extension.
+			// Directories come before the files in them, so undo removes them last
+			expected := testutil.Path(dir, "docs", "docs", "readme.txt")
+			created := []string{testutil.Path(dir, "docs"), testutil.Path(dir, "docs", "docs"), expected}
+			if !slices.Equal(result.Created, created) {
+				t.Fatalf("Created = %v, want %v", result.Created, created)
+			}
+			content, _ := afero.ReadFile(filesystem, expected)
+			if string(content) != "hello" {
+				t.Errorf("content = %q, want %q", content, "hello")
+			}
+			if result.Deleted {
+				t.Error("archive should be kept by default")
+			}
+		})
+	}
+}
+
+func TestExtract_Execute_RejectsPathTraversal(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+	}{
+		{"parent directory", "../evil.txt"},
+		{"nested parent directory", "docs/../../evil.txt"},
+		{"absolute path", "/etc/evil.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.Path("/", "downloads")
+			archivePath := testutil.Path(dir, "evil.zip")
+
+			filesystem := fs.NewMem()
+			buildArchive(t, filesystem, archivePath, FormatZip, [][2]string{
+				{"good.txt", "fine"},
+				{tt.entry, "pwned"},
+			})
+
+			_, err := (&Extract{}).Execute(archivePath, filesystem)
+			if err == nil {
+				t.Fatal("expected error for path traversal")
+			}
+
+			// Reported as a path error so only this file is skipped
+			var pathErr *os.PathError
+			if !errors.As(err, &pathErr) {
+				t.Errorf("expected *os.PathError, got %T", err)
+			}
+
+			// Nothing is extracted, not even the valid entries
+			if exists, _ := afero.Exists(filesystem, testutil.Path(dir, "evil")); exists {
+				t.Error("destination should not be created")
+			}
+			if exists, _ := afero.Exists(filesystem, testutil.Path(dir, "evil.txt")); exists {
+				t.Error("entry escaped the destination")
+			}
+		})
+	}
+}
+
+func TestExtract_Execute_Conflicts(t *testing.T) {
+	tests := []struct {
+		name            string
+		onConflict      fs.ConflictMode
+		expectedCreated string
+		expectedContent string // Content of the original file.txt afterwards
+		expectedTrashed bool
+	}{
+		{"rename_with_suffix is default", "", "file_2.txt", "existing", false},
+		{"skip", fs.ConflictSkip, "", "existing", false},
+		{"overwrite", fs.ConflictOverwrite, "file.txt", "archived", false},
+		{"trash", fs.ConflictTrash, "file.txt", "archived", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.Path("/", "downloads")
+			dest := testutil.Path("/", "extracted")
+			archivePath := testutil.Path(dir, "files.zip")
+
+			filesystem := fs.NewMem()
+			buildArchive(t, filesystem, archivePath, FormatZip, [][2]string{{"file.txt", "archived"}})
+			filesystem.MkdirAll(dest, 0755)
+			afero.WriteFile(filesystem, testutil.Path(dest, "file.txt"), []byte("existing"), 0644)
+
+			e := &Extract{Dest: utils.Template(dest), OnConflict: tt.onConflict}
+			result, err := e.Execute(archivePath, filesystem)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.expectedCreated == "" {
+				if len(result.Created) != 0 {
+					t.Errorf("expected nothing extracted, got %v", result.Created)
+				}
+				if !strings.Contains(result.Detail, "skipped 1 existing") {
+					t.Errorf("Detail = %q, want skipped count", result.Detail)
+				}
+			} else if len(result.Created) != 1 || result.Created[0] != testutil.Path(dest, tt.expectedCreated) {
+				t.Errorf("Created = %v, want [%s]", result.Created, testutil.Path(dest, tt.expectedCreated))
+			}
+
+			if tt.expectedTrashed {
+				if len(result.Trashed) != 1 || result.Trashed[0] != testutil.Path(dest, "file.txt") {
+					t.Errorf("Trashed = %v, want [%s]", result.Trashed, testutil.Path(dest, "file.txt"))
+				}
+			} else if len(result.Trashed) != 0 {
+				t.Errorf("expected nothing trashed, got %v", result.Trashed)
+			}
+
+			content, _ := afero.ReadFile(filesystem, testutil.Path(dest, "file.txt"))
+			if string(content) != tt.expectedContent {
+				t.Errorf("file.txt = %q, want %q", content, tt.expectedContent)
+			}
+
+			// Temporary files are cleaned up
+			entries, _ := afero.ReadDir(filesystem, dest)
+			for _, entry := range entries {
+				if strings.HasSuffix(entry.Name(), ".partial") {
+					t.Errorf("temporary file left behind: %s", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestExtract_Execute_TrashArchive(t *testing.T) {
+	dir := testutil.Path("/", "downloads")
+	archivePath := testutil.Path(dir, "photos.tar.gz")
+
+	filesystem := fs.NewMem()
+	buildArchive(t, filesystem, archivePath, FormatTarGz, [][2]string{
+		{"a.jpg", "a"},
+		{"b.jpg", "b"},
+	})
+
+	e := &Extract{Dest: utils.Template(testutil.Path("/", "pictures", "${name}")), TrashArchive: true}
+	result, err := e.Execute(archivePath, filesystem)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !result.Deleted || len(result.Trashed) != 1 || result.Trashed[0] != archivePath {
+		t.Errorf("expected archive to be trashed, got %+v", result)
+	}
+	pictures := testutil.Path("/", "pictures")
+	created := []string{pictures, testutil.Path(pictures, "photos"), testutil.Path(pictures, "photos", "a.jpg"), testutil.Path(pictures, "photos", "b.jpg")}
+	if !slices.Equal(result.Created, created) {
+		t.Errorf("Created = %v, want %v", result.Created, created)
+	}
+	if exists, _ := afero.Exists(filesystem, archivePath); exists {
+		t.Error("archive should be removed")
+	}
+	if exists, _ := afero.Exists(filesystem, testutil.Path("/", "pictures", "photos", "b.jpg")); !exists {
+		t.Error("expected b.jpg to be extracted")
+	}
+}
+
+func TestExtract_Execute_SizeLimit(t *testing.T) {
+	defer func(limit int64) { maxExtractSize = limit }(maxExtractSize)
+	maxExtractSize = 10
+
+	tests := []struct {
+		name        string
+		entries     [][2]string
+		errContains string
+	}{
+		{name: "within limit", entries: [][2]string{{"a.txt", "12345"}, {"b.txt", "12345"}}},
+		{name: "over limit", entries: [][2]string{{"a.txt", "12345"}, {"b.txt", "123456"}}, errContains: "archive expands to more than 10 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath := testutil.Path("/", "downloads", "bomb.zip")
+			filesystem := fs.NewMem()
+			buildArchive(t, filesystem, archivePath, FormatZip, tt.entries)
+
+			_, err := (&Extract{}).Execute(archivePath, filesystem)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Fatalf("expected error containing %q, got %v", tt.errContains, err)
+			}
+			if exists, _ := afero.Exists(filesystem, testutil.Path("/", "downloads", "bomb")); exists {
+				t.Error("destination should not be created")
+			}
+		})
+	}
+}
+
+// renameFailingFs fails renames to paths named failOn, like a permission error
+// partway through an extraction.
+type renameFailingFs struct {
+	fs.FileSystem
+	failOn string
+}
+
+func (f renameFailingFs) Rename(oldname, newname string) error {
+	if filepath.Base(newname) == f.failOn {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrPermission}
+	}
+	return f.FileSystem.Rename(oldname, newname)
+}
+
+func TestExtract_Execute_FailureRemovesExtracted(t *testing.T) {
+	archivePath := testutil.Path("/", "downloads", "docs.zip")
+	dest := testutil.Path("/", "downloads", "docs")
+	mem := fs.NewMem()
+	buildArchive(t, mem, archivePath, FormatZip, [][2]string{{"sub/a.txt", "a"}, {"sub/b.txt", "b"}})
+
+	_, err := (&Extract{}).Execute(archivePath, renameFailingFs{FileSystem: mem, failOn: "b.txt"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// Nothing extracted before the failure is left behind
+	if exists, _ := afero.Exists(mem, dest); exists {
+		t.Error("destination should be removed")
+	}
+	if exists, _ := afero.Exists(mem, archivePath); !exists {
+		t.Error("archive should be kept")
+	}
+}
+
+func TestExtract_Execute_NotAnArchive(t *testing.T) {
+	path := testutil.Path("/", "file.txt")
+	filesystem := fs.NewMem()
+	afero.WriteFile(filesystem, path, []byte("text"), 0644)
+
+	_, err := (&Extract{}).Execute(path, filesystem)
+	var pathErr *os.PathError
+	if !errors.As(err, &pathErr) {
+		t.Errorf("expected *os.PathError, got %v", err)
+	}
+}
+
+func TestDeserializeExtract(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		expectedDest string
+		trash        bool
+		onConflict   fs.ConflictMode
+	}{
+		{"bare", "extract", "", false, ""},
+		{"scalar", "extract: ~/Extracted/${name}", "~/Extracted/${name}", false, ""},
+		{"mapping", "extract:\n  dest: out\n  on_conflict: skip\n  trash_archive: true", "out", true, fs.ConflictSkip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a rules.Action
+			if err := yaml.Unmarshal([]byte(tt.yaml), &a); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			e, ok := a.Inner.(*Extract)
+			if !ok {
+				t.Fatalf("inner is not *Extract, got %T", a.Inner)
+			}
+			if e.Dest.String() != tt.expectedDest {
+				t.Errorf("Dest = %q, want %q", e.Dest, tt.expectedDest)
+			}
+			if e.TrashArchive != tt.trash {
+				t.Errorf("TrashArchive = %v, want %v", e.TrashArchive, tt.trash)
+			}
+			if e.OnConflict != tt.onConflict {
+				t.Errorf("OnConflict = %q, want %q", e.OnConflict, tt.onConflict)
+			}
+		})
+	}
+}
@@ -17,13 +17,35 @@ func init() {
 
 // Log is an action that logs a message.
 type Log struct {
-	Msg   utils.Template
-	Level string // debug, info, warn, error
+	Msg        utils.Template
+	Level      string     // debug, info, warn, error
+	TimeSource TimeSource // Time used for strftime tokens, defaults to now
+
+	templateScope
+}
+
+// Templates implements rules.Templated.
+func (l *Log) Templates() []utils.Template {
+	return []utils.Template{l.Msg}
 }
 
 // Execute logs the message at the specified level.
-func (l *Log) Execute(path string, _ fs.FileSystem) (*rules.ExecutionResult, error) {
-	msg := l.Msg.ExpandTilde().ExpandWithNameExt(path).ExpandWithTime().String()
+func (l *Log) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionResult, error) {
+	return l.ExecuteMatch(path, filesystem, nil)
+}
+
+// ExecuteMatch implements rules.MatchExecutable, expanding variables captured by filters.
+func (l *Log) ExecuteMatch(path string, filesystem fs.FileSystem, match *rules.Match) (*rules.ExecutionResult, error) {
+	at, err := l.TimeSource.timeFor(filesystem, path)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded, err := l.Msg.ExpandTilde().Expand(l.fileContext(filesystem, path, match))
+	if err != nil {
+		return nil, err
+	}
+	msg := expanded.ExpandWithTimeAt(at).String()
 
 	switch l.Level {
 	case "debug":
@@ -53,10 +75,11 @@ func deserializeLog(node yaml.Node) (rules.Executable, error) {
 
 	// Otherwise expect a mapping with "msg" key and optional "level"
 	var m struct {
-		Msg   utils.Template `yaml:"msg"`
-		Level string         `yaml:"level"`
+		Msg        utils.Template `yaml:"msg"`
+		Level      string         `yaml:"level"`
+		TimeSource TimeSource     `yaml:"time_source"`
 	}
-	if err := node.Decode(&m); err != nil {
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
 		return nil, err
 	}
 
@@ -77,5 +100,9 @@ func deserializeLog(node yaml.Node) (rules.Executable, error) {
 		return nil, fmt.Errorf("log action has invalid level: %s (must be debug, info, warn, or error)", level)
 	}
 
-	return &Log{Msg: m.Msg, Level: level}, nil
+	if err := validateTimeSource(m.TimeSource); err != nil {
+		return nil, err
+	}
+
+	return &Log{Msg: m.Msg, Level: level, TimeSource: m.TimeSource}, nil
 }
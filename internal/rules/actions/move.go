@@ -17,8 +17,22 @@ func init() {
 
 // Move is an action that moves files to a destination directory.
 type Move struct {
-	Dest       utils.Template
-	OnConflict fs.ConflictMode // Defaults to rename_with_suffix
+	Dest        utils.Template
+	OnConflict  fs.ConflictMode // Defaults to rename_with_suffix
+	OnIdentical IdenticalMode   // Used by skip_if_identical, defaults to keep
+	TimeSource  TimeSource      // Time used for strftime tokens, defaults to now
+
+	templateScope
+}
+
+// Templates implements rules.Templated.
+func (m *Move) Templates() []utils.Template {
+	return []utils.Template{m.Dest}
+}
+
+// Destination implements rules.Destined.
+func (m *Move) Destination() utils.Template {
+	return m.Dest
 }
 
 // getConflictMode returns the conflict mode, defaulting to rename_with_suffix.
@@ -32,7 +46,21 @@ func (m *Move) getConflictMode() fs.ConflictMode {
 // Execute moves the file to the destination directory.
 // The destination must be a directory, not a file path.
 func (m *Move) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionResult, error) {
-	destDir := m.Dest.ExpandTilde().ExpandWithNameExt(path).ExpandWithTime().String()
+	return m.ExecuteMatch(path, filesystem, nil)
+}
+
+// ExecuteMatch implements rules.MatchExecutable, expanding variables captured by filters.
+func (m *Move) ExecuteMatch(path string, filesystem fs.FileSystem, match *rules.Match) (*rules.ExecutionResult, error) {
+	at, err := m.TimeSource.timeFor(filesystem, path)
+	if err != nil {
+		return nil, err
+	}
+
+	dest, err := m.Dest.ExpandTilde().Expand(m.fileContext(filesystem, path, match))
+	if err != nil {
+		return nil, err
+	}
+	destDir := dest.ExpandWithTimeAt(at).String()
 	filename := filepath.Base(path)
 	destPath := filepath.Join(destDir, filename)
 
@@ -52,15 +80,20 @@ func (m *Move) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionR
 	}
 
 	// Check if destination file already exists
+	var trashed []string
+	var detail string
 	if _, err := filesystem.Stat(destPath); err == nil {
-		newDestPath, proceed, err := filesystem.ResolveConflict(m.getConflictMode(), path, destPath)
+		res, err := resolveConflict(filesystem, m.getConflictMode(), path, destPath)
 		if err != nil {
 			return nil, err
 		}
-		if !proceed {
-			return &rules.ExecutionResult{ConflictAlreadyExists: true}, nil
+		if !res.proceed {
+			return skippedResult(filesystem, res, m.OnIdentical, path)
+		}
+		destPath, detail = res.destPath, res.detail
+		if res.trashed != "" {
+			trashed = []string{res.trashed}
 		}
-		destPath = newDestPath
 	}
 
 	if err := filesystem.Rename(path, destPath); err != nil {
@@ -69,11 +102,14 @@ func (m *Move) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionR
 
 	return &rules.ExecutionResult{
 		NewPath: destPath,
+		Trashed: trashed,
+		Detail:  detail,
 	}, nil
 }
 
 // deserializeMove creates a Move action from YAML.
 // Supports both "move: ~/dest" and "move: {dest: ~/dest, on_conflict: skip}".
+// With on_conflict: skip_if_identical, "on_identical: delete" removes redundant sources.
 func deserializeMove(node yaml.Node) (rules.Executable, error) {
 	// Try as plain string first
 	if node.Kind == yaml.ScalarNode {
@@ -84,13 +120,24 @@ func deserializeMove(node yaml.Node) (rules.Executable, error) {
 		return &Move{Dest: utils.Template(dest)}, nil
 	}
 
-	// Otherwise expect a mapping with "dest" key and optional "on_conflict"
+	// Otherwise expect a mapping with "dest" key and optional "on_conflict"/"on_identical"
 	var m struct {
-		Dest       utils.Template  `yaml:"dest"`
-		OnConflict fs.ConflictMode `yaml:"on_conflict"`
+		Dest        utils.Template  `yaml:"dest"`
+		OnConflict  fs.ConflictMode `yaml:"on_conflict"`
+		OnIdentical IdenticalMode   `yaml:"on_identical"`
+		TimeSource  TimeSource      `yaml:"time_source"`
+	}
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
+		return nil, err
+	}
+	if err := validateConflictMode(m.OnConflict); err != nil {
+		return nil, err
+	}
+	if err := validateIdenticalMode(m.OnIdentical); err != nil {
+		return nil, err
 	}
-	if err := node.Decode(&m); err != nil {
+	if err := validateTimeSource(m.TimeSource); err != nil {
 		return nil, err
 	}
-	return &Move{Dest: m.Dest, OnConflict: m.OnConflict}, nil
+	return &Move{Dest: m.Dest, OnConflict: m.OnConflict, OnIdentical: m.OnIdentical, TimeSource: m.TimeSource}, nil
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
@@ -157,6 +158,53 @@ func TestMove_Execute_NonExistentSource(t *testing.T) {
 	}
 }
 
+func TestMove_Execute_MirrorsRelativePath(t *testing.T) {
+	src := testutil.Path("/", "src")
+	srcFile := testutil.Path(src, "work", "2024", "report.pdf")
+	dest := testutil.Path("/", "dest")
+
+	filesystem := fs.NewMem()
+	afero.WriteFile(filesystem, srcFile, []byte("content"), 0644)
+
+	m := &Move{
+		Dest: utils.Template(dest + "/${relpath}"),
+	}
+	m.BindLocations([]string{src}, true)
+
+	result, err := m.Execute(srcFile, filesystem)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := testutil.Path(dest, "work", "2024", "report.pdf")
+	if result == nil || result.NewPath != expected {
+		t.Errorf("NewPath = %v, want %q", result, expected)
+	}
+}
+
+func TestMove_ExecuteMatch_CapturedVariables(t *testing.T) {
+	srcFile := testutil.Path("/", "downloads", "ACME_2024_invoice.pdf")
+	clients := testutil.Path("/", "clients")
+
+	filesystem := fs.NewMem()
+	afero.WriteFile(filesystem, srcFile, []byte("content"), 0644)
+
+	m := &Move{
+		Dest: utils.Template(clients + "/${client|lower}/${year}"),
+	}
+	match := &rules.Match{Vars: map[string]string{"client": "ACME", "year": "2024"}}
+
+	result, err := m.ExecuteMatch(srcFile, filesystem, match)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := testutil.Path(clients, "acme", "2024", "ACME_2024_invoice.pdf")
+	if result == nil || result.NewPath != expected {
+		t.Errorf("NewPath = %v, want %q", result, expected)
+	}
+}
+
 func TestMove_Execute_DestinationIsFile(t *testing.T) {
 	src := testutil.Path("/", "src")
 	srcFile := testutil.Path(src, "file.txt")
@@ -327,7 +375,7 @@ func TestMove_Execute_ConflictOverwrite(t *testing.T) {
 	}
 }
 
-func TestMove_Execute_ConflictTrashNotImplemented(t *testing.T) {
+func TestMove_Execute_ConflictTrash(t *testing.T) {
 	src := testutil.Path("/", "src")
 	dest := testutil.Path("/", "dest")
 	srcFile := testutil.Path(src, "file.txt")
@@ -345,14 +393,180 @@ func TestMove_Execute_ConflictTrashNotImplemented(t *testing.T) {
 		OnConflict: fs.ConflictTrash,
 	}
 
-	_, err := m.Execute(srcFile, filesystem)
-	if err == nil {
-		t.Error("expected error for trash mode, got none")
+	result, err := m.Execute(srcFile, filesystem)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	expectedMsg := "trash conflict mode is not yet implemented"
-	if err.Error() != expectedMsg {
-		t.Errorf("error = %q, want %q", err.Error(), expectedMsg)
+	if result == nil {
+		t.Fatal("expected result, got nil")
+	}
+
+	if result.NewPath != destFile {
+		t.Errorf("NewPath = %q, want %q", result.NewPath, destFile)
+	}
+
+	if len(result.Trashed) != 1 || result.Trashed[0] != destFile {
+		t.Errorf("Trashed = %v, want [%s]", result.Trashed, destFile)
+	}
+
+	// Destination should have source content
+	content, _ := afero.ReadFile(filesystem, destFile)
+	if string(content) != "content" {
+		t.Errorf("destination content = %q, want %q", string(content), "content")
+	}
+}
+
+func TestMove_Execute_ConflictTrashDryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	dest := filepath.Join(tmpDir, "dest")
+	srcFile := filepath.Join(src, "file.txt")
+	destFile := filepath.Join(dest, "file.txt")
+
+	os.MkdirAll(src, 0755)
+	os.MkdirAll(dest, 0755)
+	os.WriteFile(srcFile, []byte("content"), 0644)
+	os.WriteFile(destFile, []byte("existing"), 0644)
+
+	m := &Move{
+		Dest:       utils.Template(dest),
+		OnConflict: fs.ConflictTrash,
+	}
+
+	result, err := m.Execute(srcFile, fs.NewDryRun())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result == nil || len(result.Trashed) != 1 || result.Trashed[0] != destFile {
+		t.Fatalf("expected preview to report trashed destination, got %+v", result)
+	}
+
+	// Nothing on disk should change
+	content, _ := os.ReadFile(destFile)
+	if string(content) != "existing" {
+		t.Errorf("destination content = %q, want %q", string(content), "existing")
+	}
+}
+
+func TestMove_Execute_ConflictSkipIfIdentical(t *testing.T) {
+	src := testutil.Path("/", "src")
+	dest := testutil.Path("/", "dest")
+	srcFile := testutil.Path(src, "report.pdf")
+	destFile := testutil.Path(dest, "report.pdf")
+
+	tests := []struct {
+		name            string
+		srcContent      string
+		onIdentical     IdenticalMode
+		expectSkipped   bool
+		expectDeleted   bool
+		expectedNewPath string
+	}{
+		{
+			name:          "identical keeps source by default",
+			srcContent:    "same",
+			expectSkipped: true,
+		},
+		{
+			name:          "identical deletes source when configured",
+			srcContent:    "same",
+			onIdentical:   IdenticalDelete,
+			expectDeleted: true,
+		},
+		{
+			name:            "different content renames with suffix",
+			srcContent:      "different",
+			onIdentical:     IdenticalDelete,
+			expectedNewPath: testutil.Path(dest, "report_2.pdf"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filesystem := fs.NewMem()
+			filesystem.MkdirAll(src, 0755)
+			filesystem.MkdirAll(dest, 0755)
+			afero.WriteFile(filesystem, srcFile, []byte(tt.srcContent), 0644)
+			afero.WriteFile(filesystem, destFile, []byte("same"), 0644)
+
+			m := &Move{
+				Dest:        utils.Template(dest),
+				OnConflict:  fs.ConflictSkipIfIdentical,
+				OnIdentical: tt.onIdentical,
+			}
+
+			result, err := m.Execute(srcFile, filesystem)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result == nil {
+				t.Fatal("expected result, got nil")
+			}
+
+			if result.ConflictAlreadyExists != tt.expectSkipped {
+				t.Errorf("ConflictAlreadyExists = %v, want %v", result.ConflictAlreadyExists, tt.expectSkipped)
+			}
+			if result.Deleted != tt.expectDeleted {
+				t.Errorf("Deleted = %v, want %v", result.Deleted, tt.expectDeleted)
+			}
+			if result.NewPath != tt.expectedNewPath {
+				t.Errorf("NewPath = %q, want %q", result.NewPath, tt.expectedNewPath)
+			}
+			if (tt.expectSkipped || tt.expectDeleted) && result.Detail == "" {
+				t.Error("expected detail describing the identical destination")
+			}
+
+			srcExists, _ := afero.Exists(filesystem, srcFile)
+			if srcExists != tt.expectSkipped {
+				t.Errorf("source exists = %v, want %v", srcExists, tt.expectSkipped)
+			}
+		})
+	}
+}
+
+func TestMove_Execute_ConflictKeepNewer(t *testing.T) {
+	src := testutil.Path("/", "src")
+	dest := testutil.Path("/", "dest")
+	srcFile := testutil.Path(src, "file.txt")
+	destFile := testutil.Path(dest, "file.txt")
+	now := time.Now()
+
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll(src, 0755)
+	filesystem.MkdirAll(dest, 0755)
+	afero.WriteFile(filesystem, srcFile, []byte("older"), 0644)
+	afero.WriteFile(filesystem, destFile, []byte("newer"), 0644)
+	filesystem.Chtimes(srcFile, now, now.Add(-2*time.Hour))
+	filesystem.Chtimes(destFile, now, now.Add(-time.Hour))
+
+	m := &Move{
+		Dest:       utils.Template(dest),
+		OnConflict: fs.ConflictKeepNewer,
+	}
+
+	result, err := m.Execute(srcFile, filesystem)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result == nil || !result.ConflictAlreadyExists {
+		t.Fatalf("expected skip when destination is newer, got %+v", result)
+	}
+
+	// Make the source newer and try again
+	filesystem.Chtimes(srcFile, now, now)
+	result, err = m.Execute(srcFile, filesystem)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result == nil || result.NewPath != destFile {
+		t.Fatalf("expected move to replace older destination, got %+v", result)
+	}
+
+	content, _ := afero.ReadFile(filesystem, destFile)
+	if string(content) != "older" {
+		t.Errorf("destination content = %q, want %q", string(content), "older")
 	}
 }
 
@@ -399,6 +613,33 @@ func TestDeserializeMove(t *testing.T) {
 			expectedOnConflict: fs.ConflictOverwrite,
 			wantErr:            false,
 		},
+		{
+			name:               "mapping with skip_if_identical and on_identical",
+			yaml:               "move:\n  dest: /path\n  on_conflict: skip_if_identical\n  on_identical: delete",
+			expectedDest:       "/path",
+			expectedOnConflict: fs.ConflictSkipIfIdentical,
+			wantErr:            false,
+		},
+		{
+			name:    "invalid on_identical",
+			yaml:    "move:\n  dest: /path\n  on_identical: shred",
+			wantErr: true,
+		},
+		{
+			name:    "invalid time_source",
+			yaml:    "move:\n  dest: /path\n  time_source: yesterday",
+			wantErr: true,
+		},
+		{
+			name:    "invalid on_conflict",
+			yaml:    "move:\n  dest: /path\n  on_conflict: replace",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			yaml:    "move:\n  dest: /path\n  on_conflit: skip",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
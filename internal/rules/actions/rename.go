@@ -18,8 +18,17 @@ func init() {
 
 // Rename is an action that renames files in place.
 type Rename struct {
-	NewName    utils.Template
-	OnConflict fs.ConflictMode // Defaults to rename_with_suffix
+	NewName     utils.Template
+	OnConflict  fs.ConflictMode // Defaults to rename_with_suffix
+	OnIdentical IdenticalMode   // Used by skip_if_identical, defaults to keep
+	TimeSource  TimeSource      // Time used for strftime tokens, defaults to now
+
+	templateScope
+}
+
+// Templates implements rules.Templated.
+func (r *Rename) Templates() []utils.Template {
+	return []utils.Template{r.NewName}
 }
 
 // getConflictMode returns the conflict mode, defaulting to rename_with_suffix.
@@ -32,7 +41,21 @@ func (r *Rename) getConflictMode() fs.ConflictMode {
 
 // Execute renames the file to the new name in the same directory.
 func (r *Rename) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionResult, error) {
-	newName := r.NewName.ExpandWithNameExt(path).ExpandWithTime().String()
+	return r.ExecuteMatch(path, filesystem, nil)
+}
+
+// ExecuteMatch implements rules.MatchExecutable, expanding variables captured by filters.
+func (r *Rename) ExecuteMatch(path string, filesystem fs.FileSystem, match *rules.Match) (*rules.ExecutionResult, error) {
+	at, err := r.TimeSource.timeFor(filesystem, path)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded, err := r.NewName.Expand(r.fileContext(filesystem, path, match))
+	if err != nil {
+		return nil, err
+	}
+	newName := expanded.ExpandWithTimeAt(at).String()
 
 	// Validate that new_name doesn't contain path separators
 	if strings.ContainsRune(newName, filepath.Separator) {
@@ -48,15 +71,20 @@ func (r *Rename) Execute(path string, filesystem fs.FileSystem) (*rules.Executio
 	}
 
 	// Check if destination file already exists
+	var trashed []string
+	var detail string
 	if _, err := filesystem.Stat(destPath); err == nil {
-		newDestPath, proceed, err := filesystem.ResolveConflict(r.getConflictMode(), path, destPath)
+		res, err := resolveConflict(filesystem, r.getConflictMode(), path, destPath)
 		if err != nil {
 			return nil, err
 		}
-		if !proceed {
-			return &rules.ExecutionResult{ConflictAlreadyExists: true}, nil
+		if !res.proceed {
+			return skippedResult(filesystem, res, r.OnIdentical, path)
+		}
+		destPath, detail = res.destPath, res.detail
+		if res.trashed != "" {
+			trashed = []string{res.trashed}
 		}
-		destPath = newDestPath
 	}
 
 	if err := filesystem.Rename(path, destPath); err != nil {
@@ -65,6 +93,8 @@ func (r *Rename) Execute(path string, filesystem fs.FileSystem) (*rules.Executio
 
 	return &rules.ExecutionResult{
 		NewPath: destPath,
+		Trashed: trashed,
+		Detail:  detail,
 	}, nil
 }
 
@@ -80,12 +110,14 @@ func deserializeRename(node yaml.Node) (rules.Executable, error) {
 		return &Rename{NewName: utils.Template(newName)}, nil
 	}
 
-	// Otherwise expect a mapping with "new_name" key and optional "on_conflict"
+	// Otherwise expect a mapping with "new_name" key and optional "on_conflict"/"on_identical"
 	var m struct {
-		NewName    utils.Template  `yaml:"new_name"`
-		OnConflict fs.ConflictMode `yaml:"on_conflict"`
+		NewName     utils.Template  `yaml:"new_name"`
+		OnConflict  fs.ConflictMode `yaml:"on_conflict"`
+		OnIdentical IdenticalMode   `yaml:"on_identical"`
+		TimeSource  TimeSource      `yaml:"time_source"`
 	}
-	if err := node.Decode(&m); err != nil {
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
 		return nil, err
 	}
 
@@ -93,5 +125,16 @@ func deserializeRename(node yaml.Node) (rules.Executable, error) {
 		return nil, fmt.Errorf("rename action requires new_name")
 	}
 
-	return &Rename{NewName: m.NewName, OnConflict: m.OnConflict}, nil
+	if err := validateConflictMode(m.OnConflict); err != nil {
+		return nil, err
+	}
+	if err := validateIdenticalMode(m.OnIdentical); err != nil {
+		return nil, err
+	}
+
+	if err := validateTimeSource(m.TimeSource); err != nil {
+		return nil, err
+	}
+
+	return &Rename{NewName: m.NewName, OnConflict: m.OnConflict, OnIdentical: m.OnIdentical, TimeSource: m.TimeSource}, nil
 }
@@ -151,6 +151,44 @@ func TestRename_Execute_PathSeparatorInvalid(t *testing.T) {
 	}
 }
 
+func TestRename_Execute_Counter(t *testing.T) {
+	dir := testutil.Path("/", "dir")
+
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll(dir, 0755)
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		afero.WriteFile(filesystem, testutil.Path(dir, name), []byte(name), 0644)
+	}
+
+	r := &Rename{
+		NewName: utils.Template("photo_${counter:3}${ext}"),
+	}
+
+	run := func(names ...string) []string {
+		r.StartRun()
+		var got []string
+		for _, name := range names {
+			result, err := r.Execute(testutil.Path(dir, name), filesystem)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got = append(got, filepath.Base(result.NewPath))
+		}
+		return got
+	}
+
+	got := run("a.jpg", "b.jpg")
+	if strings.Join(got, ",") != "photo_001.jpg,photo_002.jpg" {
+		t.Errorf("first run = %v, want [photo_001.jpg photo_002.jpg]", got)
+	}
+
+	// The counter restarts with every run, conflicts still get a suffix
+	got = run("c.jpg")
+	if strings.Join(got, ",") != "photo_001_2.jpg" {
+		t.Errorf("second run = %v, want [photo_001_2.jpg]", got)
+	}
+}
+
 func TestRename_Execute_ConflictSkip(t *testing.T) {
 	dir := testutil.Path("/", "dir")
 	srcFile := testutil.Path(dir, "source.txt")
@@ -268,6 +306,11 @@ func TestDeserializeRename(t *testing.T) {
 			yaml:    "rename:\n  on_conflict: skip",
 			wantErr: true,
 		},
+		{
+			name:    "invalid time_source",
+			yaml:    "rename:\n  new_name: x.txt\n  time_source: yesterday",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
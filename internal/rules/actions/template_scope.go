@@ -0,0 +1,49 @@
+package actions
+
+import (
+	"sync"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+)
+
+// templateScope holds the rule context that template variables need beyond
+// the file itself: the rule's locations for ${location} and ${relpath}, and
+// the per-run sequence for ${counter}. Actions with templates embed it.
+type templateScope struct {
+	mu        sync.Mutex
+	locations []string
+	counter   int
+}
+
+// BindLocations implements rules.LocationBinder.
+func (s *templateScope) BindLocations(locations []string, recursive bool) {
+	s.locations = locations
+}
+
+// StartRun implements rules.RunScoped, restarting ${counter} at 1.
+func (s *templateScope) StartRun() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counter = 0
+}
+
+// fileContext returns the context for expanding templates for the file,
+// advancing the counter. Variables captured by filters come from match,
+// which may be nil.
+func (s *templateScope) fileContext(filesystem fs.FileSystem, path string, match *rules.Match) utils.FileContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counter++
+	ctx := utils.FileContext{
+		Fs:        filesystem,
+		Path:      path,
+		Locations: s.locations,
+		Counter:   s.counter,
+	}
+	if match != nil {
+		ctx.Vars = match.Vars
+	}
+	return ctx
+}
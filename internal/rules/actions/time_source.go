@@ -0,0 +1,94 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/filetime"
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/media"
+)
+
+// TimeSource selects the time that strftime tokens like %Y in templates are formatted from.
+type TimeSource string
+
+const (
+	TimeSourceNow      TimeSource = "now"      // When the action runs
+	TimeSourceModified TimeSource = "modified" // The file's modification time
+	TimeSourceCreated  TimeSource = "created"  // The file's creation time
+	TimeSourceAccessed TimeSource = "accessed" // The file's last access time
+	TimeSourceChanged  TimeSource = "changed"  // The file's metadata change time
+	TimeSourceTaken    TimeSource = "taken"    // When a photo or video was captured, falling back to modification time
+)
+
+// validateTimeSource checks that a time_source value is supported.
+func validateTimeSource(source TimeSource) error {
+	switch source {
+	case "", TimeSourceNow, TimeSourceModified, TimeSourceCreated, TimeSourceAccessed, TimeSourceChanged, TimeSourceTaken:
+		return nil
+	default:
+		return fmt.Errorf("invalid time_source %q: must be now, modified, created, accessed, changed or taken", source)
+	}
+}
+
+// timeFor returns the time to format templates with for the file at path.
+func (s TimeSource) timeFor(filesystem fs.FileSystem, path string) (time.Time, error) {
+	switch s {
+	case TimeSourceModified:
+		// Stat through the filesystem, so files staged by an earlier
+		// action in a dry run are found. Renamed files are looked up where
+		// they started, since the dry run's copy has new times
+		info, err := filesystem.Stat(fs.Origin(filesystem, path))
+		if err != nil {
+			info, err = filesystem.Stat(path)
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		return info.ModTime(), nil
+	case TimeSourceCreated:
+		return fileTime(filesystem, path, filetime.Created)
+	case TimeSourceAccessed:
+		return fileTime(filesystem, path, filetime.Accessed)
+	case TimeSourceChanged:
+		return fileTime(filesystem, path, filetime.Changed)
+	case TimeSourceTaken:
+		taken, err := media.DateTaken(filesystem, path)
+		if err == nil {
+			return taken, nil
+		}
+		if !errors.Is(err, media.ErrNoDateTaken) {
+			return time.Time{}, err
+		}
+
+		// Screenshots and edited images often have no capture date
+		return TimeSourceModified.timeFor(filesystem, path)
+	default:
+		return time.Now(), nil
+	}
+}
+
+// statFileTime reads a file time from the OS, replaced in tests.
+var statFileTime = filetime.Stat
+
+// fileTime returns a timestamp that os.FileInfo doesn't expose, which has to
+// be read from the OS. Files renamed in a dry run are looked up where they
+// started. Timestamps the filesystem doesn't record, like creation time on
+// many Linux filesystems, fall back to modification time, as does a file that
+// only exists in the filesystem, like one extracted in a dry run.
+func fileTime(filesystem fs.FileSystem, path string, kind filetime.Kind) (time.Time, error) {
+	t, err := statFileTime(fs.Origin(filesystem, path), kind)
+	if errors.Is(err, filetime.ErrUnavailable) {
+		slog.Warn("file time is not recorded, using modification time instead", "path", path, "time_source", kind, "error", err)
+		return TimeSourceModified.timeFor(filesystem, path)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		if _, statErr := filesystem.Stat(path); statErr == nil {
+			return TimeSourceModified.timeFor(filesystem, path)
+		}
+	}
+	return t, err
+}
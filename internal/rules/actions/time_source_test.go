@@ -0,0 +1,231 @@
+package actions
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/filetime"
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+
+	"github.com/spf13/afero"
+)
+
+// exifJPEG is a minimal JPEG whose EXIF DateTimeOriginal is 2019:07:14 09:30:15.
+const exifJPEG = "/9j/4AAHSkZJRgD/4QBIRXhpZgAASUkqAAgAAAABAGmHBAABAAAAGgAAAAAAAAABAAOQAgAUAAAALAAAAAAAAAAyMDE5OjA3OjE0IDA5OjMwOjE1AP/aAAISNP/Z"
+
+func TestTimeSource_Execute(t *testing.T) {
+	dir := testutil.Path("/", "downloads")
+	photo := testutil.Path(dir, "photo.jpg")
+	screenshot := testutil.Path(dir, "screenshot.png")
+	modified := time.Date(2021, time.March, 2, 12, 0, 0, 0, time.Local)
+	thisYear := time.Now().Format("2006")
+
+	tests := []struct {
+		name     string
+		path     string
+		action   rules.Executable
+		expected string
+	}{
+		{
+			name:     "move by date taken",
+			path:     photo,
+			action:   &Move{Dest: utils.Template(testutil.Path("/", "pictures", "%Y", "%B")), TimeSource: TimeSourceTaken},
+			expected: testutil.Path("/", "pictures", "2019", "July", "photo.jpg"),
+		},
+		{
+			name:     "move falls back to modification time",
+			path:     screenshot,
+			action:   &Move{Dest: utils.Template(testutil.Path("/", "pictures", "%Y")), TimeSource: TimeSourceTaken},
+			expected: testutil.Path("/", "pictures", "2021", "screenshot.png"),
+		},
+		{
+			name:     "move defaults to now",
+			path:     photo,
+			action:   &Move{Dest: utils.Template(testutil.Path("/", "pictures", "%Y"))},
+			expected: testutil.Path("/", "pictures", thisYear, "photo.jpg"),
+		},
+		{
+			name:     "rename by modification time",
+			path:     screenshot,
+			action:   &Rename{NewName: "%Y-%m-%d ${name}${ext}", TimeSource: TimeSourceModified},
+			expected: testutil.Path(dir, "2021-03-02 screenshot.png"),
+		},
+		{
+			name:     "rename by date taken",
+			path:     photo,
+			action:   &Rename{NewName: "%Y-%m-%d ${name}${ext}", TimeSource: TimeSourceTaken},
+			expected: testutil.Path(dir, "2019-07-14 photo.jpg"),
+		},
+		{
+			name:     "copy by date taken",
+			path:     photo,
+			action:   &Copy{NewName: "%Y%m%d_%H%M%S${ext}", TimeSource: TimeSourceTaken},
+			expected: testutil.Path(dir, "20190714_093015.jpg"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filesystem := fs.NewMem()
+			data, _ := base64.StdEncoding.DecodeString(exifJPEG)
+			filesystem.MkdirAll(dir, 0755)
+			afero.WriteFile(filesystem, photo, data, 0644)
+			afero.WriteFile(filesystem, screenshot, []byte("png"), 0644)
+			filesystem.Chtimes(screenshot, modified, modified)
+
+			result, err := tt.action.Execute(tt.path, filesystem)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result == nil || result.NewPath != tt.expected {
+				t.Fatalf("result = %+v, want NewPath %s", result, tt.expected)
+			}
+			if exists, _ := afero.Exists(filesystem, tt.expected); !exists {
+				t.Errorf("expected %s to exist", tt.expected)
+			}
+		})
+	}
+}
+
+func TestTimeSource_TimeFor_FileTimes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	mtime := time.Date(2019, time.July, 14, 9, 30, 0, 0, time.UTC)
+	atime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, atime, mtime); err != nil {
+		t.Fatalf("failed to set times: %v", err)
+	}
+
+	filesystem := fs.NewReal()
+	for _, tt := range []struct {
+		source   TimeSource
+		expected time.Time
+	}{
+		{TimeSourceModified, mtime},
+		{TimeSourceAccessed, atime},
+	} {
+		got, err := tt.source.timeFor(filesystem, path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.source, err)
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("%s: got %v, want %v", tt.source, got, tt.expected)
+		}
+	}
+
+	// Change and creation times are set by the filesystem, and fall back to
+	// the modification time where it doesn't record them
+	for _, source := range []TimeSource{TimeSourceChanged, TimeSourceCreated} {
+		got, err := source.timeFor(filesystem, path)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", source, err)
+			continue
+		}
+		if _, statErr := filetime.Stat(path, filetime.Kind(source)); errors.Is(statErr, filetime.ErrUnavailable) {
+			if !got.Equal(mtime) {
+				t.Errorf("%s: got %v, want the modification time %v", source, got, mtime)
+			}
+			continue
+		}
+		if time.Since(got) > time.Hour {
+			t.Errorf("%s: got %v, expected a recent time", source, got)
+		}
+	}
+}
+
+func TestTimeSource_TimeFor_Unavailable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	mtime := time.Date(2019, time.July, 14, 9, 30, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("failed to set times: %v", err)
+	}
+
+	// Like tmpfs or a network filesystem that records no birth or change time
+	defer func(stat func(string, filetime.Kind) (time.Time, error)) { statFileTime = stat }(statFileTime)
+	statFileTime = func(path string, kind filetime.Kind) (time.Time, error) {
+		if kind == filetime.Created || kind == filetime.Changed {
+			return time.Time{}, fmt.Errorf("%s time is %w for %s", kind, filetime.ErrUnavailable, path)
+		}
+		return filetime.Stat(path, kind)
+	}
+
+	for _, source := range []TimeSource{TimeSourceCreated, TimeSourceChanged} {
+		got, err := source.timeFor(fs.NewReal(), path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", source, err)
+		}
+		if !got.Equal(mtime) {
+			t.Errorf("%s: got %v, want the modification time %v", source, got, mtime)
+		}
+	}
+}
+
+func TestTimeSource_TimeFor_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	mtime := time.Date(2019, time.July, 14, 9, 30, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("failed to set times: %v", err)
+	}
+
+	// Earlier actions moved the file, which only happened in memory
+	filesystem := fs.NewDryRun()
+	moved := filepath.Join(dir, "sorted", "moved.txt")
+	filesystem.MkdirAll(filepath.Dir(moved), 0755)
+	if err := filesystem.Rename(path, moved); err != nil {
+		t.Fatalf("failed to move in dry run: %v", err)
+	}
+	renamedDir := filepath.Join(dir, "archive")
+	if err := filesystem.Rename(filepath.Dir(moved), renamedDir); err != nil {
+		t.Fatalf("failed to rename directory in dry run: %v", err)
+	}
+	moved = filepath.Join(renamedDir, "moved.txt")
+
+	// Times come from the file on disk
+	for _, kind := range []filetime.Kind{filetime.Created, filetime.Accessed, filetime.Changed, filetime.Modified} {
+		expected, expectedErr := filetime.Stat(path, kind)
+		if errors.Is(expectedErr, filetime.ErrUnavailable) {
+			expected, expectedErr = mtime, nil
+		}
+		got, err := TimeSource(kind).timeFor(filesystem, moved)
+		if (err != nil) != (expectedErr != nil) {
+			t.Fatalf("%s: error = %v, want %v", kind, err, expectedErr)
+		}
+		if !got.Equal(expected) {
+			t.Errorf("%s: got %v, want %v", kind, got, expected)
+		}
+	}
+
+	// Files that only exist in the dry run fall back to modification time
+	extracted := filepath.Join(dir, "extracted.txt")
+	afero.WriteFile(filesystem, extracted, []byte("new"), 0644)
+	filesystem.Chtimes(extracted, mtime, mtime)
+	got, err := TimeSourceCreated.timeFor(filesystem, extracted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Equal(mtime) {
+		t.Errorf("got %v, want the modification time %v", got, mtime)
+	}
+
+	// Files that don't exist at all are still an error
+	if _, err := TimeSourceCreated.timeFor(filesystem, filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,149 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PositionError is a config error at a position in the YAML source, so it
+// can be reported with a line and column.
+type PositionError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *PositionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownFieldError is a mapping key that doesn't match a field of the type
+// being decoded.
+type UnknownFieldError struct {
+	Field     string
+	Available []string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q, available: %v", e.Field, e.Available)
+}
+
+// errorAt attaches the position of node to err. Errors that already have a
+// position keep it, since it is closer to the cause.
+func errorAt(node *yaml.Node, err error) error {
+	var perr *PositionError
+	if err == nil || errors.As(err, &perr) {
+		return err
+	}
+	return &PositionError{Line: node.Line, Column: node.Column, Err: err}
+}
+
+// fieldNode returns the value of key in the mapping node, or node itself if
+// it has no such key, for reporting errors about a field.
+func fieldNode(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1]
+			}
+		}
+	}
+	return node
+}
+
+// DecodeKnownFields decodes node into v like node.Decode, but fails on
+// mapping keys that don't match a field of v, or of the structs it contains.
+// node.Decode silently ignores them, so a typo like on_conflit would be lost.
+func DecodeKnownFields(node *yaml.Node, v any) error {
+	if err := checkKnownFields(node, reflect.TypeOf(v)); err != nil {
+		return err
+	}
+	return node.Decode(v)
+}
+
+// unmarshalerType is the interface of types that decode themselves.
+var unmarshalerType = reflect.TypeFor[yaml.Unmarshaler]()
+
+// checkKnownFields checks the keys of node, and of the mappings nested in it,
+// against the fields of t. Types that decode themselves check their own keys.
+func checkKnownFields(node *yaml.Node, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	switch {
+	case node.Kind == yaml.SequenceNode && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		for _, item := range node.Content {
+			if err := checkKnownFields(item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields, inlineMap := yamlFields(t)
+		if inlineMap {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			field, ok := fields[key.Value]
+			if !ok {
+				names := make([]string, 0, len(fields))
+				for name := range fields {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				return errorAt(key, &UnknownFieldError{Field: key.Value, Available: names})
+			}
+			if err := checkKnownFields(node.Content[i+1], field.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// yamlFields returns the fields of the struct type t by their YAML key,
+// following yaml.v3's rules, and whether an inline map accepts any key.
+func yamlFields(t reflect.Type) (map[string]reflect.StructField, bool) {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			if field.Type.Kind() == reflect.Map {
+				return nil, true
+			}
+			inner, inlineMap := yamlFields(field.Type)
+			if inlineMap {
+				return nil, true
+			}
+			for k, v := range inner {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields, false
+}
@@ -0,0 +1,106 @@
+package rules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDecodeKnownFields(t *testing.T) {
+	type limits struct {
+		Min int `yaml:"min"`
+		Max int `yaml:"max"`
+	}
+	type target struct {
+		Dest     string        `yaml:"dest"`
+		Timeout  time.Duration `yaml:"timeout"`
+		Limits   *limits       `yaml:"limits"`
+		Ranges   []limits      `yaml:"ranges"`
+		Schedule *Schedule     `yaml:"schedule"`
+		Skipped  string        `yaml:"-"`
+	}
+
+	tests := []struct {
+		name        string
+		input       string
+		errContains string
+		line        int
+		column      int
+	}{
+		{name: "known fields", input: "dest: /a\ntimeout: 1s\nlimits: {min: 1}\nranges: [{max: 2}]"},
+		{name: "unknown field", input: "dest: /a\ndset: /b", errContains: `unknown field "dset", available: [dest limits ranges schedule timeout]`, line: 2, column: 1},
+		{name: "unknown nested field", input: "limits:\n  min: 1\n  mni: 2", errContains: `unknown field "mni"`, line: 3, column: 3},
+		{name: "unknown field in list", input: "ranges:\n  - {max: 2, mx: 3}", errContains: `unknown field "mx"`, line: 2, column: 14},
+		{name: "ignored field", input: "Skipped: x", errContains: `unknown field "Skipped"`, line: 1, column: 1},
+		{name: "self-decoding type", input: "schedule: {every: 1h}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.input), &node); err != nil {
+				t.Fatalf("invalid test input: %v", err)
+			}
+
+			var v target
+			err := DecodeKnownFields(node.Content[0], &v)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", tt.errContains)
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %v, want containing %q", err, tt.errContains)
+			}
+			var perr *PositionError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected a PositionError, got %T", err)
+			}
+			if perr.Line != tt.line || perr.Column != tt.column {
+				t.Errorf("position = %d:%d, want %d:%d", perr.Line, perr.Column, tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestRule_UnmarshalYAML_ErrorPosition(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		errContains string
+		line        int
+		column      int
+	}{
+		{name: "unknown field", input: "name: test\nrecursve: true", errContains: `unknown field "recursve"`, line: 2, column: 1},
+		{name: "relative location", input: "name: test\nlocations:\n  - Downloads", errContains: "location must be an absolute path", line: 3, column: 3},
+		{name: "invalid max_depth", input: "name: test\nmax_depth: 0", errContains: "max_depth must be at least 1", line: 2, column: 12},
+		{name: "invalid schedule", input: "name: test\nschedule:\n  every: 1h\n  evry: 2h", errContains: `unknown field "evry"`, line: 4, column: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r Rule
+			err := yaml.Unmarshal([]byte(tt.input), &r)
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", tt.errContains)
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %v, want containing %q", err, tt.errContains)
+			}
+			var perr *PositionError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected a PositionError, got %T", err)
+			}
+			if perr.Line != tt.line || perr.Column != tt.column {
+				t.Errorf("position = %d:%d, want %d:%d", perr.Line, perr.Column, tt.line, tt.column)
+			}
+		})
+	}
+}
@@ -11,6 +11,18 @@ type Evaluable interface {
 	Evaluate(path string) (bool, error)
 }
 
+// DetailedEvaluable is implemented by filters that can explain their result.
+// The detail is shown next to the filter in reports.
+type DetailedEvaluable interface {
+	EvaluateWithDetail(path string) (bool, string, error)
+}
+
+// LocationBinder is implemented by filters and actions that depend on the
+// locations of the rule they belong to. Locations are bound when the rule is loaded.
+type LocationBinder interface {
+	BindLocations(locations []string, recursive bool)
+}
+
 // FilterDeserializer is a function that creates an Evaluable from a YAML value.
 type FilterDeserializer func(value yaml.Node) (Evaluable, error)
 
@@ -33,15 +45,25 @@ func (f *Filter) Evaluate(path string) (bool, error) {
 	return f.Inner.Evaluate(path)
 }
 
+// EvaluateWithDetail evaluates the filter, including a detail for reporting
+// when the inner Evaluable provides one.
+func (f *Filter) EvaluateWithDetail(path string) (bool, string, error) {
+	if d, ok := f.Inner.(DetailedEvaluable); ok {
+		return d.EvaluateWithDetail(path)
+	}
+	matched, err := f.Inner.Evaluate(path)
+	return matched, "", err
+}
+
 // UnmarshalYAML implements custom YAML unmarshaling for Filter.
 // It expects a mapping with exactly one key that matches a registered filter name.
 func (f *Filter) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.MappingNode {
-		return fmt.Errorf("filter must be a mapping, got %v", node.Kind)
+		return errorAt(node, fmt.Errorf("filter must be a mapping, got %v", node.Kind))
 	}
 
 	if len(node.Content) != 2 {
-		return fmt.Errorf("filter must have exactly one key, got %d", len(node.Content)/2)
+		return errorAt(node, fmt.Errorf("filter must have exactly one key, got %d", len(node.Content)/2))
 	}
 
 	var name string
@@ -55,12 +77,12 @@ func (f *Filter) UnmarshalYAML(node *yaml.Node) error {
 		for k := range filterRegistry {
 			available = append(available, k)
 		}
-		return fmt.Errorf("unknown filter %q, available: %v", name, available)
+		return errorAt(node.Content[0], fmt.Errorf("unknown filter %q, available: %v", name, available))
 	}
 
 	inner, err := deserializer(*node.Content[1])
 	if err != nil {
-		return fmt.Errorf("failed to deserialize filter %q: %w", name, err)
+		return errorAt(node.Content[1], fmt.Errorf("failed to deserialize filter %q: %w", name, err))
 	}
 
 	f.Name = name
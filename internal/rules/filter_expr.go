@@ -24,24 +24,35 @@ type FilterExpr struct {
 // Evaluate evaluates the filter expression against a path.
 // The reporter records filter results for output.
 func (fe *FilterExpr) Evaluate(path string, r report.Reporter) (bool, error) {
+	return fe.evaluate(path, r, nil)
+}
+
+// evaluate evaluates the expression, merging captures into match if it passes.
+// Captures come from matching filters and the first matching any: branch,
+// never from not:.
+func (fe *FilterExpr) evaluate(path string, r report.Reporter, match *Match) (bool, error) {
 	// Short-circuit when not reporting
 	_, canShortCircuit := r.(report.NullReporter)
 
 	filtersMatched := true
 	anyMatched := true
 	notMatched := false
+	captured := newMatch()
 
 	// Evaluate regular filters (AND'd together)
 	for _, f := range fe.Filters {
-		matched, err := f.Evaluate(path)
+		matched, detail, err := f.EvaluateWithDetail(path)
 		if err != nil {
 			return false, err
 		}
-		r.RecordFilter(f.Name, matched, "")
+		r.RecordFilter(f.Name, matched, detail)
 		filtersMatched = filtersMatched && matched
 		if !filtersMatched && canShortCircuit {
 			return false, nil
 		}
+		if c, ok := f.Inner.(Capturer); ok && matched {
+			captured.merge(&Match{Vars: c.Captures(path)})
+		}
 	}
 
 	// Evaluate any: (OR - at least one must match)
@@ -49,7 +60,12 @@ func (fe *FilterExpr) Evaluate(path string, r report.Reporter) (bool, error) {
 		r.PushOperator("any")
 		anyMatched = false
 		for _, expr := range fe.Any {
-			matched, err := expr.Evaluate(path, r)
+			branch := captured
+			if anyMatched {
+				// Only the first matching branch contributes captures
+				branch = nil
+			}
+			matched, err := expr.evaluate(path, r, branch)
 			if err != nil {
 				return false, err
 			}
@@ -69,7 +85,7 @@ func (fe *FilterExpr) Evaluate(path string, r report.Reporter) (bool, error) {
 		r.PushOperator("not")
 		notMatched = true
 		for _, expr := range fe.Not {
-			matched, err := expr.Evaluate(path, r)
+			matched, err := expr.evaluate(path, r, nil)
 			if err != nil {
 				return false, err
 			}
@@ -84,7 +100,24 @@ func (fe *FilterExpr) Evaluate(path string, r report.Reporter) (bool, error) {
 		}
 	}
 
-	return filtersMatched && anyMatched && !notMatched, nil
+	passed := filtersMatched && anyMatched && !notMatched
+	if passed {
+		match.merge(captured)
+	}
+	return passed, nil
+}
+
+// eachFilter calls fn for every filter in the expression and its children.
+func (fe *FilterExpr) eachFilter(fn func(f *Filter)) {
+	for i := range fe.Filters {
+		fn(&fe.Filters[i])
+	}
+	for _, child := range fe.Any {
+		child.eachFilter(fn)
+	}
+	for _, child := range fe.Not {
+		child.eachFilter(fn)
+	}
 }
 
 // UnmarshalYAML implements custom YAML unmarshaling for FilterExpr.
@@ -95,7 +128,7 @@ func (fe *FilterExpr) Evaluate(path string, r report.Reporter) (bool, error) {
 // - Multiple keys in mapping -> implicit AND
 func (fe *FilterExpr) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.MappingNode {
-		return fmt.Errorf("filter expression must be a mapping, got %v", node.Kind)
+		return errorAt(node, fmt.Errorf("filter expression must be a mapping, got %v", node.Kind))
 	}
 
 	// Process key-value pairs in the mapping
@@ -113,7 +146,7 @@ func (fe *FilterExpr) UnmarshalYAML(node *yaml.Node) error {
 			// Parse any: as OR operator
 			children, err := parseFilterExprList(valueNode)
 			if err != nil {
-				return fmt.Errorf("failed to parse 'any' operator: %w", err)
+				return errorAt(valueNode, fmt.Errorf("failed to parse 'any' operator: %w", err))
 			}
 			fe.Any = children
 
@@ -121,7 +154,7 @@ func (fe *FilterExpr) UnmarshalYAML(node *yaml.Node) error {
 			// Parse not: as NOT operator
 			children, err := parseFilterExprList(valueNode)
 			if err != nil {
-				return fmt.Errorf("failed to parse 'not' operator: %w", err)
+				return errorAt(valueNode, fmt.Errorf("failed to parse 'not' operator: %w", err))
 			}
 			fe.Not = children
 
@@ -133,7 +166,7 @@ func (fe *FilterExpr) UnmarshalYAML(node *yaml.Node) error {
 					available = append(available, k)
 				}
 				sort.Strings(available)
-				return fmt.Errorf("unknown filter %q, available: %v", key, available)
+				return errorAt(keyNode, fmt.Errorf("unknown filter %q, available: %v", key, available))
 			}
 
 			// Create a filter from this key-value pair
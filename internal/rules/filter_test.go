@@ -71,3 +71,53 @@ func TestFilter_Evaluate(t *testing.T) {
 		})
 	}
 }
+
+// detailedEvaluable is a test helper that implements DetailedEvaluable.
+type detailedEvaluable struct {
+	mockEvaluable
+	detail string
+}
+
+func (d *detailedEvaluable) EvaluateWithDetail(path string) (bool, string, error) {
+	return d.result, d.detail, d.err
+}
+
+func TestFilter_EvaluateWithDetail(t *testing.T) {
+	testPath := testutil.Path("/", "test", "path")
+
+	tests := []struct {
+		name           string
+		inner          Evaluable
+		expected       bool
+		expectedDetail string
+	}{
+		{
+			name:           "uses detail from DetailedEvaluable",
+			inner:          &detailedEvaluable{mockEvaluable: mockEvaluable{result: true}, detail: "found it"},
+			expected:       true,
+			expectedDetail: "found it",
+		},
+		{
+			name:           "plain Evaluable has no detail",
+			inner:          &mockEvaluable{result: true},
+			expected:       true,
+			expectedDetail: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Filter{Name: "test", Inner: tt.inner}
+			got, detail, err := f.EvaluateWithDetail(testPath)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("EvaluateWithDetail() = %v, want %v", got, tt.expected)
+			}
+			if detail != tt.expectedDetail {
+				t.Errorf("detail = %q, want %q", detail, tt.expectedDetail)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package filters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rules.RegisterFilter("command", deserializeCommand)
+}
+
+// defaultCommandTimeout bounds each invocation when no timeout is configured.
+const defaultCommandTimeout = 10 * time.Second
+
+// maxCommandCacheEntries bounds the result cache. It is cleared when full.
+const maxCommandCacheEntries = 10000
+
+// maxCommandDetail limits how much stderr is shown in reports.
+const maxCommandDetail = 200
+
+// commandCacheKey identifies a file version. A changed size or
+// modification time invalidates the cached result.
+type commandCacheKey struct {
+	path    string
+	size    int64
+	modTime int64
+}
+
+// commandResult is a cached command outcome.
+type commandResult struct {
+	matched bool
+	detail  string
+}
+
+// Command is a filter that runs an external program and matches on exit status 0.
+// Results are cached per file version, so unchanged files don't spawn a process
+// on every run.
+type Command struct {
+	Args    []utils.Template
+	Timeout time.Duration // Defaults to 10 seconds
+	Fs      afero.Fs
+
+	mu        sync.Mutex
+	cache     map[commandCacheKey]commandResult
+	locations []string
+}
+
+// BindLocations implements rules.LocationBinder, for ${location} and ${relpath}.
+func (c *Command) BindLocations(locations []string, recursive bool) {
+	c.locations = locations
+}
+
+// Templates implements rules.Templated.
+func (c *Command) Templates() []utils.Template {
+	return c.Args
+}
+
+// getTimeout returns the timeout, defaulting to 10 seconds.
+func (c *Command) getTimeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultCommandTimeout
+	}
+	return c.Timeout
+}
+
+// expandArgs expands template variables like ${path} and ${name} in every argument.
+// The path is appended when no argument references ${path}.
+func (c *Command) expandArgs(fs afero.Fs, path string) ([]string, error) {
+	ctx := utils.FileContext{Fs: fs, Path: path, Locations: c.locations}
+	args := make([]string, 0, len(c.Args)+1)
+	hasPath := false
+	for _, arg := range c.Args {
+		if arg.References("path") {
+			hasPath = true
+		}
+		expanded, err := arg.ExpandTilde().Expand(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, expanded.String())
+	}
+	if !hasPath {
+		args = append(args, path)
+	}
+	return args, nil
+}
+
+// Evaluate runs the command and matches if it exits with status 0.
+func (c *Command) Evaluate(path string) (bool, error) {
+	matched, _, err := c.EvaluateWithDetail(path)
+	return matched, err
+}
+
+// EvaluateWithDetail runs the command and reports its stderr as the detail.
+func (c *Command) EvaluateWithDetail(path string) (bool, string, error) {
+	fs := c.Fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+
+	info, err := fs.Stat(path)
+	if err != nil {
+		return false, "", err
+	}
+
+	key := commandCacheKey{path: path, size: info.Size(), modTime: info.ModTime().UnixNano()}
+	if result, ok := c.cached(key); ok {
+		return result.matched, result.detail, nil
+	}
+
+	args, err := c.expandArgs(fs, path)
+	if err != nil {
+		return false, "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.getTimeout())
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	// Don't wait forever on children that keep stderr open
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	detail := strings.TrimSpace(stderr.String())
+	if len(detail) > maxCommandDetail {
+		detail = detail[:maxCommandDetail] + "…"
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		// Not cached, the next run may be faster
+		return false, fmt.Sprintf("timed out after %s", c.getTimeout()), nil
+	case err == nil:
+		c.store(key, commandResult{matched: true, detail: detail})
+		return true, detail, nil
+	case errors.As(err, &exitErr):
+		if detail == "" {
+			detail = fmt.Sprintf("exit status %d", exitErr.ExitCode())
+		}
+		c.store(key, commandResult{matched: false, detail: detail})
+		return false, detail, nil
+	default:
+		return false, "", &rules.ItemError{Err: fmt.Errorf("failed to run %s: %w", args[0], err)}
+	}
+}
+
+// cached returns the cached result for a file version, if any.
+func (c *Command) cached(key commandCacheKey) (commandResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.cache[key]
+	return result, ok
+}
+
+// store caches a result, clearing the cache first if it is full.
+func (c *Command) store(key commandCacheKey, result commandResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache == nil || len(c.cache) >= maxCommandCacheEntries {
+		c.cache = make(map[commandCacheKey]commandResult)
+	}
+	c.cache[key] = result
+}
+
+// deserializeCommand creates a Command filter from YAML.
+// Supports both "command: [is-screenshot]" and
+// `command: {args: [grep, -q, TODO, "${path}"], timeout: 5s}`.
+func deserializeCommand(node yaml.Node) (rules.Evaluable, error) {
+	// Arguments are never split by a shell, so a plain string is ambiguous
+	if node.Kind == yaml.ScalarNode {
+		return nil, fmt.Errorf("command filter requires a list of arguments, e.g. [is-screenshot, \"${path}\"]")
+	}
+
+	c := &Command{Fs: afero.NewOsFs()}
+	if node.Kind == yaml.SequenceNode {
+		if err := node.Decode(&c.Args); err != nil {
+			return nil, err
+		}
+	} else {
+		var m struct {
+			Args    []utils.Template `yaml:"args"`
+			Timeout time.Duration    `yaml:"timeout"`
+		}
+		if err := rules.DecodeKnownFields(&node, &m); err != nil {
+			return nil, err
+		}
+		if m.Timeout < 0 {
+			return nil, fmt.Errorf("command timeout must be positive, got %s", m.Timeout)
+		}
+		c.Args = m.Args
+		c.Timeout = m.Timeout
+	}
+
+	if len(c.Args) == 0 || c.Args[0] == "" {
+		return nil, fmt.Errorf("command filter requires a program")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,235 @@
+package filters
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// shellCommand builds a Command that runs a POSIX shell script with the file path as $1.
+func shellCommand(t *testing.T, script string) *Command {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	return &Command{
+		Args: []utils.Template{"sh", "-c", utils.Template(script), "sh"},
+		Fs:   afero.NewOsFs(),
+	}
+}
+
+func TestCommand_Evaluate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("TODO: write tests"), 0644)
+
+	tests := []struct {
+		name           string
+		script         string
+		expected       bool
+		expectedDetail string
+	}{
+		{"exit 0 matches", `grep -q TODO "$1"`, true, ""},
+		{"non-zero exit does not match", `grep -q DONE "$1"`, false, "exit status 1"},
+		{"stderr is the detail", `echo "no DONE marker" >&2; exit 1`, false, "no DONE marker"},
+		{"stderr on match", `echo "found TODO" >&2`, true, "found TODO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := shellCommand(t, tt.script)
+			matched, detail, err := c.EvaluateWithDetail(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matched != tt.expected {
+				t.Errorf("matched = %v, want %v", matched, tt.expected)
+			}
+			if detail != tt.expectedDetail {
+				t.Errorf("detail = %q, want %q", detail, tt.expectedDetail)
+			}
+		})
+	}
+}
+
+func TestCommand_Evaluate_ExplicitPath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.jpg")
+	os.WriteFile(path, []byte("jpg"), 0644)
+
+	// ${path} in the arguments means it isn't appended again
+	c := shellCommand(t, `test "$#" -eq 2 && test "$1" = "$2"`)
+	c.Args = append(c.Args, "${path}", "${path}")
+
+	matched, err := c.Evaluate(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !matched {
+		t.Error("expected exactly the two explicit path arguments")
+	}
+}
+
+func TestCommand_ExpandArgs(t *testing.T) {
+	path := filepath.Join(string(filepath.Separator), "downloads", "Photo.JPG")
+
+	tests := []struct {
+		name     string
+		args     []utils.Template
+		expected []string
+	}{
+		{"path appended", []utils.Template{"exiftool", "-q"}, []string{"exiftool", "-q", path}},
+		{"explicit path", []utils.Template{"exiftool", "${path}"}, []string{"exiftool", path}},
+		{"path with pipe", []utils.Template{"check", "${path|lower}"}, []string{"check", strings.ToLower(path)}},
+		{"path inside argument", []utils.Template{"cp", "${path}", "${path}.bak"}, []string{"cp", path, path + ".bak"}},
+		{"other variables", []utils.Template{"check", "${name}"}, []string{"check", "Photo", path}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Command{Args: tt.args}
+			args, err := c.expandArgs(afero.NewMemMapFs(), path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(args, tt.expected) {
+				t.Errorf("args = %q, want %q", args, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCommand_Evaluate_Cache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	counter := filepath.Join(dir, "invocations")
+	os.WriteFile(path, []byte("v1"), 0644)
+
+	c := shellCommand(t, `echo run >> "`+counter+`"`)
+
+	invocations := func() int {
+		data, _ := os.ReadFile(counter)
+		return strings.Count(string(data), "run")
+	}
+
+	c.Evaluate(path)
+	c.Evaluate(path)
+	if got := invocations(); got != 1 {
+		t.Errorf("expected 1 invocation for an unchanged file, got %d", got)
+	}
+
+	// Changing the file invalidates the cache
+	os.WriteFile(path, []byte("version 2"), 0644)
+	c.Evaluate(path)
+	if got := invocations(); got != 2 {
+		t.Errorf("expected 2 invocations after the file changed, got %d", got)
+	}
+}
+
+func TestCommand_Evaluate_Timeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	os.WriteFile(path, []byte("content"), 0644)
+
+	c := shellCommand(t, `sleep 5`)
+	c.Timeout = 50 * time.Millisecond
+
+	matched, detail, err := c.EvaluateWithDetail(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if matched {
+		t.Error("timed out command should not match")
+	}
+	if !strings.Contains(detail, "timed out") {
+		t.Errorf("detail = %q, want timeout", detail)
+	}
+}
+
+func TestCommand_Evaluate_MissingProgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	os.WriteFile(path, []byte("content"), 0644)
+
+	c := &Command{Args: []utils.Template{"autotidy-test-no-such-program"}, Fs: afero.NewOsFs()}
+	_, err := c.Evaluate(path)
+
+	var itemErr *rules.ItemError
+	if !errors.As(err, &itemErr) {
+		t.Errorf("expected *rules.ItemError, got %v", err)
+	}
+}
+
+func TestDeserializeCommand(t *testing.T) {
+	tests := []struct {
+		name            string
+		yaml            string
+		expectedArgs    []string
+		expectedTimeout time.Duration
+		wantErr         bool
+	}{
+		{
+			name:         "argument list",
+			yaml:         "command: [is-screenshot]",
+			expectedArgs: []string{"is-screenshot"},
+		},
+		{
+			name:            "mapping with timeout",
+			yaml:            "command:\n  args: [grep, -q, TODO, \"${path}\"]\n  timeout: 5s",
+			expectedArgs:    []string{"grep", "-q", "TODO", "${path}"},
+			expectedTimeout: 5 * time.Second,
+		},
+		{
+			name:    "plain string is rejected",
+			yaml:    "command: is-screenshot",
+			wantErr: true,
+		},
+		{
+			name:    "empty args",
+			yaml:    "command: []",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f rules.Filter
+			err := yaml.Unmarshal([]byte(tt.yaml), &f)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c, ok := f.Inner.(*Command)
+			if !ok {
+				t.Fatalf("inner is not *Command, got %T", f.Inner)
+			}
+			if len(c.Args) != len(tt.expectedArgs) {
+				t.Fatalf("Args = %v, want %v", c.Args, tt.expectedArgs)
+			}
+			for i := range c.Args {
+				if c.Args[i].String() != tt.expectedArgs[i] {
+					t.Errorf("Args = %v, want %v", c.Args, tt.expectedArgs)
+					break
+				}
+			}
+			if c.Timeout != tt.expectedTimeout {
+				t.Errorf("Timeout = %s, want %s", c.Timeout, tt.expectedTimeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package filters
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rules.RegisterFilter("content", deserializeContent)
+}
+
+// defaultContentMaxBytes is how much of a file is searched by default.
+const defaultContentMaxBytes = 1024 * 1024
+
+// binarySniffBytes is how much of a file is checked for NUL bytes,
+// the same heuristic git uses to detect binary files.
+const binarySniffBytes = 8000
+
+// maxContentDetail limits how much matched text is shown in reports.
+const maxContentDetail = 40
+
+// Content is a filter that matches text inside files.
+// Literal text is compiled to an escaped regex, so both use the same matcher.
+// Binary files never match.
+type Content struct {
+	Pattern  *regexp.Regexp
+	MaxBytes int64 // Only the first MaxBytes are searched, defaults to 1 MiB
+	Fs       afero.Fs
+}
+
+// getMaxBytes returns the read limit, defaulting to 1 MiB.
+func (c *Content) getMaxBytes() int64 {
+	if c.MaxBytes <= 0 {
+		return defaultContentMaxBytes
+	}
+	return c.MaxBytes
+}
+
+// Evaluate checks if the file's contents match the pattern.
+func (c *Content) Evaluate(path string) (bool, error) {
+	matched, _, err := c.EvaluateWithDetail(path)
+	return matched, err
+}
+
+// EvaluateWithDetail checks the file's contents and reports the matched text.
+func (c *Content) EvaluateWithDetail(path string) (bool, string, error) {
+	fs := c.Fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+
+	info, err := fs.Stat(path)
+	if err != nil {
+		return false, "", err
+	}
+	if info.IsDir() {
+		return false, "", nil
+	}
+
+	f, err := fs.Open(path)
+	if err != nil {
+		return false, "", err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, c.getMaxBytes()))
+	if err != nil {
+		return false, "", err
+	}
+
+	sniff := data
+	if len(sniff) > binarySniffBytes {
+		sniff = sniff[:binarySniffBytes]
+	}
+	if bytes.IndexByte(sniff, 0) != -1 {
+		return false, "binary file", nil
+	}
+
+	match := c.Pattern.Find(data)
+	if match == nil {
+		return false, "", nil
+	}
+
+	text := string(match)
+	if len(text) > maxContentDetail {
+		text = text[:maxContentDetail] + "…"
+	}
+	return true, "found " + strconv.Quote(text), nil
+}
+
+// deserializeContent creates a Content filter from YAML.
+// Supports:
+//   - "content: Invoice No." (literal shorthand)
+//   - "content: {text: Invoice No., case_insensitive: true}"
+//   - "content: {regex: 'INV-\d{6}', max_bytes: 65536}"
+func deserializeContent(node yaml.Node) (rules.Evaluable, error) {
+	// Try as plain string first (treated as literal text)
+	if node.Kind == yaml.ScalarNode {
+		var text string
+		if err := node.Decode(&text); err != nil {
+			return nil, err
+		}
+		if text == "" {
+			return nil, fmt.Errorf("content filter requires text or regex")
+		}
+		return &Content{Pattern: regexp.MustCompile(regexp.QuoteMeta(text)), Fs: afero.NewOsFs()}, nil
+	}
+
+	// Otherwise expect a mapping with "text" or "regex" and options
+	var m struct {
+		Text            string `yaml:"text"`
+		Regex           string `yaml:"regex"`
+		CaseInsensitive bool   `yaml:"case_insensitive"`
+		MaxBytes        int64  `yaml:"max_bytes"`
+	}
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
+		return nil, err
+	}
+
+	if m.Text != "" && m.Regex != "" {
+		return nil, fmt.Errorf("content filter cannot have both text and regex")
+	}
+	if m.Text == "" && m.Regex == "" {
+		return nil, fmt.Errorf("content filter requires text or regex")
+	}
+	if m.MaxBytes < 0 {
+		return nil, fmt.Errorf("content max_bytes must be positive, got %d", m.MaxBytes)
+	}
+
+	pattern := m.Regex
+	if m.Text != "" {
+		pattern = regexp.QuoteMeta(m.Text)
+	}
+	if m.CaseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern %q: %w", m.Regex, err)
+	}
+
+	return &Content{Pattern: re, MaxBytes: m.MaxBytes, Fs: afero.NewOsFs()}, nil
+}
@@ -0,0 +1,150 @@
+package filters
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+func TestContent_Evaluate(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	invoicePath := testutil.Path("/", "invoice.txt")
+	binaryPath := testutil.Path("/", "image.bin")
+	longPath := testutil.Path("/", "long.txt")
+	dirPath := testutil.Path("/", "subdir")
+
+	afero.WriteFile(fs, invoicePath, []byte("ACME Corp\nInvoice No. INV-123456\nTotal: $10"), 0644)
+	afero.WriteFile(fs, binaryPath, []byte("Invoice No.\x00\x01\x02"), 0644)
+	afero.WriteFile(fs, longPath, []byte(strings.Repeat("x", 100)+"Invoice No."), 0644)
+	fs.Mkdir(dirPath, 0755)
+
+	tests := []struct {
+		name     string
+		pattern  string
+		maxBytes int64
+		path     string
+		expected bool
+		detail   string
+	}{
+		{"literal match", regexp.QuoteMeta("Invoice No."), 0, invoicePath, true, `found "Invoice No."`},
+		{"literal no match", regexp.QuoteMeta("Receipt"), 0, invoicePath, false, ""},
+		{"regex match", `INV-\d{6}`, 0, invoicePath, true, `found "INV-123456"`},
+		{"case-insensitive", "(?i)" + regexp.QuoteMeta("invoice no."), 0, invoicePath, true, `found "Invoice No."`},
+		{"case-sensitive by default", regexp.QuoteMeta("invoice no."), 0, invoicePath, false, ""},
+		{"binary files are skipped", regexp.QuoteMeta("Invoice No."), 0, binaryPath, false, "binary file"},
+		{"match beyond max_bytes", regexp.QuoteMeta("Invoice No."), 50, longPath, false, ""},
+		{"match within max_bytes", regexp.QuoteMeta("Invoice No."), 200, longPath, true, `found "Invoice No."`},
+		{"directory", ".", 0, dirPath, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Content{Pattern: regexp.MustCompile(tt.pattern), MaxBytes: tt.maxBytes, Fs: fs}
+			matched, detail, err := c.EvaluateWithDetail(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matched != tt.expected {
+				t.Errorf("matched = %v, want %v", matched, tt.expected)
+			}
+			if detail != tt.detail {
+				t.Errorf("detail = %q, want %q", detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestContent_Evaluate_NonExistent(t *testing.T) {
+	c := &Content{Pattern: regexp.MustCompile("x"), Fs: afero.NewMemMapFs()}
+	if _, err := c.Evaluate(testutil.Path("/", "missing.txt")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestDeserializeContent(t *testing.T) {
+	tests := []struct {
+		name            string
+		yaml            string
+		matches         string
+		doesNotMatch    string
+		expectedMaxSize int64
+		wantErr         bool
+	}{
+		{
+			name:         "literal shorthand",
+			yaml:         "content: Invoice No. (draft)",
+			matches:      "Invoice No. (draft)",
+			doesNotMatch: "Invoice No draft",
+		},
+		{
+			name:         "text with case_insensitive",
+			yaml:         "content:\n  text: invoice\n  case_insensitive: true",
+			matches:      "INVOICE",
+			doesNotMatch: "receipt",
+		},
+		{
+			name:            "regex with max_bytes",
+			yaml:            "content:\n  regex: 'INV-\\d{6}'\n  max_bytes: 65536",
+			matches:         "INV-123456",
+			doesNotMatch:    "INV-12",
+			expectedMaxSize: 65536,
+		},
+		{
+			name:    "both text and regex",
+			yaml:    "content:\n  text: a\n  regex: b",
+			wantErr: true,
+		},
+		{
+			name:    "neither text nor regex",
+			yaml:    "content:\n  case_insensitive: true",
+			wantErr: true,
+		},
+		{
+			name:    "invalid regex",
+			yaml:    "content:\n  regex: '[unclosed'",
+			wantErr: true,
+		},
+		{
+			name:    "negative max_bytes",
+			yaml:    "content:\n  text: a\n  max_bytes: -1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f rules.Filter
+			err := yaml.Unmarshal([]byte(tt.yaml), &f)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c, ok := f.Inner.(*Content)
+			if !ok {
+				t.Fatalf("inner is not *Content, got %T", f.Inner)
+			}
+			if !c.Pattern.MatchString(tt.matches) {
+				t.Errorf("pattern %q should match %q", c.Pattern, tt.matches)
+			}
+			if c.Pattern.MatchString(tt.doesNotMatch) {
+				t.Errorf("pattern %q should not match %q", c.Pattern, tt.doesNotMatch)
+			}
+			if c.MaxBytes != tt.expectedMaxSize {
+				t.Errorf("MaxBytes = %d, want %d", c.MaxBytes, tt.expectedMaxSize)
+			}
+		})
+	}
+}
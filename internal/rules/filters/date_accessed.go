@@ -3,9 +3,9 @@ package filters
 import (
 	"fmt"
 
+	"github.com/prettymuchbryce/autotidy/internal/filetime"
 	"github.com/prettymuchbryce/autotidy/internal/rules"
 
-	"github.com/djherbis/times"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,13 +21,11 @@ type DateAccessed struct {
 
 // Evaluate checks if the file's access time matches the criteria.
 func (d *DateAccessed) Evaluate(path string) (bool, error) {
-	t, err := times.Stat(path)
+	accessTime, err := filetime.Stat(path, filetime.Accessed)
 	if err != nil {
 		return false, err
 	}
 
-	accessTime := t.AccessTime()
-
 	if d.Before != nil {
 		threshold, err := d.Before.ToTime()
 		if err != nil {
@@ -54,7 +52,7 @@ func (d *DateAccessed) Evaluate(path string) (bool, error) {
 // deserializeDateAccessed creates a DateAccessed filter from YAML.
 func deserializeDateAccessed(node yaml.Node) (rules.Evaluable, error) {
 	var d DateAccessed
-	if err := node.Decode(&d); err != nil {
+	if err := rules.DecodeKnownFields(&node, &d); err != nil {
 		return nil, err
 	}
 
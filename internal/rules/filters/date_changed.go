@@ -3,9 +3,9 @@ package filters
 import (
 	"fmt"
 
+	"github.com/prettymuchbryce/autotidy/internal/filetime"
 	"github.com/prettymuchbryce/autotidy/internal/rules"
 
-	"github.com/djherbis/times"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,17 +21,11 @@ type DateChanged struct {
 
 // Evaluate checks if the file's change time matches the criteria.
 func (d *DateChanged) Evaluate(path string) (bool, error) {
-	t, err := times.Stat(path)
+	changeTime, err := filetime.Stat(path, filetime.Changed)
 	if err != nil {
 		return false, err
 	}
 
-	if !t.HasChangeTime() {
-		return false, fmt.Errorf("change time is not available for %s", path)
-	}
-
-	changeTime := t.ChangeTime()
-
 	if d.Before != nil {
 		threshold, err := d.Before.ToTime()
 		if err != nil {
@@ -58,7 +52,7 @@ func (d *DateChanged) Evaluate(path string) (bool, error) {
 // deserializeDateChanged creates a DateChanged filter from YAML.
 func deserializeDateChanged(node yaml.Node) (rules.Evaluable, error) {
 	var d DateChanged
-	if err := node.Decode(&d); err != nil {
+	if err := rules.DecodeKnownFields(&node, &d); err != nil {
 		return nil, err
 	}
 
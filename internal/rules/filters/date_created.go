@@ -3,9 +3,9 @@ package filters
 import (
 	"fmt"
 
+	"github.com/prettymuchbryce/autotidy/internal/filetime"
 	"github.com/prettymuchbryce/autotidy/internal/rules"
 
-	"github.com/djherbis/times"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,17 +21,11 @@ type DateCreated struct {
 
 // Evaluate checks if the file's creation time matches the criteria.
 func (d *DateCreated) Evaluate(path string) (bool, error) {
-	t, err := times.Stat(path)
+	birthTime, err := filetime.Stat(path, filetime.Created)
 	if err != nil {
 		return false, err
 	}
 
-	if !t.HasBirthTime() {
-		return false, fmt.Errorf("creation time is not available for %s", path)
-	}
-
-	birthTime := t.BirthTime()
-
 	if d.Before != nil {
 		threshold, err := d.Before.ToTime()
 		if err != nil {
@@ -58,7 +52,7 @@ func (d *DateCreated) Evaluate(path string) (bool, error) {
 // deserializeDateCreated creates a DateCreated filter from YAML.
 func deserializeDateCreated(node yaml.Node) (rules.Evaluable, error) {
 	var d DateCreated
-	if err := node.Decode(&d); err != nil {
+	if err := rules.DecodeKnownFields(&node, &d); err != nil {
 		return nil, err
 	}
 
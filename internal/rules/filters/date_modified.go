@@ -3,9 +3,9 @@ package filters
 import (
 	"fmt"
 
+	"github.com/prettymuchbryce/autotidy/internal/filetime"
 	"github.com/prettymuchbryce/autotidy/internal/rules"
 
-	"github.com/djherbis/times"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,13 +21,11 @@ type DateModified struct {
 
 // Evaluate checks if the file's modification time matches the criteria.
 func (d *DateModified) Evaluate(path string) (bool, error) {
-	t, err := times.Stat(path)
+	modTime, err := filetime.Stat(path, filetime.Modified)
 	if err != nil {
 		return false, err
 	}
 
-	modTime := t.ModTime()
-
 	if d.Before != nil {
 		threshold, err := d.Before.ToTime()
 		if err != nil {
@@ -54,7 +52,7 @@ func (d *DateModified) Evaluate(path string) (bool, error) {
 // deserializeDateModified creates a DateModified filter from YAML.
 func deserializeDateModified(node yaml.Node) (rules.Evaluable, error) {
 	var d DateModified
-	if err := node.Decode(&d); err != nil {
+	if err := rules.DecodeKnownFields(&node, &d); err != nil {
 		return nil, err
 	}
 
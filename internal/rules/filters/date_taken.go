@@ -0,0 +1,96 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prettymuchbryce/autotidy/internal/media"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rules.RegisterFilter("date_taken", deserializeDateTaken)
+}
+
+// DateTaken is a filter that matches photos and videos by when they were captured,
+// read from EXIF metadata or the movie header. Files without a date never match.
+type DateTaken struct {
+	Before *DateSpec `yaml:"before"`
+	After  *DateSpec `yaml:"after"`
+	Fs     afero.Fs  `yaml:"-"`
+}
+
+// Evaluate checks if the file's capture date matches the criteria.
+func (d *DateTaken) Evaluate(path string) (bool, error) {
+	matched, _, err := d.EvaluateWithDetail(path)
+	return matched, err
+}
+
+// EvaluateWithDetail checks the capture date and reports it.
+func (d *DateTaken) EvaluateWithDetail(path string) (bool, string, error) {
+	fs := d.Fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+
+	taken, err := media.DateTaken(fs, path)
+	if errors.Is(err, media.ErrNoDateTaken) {
+		return false, "no date taken", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	detail := "taken " + taken.Format("2006-01-02 15:04:05")
+
+	if d.Before != nil {
+		threshold, err := d.Before.ToTime()
+		if err != nil {
+			return false, "", err
+		}
+		if !taken.Before(threshold) {
+			return false, detail, nil
+		}
+	}
+
+	if d.After != nil {
+		threshold, err := d.After.ToTime()
+		if err != nil {
+			return false, "", err
+		}
+		if !taken.After(threshold) {
+			return false, detail, nil
+		}
+	}
+
+	return true, detail, nil
+}
+
+// deserializeDateTaken creates a DateTaken filter from YAML.
+func deserializeDateTaken(node yaml.Node) (rules.Evaluable, error) {
+	var d DateTaken
+	if err := rules.DecodeKnownFields(&node, &d); err != nil {
+		return nil, err
+	}
+
+	if d.Before == nil && d.After == nil {
+		return nil, fmt.Errorf("date_taken filter requires at least one of: before, after")
+	}
+
+	if d.Before != nil {
+		if err := d.Before.Validate(); err != nil {
+			return nil, fmt.Errorf("before: %w", err)
+		}
+	}
+
+	if d.After != nil {
+		if err := d.After.Validate(); err != nil {
+			return nil, fmt.Errorf("after: %w", err)
+		}
+	}
+
+	d.Fs = afero.NewOsFs()
+	return &d, nil
+}
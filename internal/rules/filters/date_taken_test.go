@@ -0,0 +1,153 @@
+package filters
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// exifJPEG is a minimal JPEG whose EXIF DateTimeOriginal is 2019:07:14 09:30:15.
+const exifJPEG = "/9j/4AAHSkZJRgD/4QBIRXhpZgAASUkqAAgAAAABAGmHBAABAAAAGgAAAAAAAAABAAOQAgAUAAAALAAAAAAAAAAyMDE5OjA3OjE0IDA5OjMwOjE1AP/aAAISNP/Z"
+
+func TestDateTaken_Evaluate(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	photo := testutil.Path("/", "photo.jpg")
+	text := testutil.Path("/", "notes.txt")
+
+	data, _ := base64.StdEncoding.DecodeString(exifJPEG)
+	afero.WriteFile(fs, photo, data, 0644)
+	afero.WriteFile(fs, text, []byte("not a photo"), 0644)
+
+	date := func(s string) *DateSpec { return &DateSpec{Date: &s} }
+
+	tests := []struct {
+		name   string
+		filter DateTaken
+		path   string
+		want   bool
+		detail string
+	}{
+		{
+			name:   "before a later date",
+			filter: DateTaken{Before: date("2020-01-01")},
+			path:   photo,
+			want:   true,
+			detail: "taken 2019-07-14 09:30:15",
+		},
+		{
+			name:   "after a later date",
+			filter: DateTaken{After: date("2020-01-01")},
+			path:   photo,
+			want:   false,
+			detail: "taken 2019-07-14 09:30:15",
+		},
+		{
+			name:   "within a range",
+			filter: DateTaken{After: date("2019-07-01"), Before: date("2019-08-01")},
+			path:   photo,
+			want:   true,
+			detail: "taken 2019-07-14 09:30:15",
+		},
+		{
+			name:   "relative to now",
+			filter: DateTaken{Before: &DateSpec{YearsAgo: ptr(1.0)}},
+			path:   photo,
+			want:   true,
+			detail: "taken 2019-07-14 09:30:15",
+		},
+		{
+			name:   "file without a date never matches",
+			filter: DateTaken{Before: &DateSpec{MinutesAgo: ptr(-1.0)}},
+			path:   text,
+			want:   false,
+			detail: "no date taken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.Fs = fs
+			got, detail, err := tt.filter.EvaluateWithDetail(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+			if detail != tt.detail {
+				t.Errorf("detail = %q, want %q", detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestDateTaken_Evaluate_NonExistent(t *testing.T) {
+	d := &DateTaken{Before: &DateSpec{DaysAgo: ptr(1.0)}, Fs: afero.NewMemMapFs()}
+	if _, err := d.Evaluate(testutil.Path("/", "missing.jpg")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestDeserializeDateTaken(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name: "before date",
+			yaml: "date_taken:\n  before:\n    date: \"2024-01-01\"",
+		},
+		{
+			name: "after years_ago",
+			yaml: "date_taken:\n  after:\n    years_ago: 1",
+		},
+		{
+			name:        "empty filter",
+			yaml:        "date_taken: {}",
+			wantErr:     true,
+			errContains: "requires at least one of",
+		},
+		{
+			name:        "multiple time specs in after",
+			yaml:        "date_taken:\n  after:\n    days_ago: 7\n    hours_ago: 2",
+			wantErr:     true,
+			errContains: "only one time specification allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f rules.Filter
+			err := yaml.Unmarshal([]byte(tt.yaml), &f)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("error should contain %q, got: %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			d, ok := f.Inner.(*DateTaken)
+			if !ok {
+				t.Fatalf("inner is not *DateTaken, got %T", f.Inner)
+			}
+			if d.Fs == nil {
+				t.Error("expected Fs to be set")
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package filters
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rules.RegisterFilter("depth", deserializeDepth)
+}
+
+// Depth is a filter that matches files by how deep they are below the rule
+// location. Entries directly inside the location have depth 1.
+type Depth struct {
+	Min *int `yaml:"min"`
+	Max *int `yaml:"max"`
+
+	locations []string
+}
+
+// BindLocations records the rule's locations, which depth is measured from.
+func (d *Depth) BindLocations(locations []string, recursive bool) {
+	d.locations = locations
+}
+
+// Evaluate checks if the file's depth is within the bounds.
+// Files outside every location never match.
+func (d *Depth) Evaluate(path string) (bool, error) {
+	matched, _, err := d.EvaluateWithDetail(path)
+	return matched, err
+}
+
+// EvaluateWithDetail checks the depth and reports it.
+func (d *Depth) EvaluateWithDetail(path string) (bool, string, error) {
+	loc, ok := ruleLocation(d.locations, path)
+	if !ok {
+		return false, "outside rule locations", nil
+	}
+	rel, err := filepath.Rel(loc, path)
+	if err != nil {
+		return false, "", nil
+	}
+	depth := strings.Count(rel, string(filepath.Separator)) + 1
+	detail := fmt.Sprintf("depth %d", depth)
+
+	if d.Min != nil && depth < *d.Min {
+		return false, detail, nil
+	}
+	if d.Max != nil && depth > *d.Max {
+		return false, detail, nil
+	}
+	return true, detail, nil
+}
+
+// deserializeDepth creates a Depth filter from YAML.
+// Supports both "depth: 1" (exact depth) and "depth: {min: 2, max: 4}".
+func deserializeDepth(node yaml.Node) (rules.Evaluable, error) {
+	var d Depth
+	if node.Kind == yaml.ScalarNode {
+		var depth int
+		if err := node.Decode(&depth); err != nil {
+			return nil, fmt.Errorf("depth must be a number or a mapping with min/max: %w", err)
+		}
+		d.Min, d.Max = &depth, &depth
+	} else if err := rules.DecodeKnownFields(&node, &d); err != nil {
+		return nil, err
+	}
+
+	if d.Min == nil && d.Max == nil {
+		return nil, fmt.Errorf("depth filter requires at least one of: min, max")
+	}
+	if d.Min != nil && *d.Min < 1 {
+		return nil, fmt.Errorf("min depth must be at least 1, got %d", *d.Min)
+	}
+	if d.Max != nil && *d.Max < 1 {
+		return nil, fmt.Errorf("max depth must be at least 1, got %d", *d.Max)
+	}
+	if d.Min != nil && d.Max != nil && *d.Min > *d.Max {
+		return nil, fmt.Errorf("min depth %d is greater than max depth %d", *d.Min, *d.Max)
+	}
+
+	return &d, nil
+}
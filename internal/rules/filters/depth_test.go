@@ -0,0 +1,169 @@
+package filters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestDepth_Evaluate(t *testing.T) {
+	downloads := testutil.Path("/", "downloads")
+
+	tests := []struct {
+		name           string
+		min            *int
+		max            *int
+		path           string
+		expected       bool
+		expectedDetail string
+	}{
+		{
+			name:           "direct child is depth 1",
+			max:            intPtr(1),
+			path:           testutil.Path(downloads, "file.txt"),
+			expected:       true,
+			expectedDetail: "depth 1",
+		},
+		{
+			name:           "nested file exceeds max",
+			max:            intPtr(1),
+			path:           testutil.Path(downloads, "a", "file.txt"),
+			expected:       false,
+			expectedDetail: "depth 2",
+		},
+		{
+			name:           "below min",
+			min:            intPtr(2),
+			path:           testutil.Path(downloads, "file.txt"),
+			expected:       false,
+			expectedDetail: "depth 1",
+		},
+		{
+			name:           "within range",
+			min:            intPtr(2),
+			max:            intPtr(3),
+			path:           testutil.Path(downloads, "a", "b", "file.txt"),
+			expected:       true,
+			expectedDetail: "depth 3",
+		},
+		{
+			name:           "outside locations",
+			min:            intPtr(1),
+			path:           testutil.Path("/", "other", "file.txt"),
+			expected:       false,
+			expectedDetail: "outside rule locations",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Depth{Min: tt.min, Max: tt.max}
+			d.BindLocations([]string{downloads}, true)
+
+			matched, detail, err := d.EvaluateWithDetail(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matched != tt.expected {
+				t.Errorf("matched = %v, want %v", matched, tt.expected)
+			}
+			if detail != tt.expectedDetail {
+				t.Errorf("detail = %q, want %q", detail, tt.expectedDetail)
+			}
+		})
+	}
+}
+
+func TestDeserializeDepth(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		expectedMin *int
+		expectedMax *int
+		errContains string
+	}{
+		{
+			name:        "exact depth",
+			yaml:        "depth: 2",
+			expectedMin: intPtr(2),
+			expectedMax: intPtr(2),
+		},
+		{
+			name:        "min and max",
+			yaml:        "depth:\n  min: 2\n  max: 4",
+			expectedMin: intPtr(2),
+			expectedMax: intPtr(4),
+		},
+		{
+			name:        "max only",
+			yaml:        "depth:\n  max: 1",
+			expectedMax: intPtr(1),
+		},
+		{
+			name:        "not a number",
+			yaml:        "depth: deep",
+			errContains: "depth must be a number",
+		},
+		{
+			name:        "no bounds",
+			yaml:        "depth: {}",
+			errContains: "requires at least one of: min, max",
+		},
+		{
+			name:        "zero depth",
+			yaml:        "depth: 0",
+			errContains: "must be at least 1",
+		},
+		{
+			name:        "min greater than max",
+			yaml:        "depth:\n  min: 3\n  max: 2",
+			errContains: "greater than max depth",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f rules.Filter
+			err := yaml.Unmarshal([]byte(tt.yaml), &f)
+
+			if tt.errContains != "" {
+				if err == nil {
+					t.Fatalf("expected error containing %q, got nil", tt.errContains)
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("error = %q, want it to contain %q", err.Error(), tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			d, ok := f.Inner.(*Depth)
+			if !ok {
+				t.Fatalf("inner is not *Depth, got %T", f.Inner)
+			}
+			if !equalIntPtr(d.Min, tt.expectedMin) {
+				t.Errorf("Min = %v, want %v", d.Min, tt.expectedMin)
+			}
+			if !equalIntPtr(d.Max, tt.expectedMax) {
+				t.Errorf("Max = %v, want %v", d.Max, tt.expectedMax)
+			}
+		})
+	}
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,395 @@
+package filters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/prettymuchbryce/autotidy/internal/pathutil"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/state"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rules.RegisterFilter("duplicate", deserializeDuplicate)
+}
+
+// fastHashBlock is how much of the start and end of a file the fast hash reads.
+const fastHashBlock = 64 * 1024
+
+// OriginalPolicy decides which of several identical files is the original.
+// Every other copy is a duplicate.
+type OriginalPolicy string
+
+const (
+	OriginalOldest       OriginalPolicy = "oldest"        // Earliest modification time
+	OriginalShortestName OriginalPolicy = "shortest_name" // Shortest filename, e.g. photo.jpg over "photo (1).jpg"
+	OriginalReference    OriginalPolicy = "reference"     // Copies inside a reference directory
+)
+
+// Duplicate is a filter that matches files whose contents are identical to
+// another file. Candidates are narrowed down by size, then by a hash of the
+// first and last blocks, and only then by a hash of the entire file.
+type Duplicate struct {
+	ReferenceDirs []string       // Compare against these directories instead of the file's location
+	Original      OriginalPolicy // Defaults to reference with ReferenceDirs, oldest otherwise
+	Fs            afero.Fs
+	Cache         *state.HashCache // Defaults to the persistent shared cache
+
+	locations []string
+	recursive bool
+
+	mu    sync.Mutex
+	index map[string]sizeIndex // Files by size for each scope, built once per run
+}
+
+// sizeIndex groups the regular files in a scope by size.
+type sizeIndex map[int64][]duplicateFile
+
+// duplicateFile is a candidate file and its metadata.
+type duplicateFile struct {
+	path string
+	info os.FileInfo
+}
+
+// BindLocations records the rule's locations, which are the default scope.
+func (d *Duplicate) BindLocations(locations []string, recursive bool) {
+	d.locations = locations
+	d.recursive = recursive
+}
+
+// StartRun implements rules.RunScoped. Files are indexed once per run, since
+// the run's actions change what is on disk.
+func (d *Duplicate) StartRun() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.index = map[string]sizeIndex{}
+}
+
+// getOriginalPolicy returns the policy, defaulting to reference when reference
+// directories are configured and oldest otherwise.
+func (d *Duplicate) getOriginalPolicy() OriginalPolicy {
+	if d.Original != "" {
+		return d.Original
+	}
+	if len(d.ReferenceDirs) > 0 {
+		return OriginalReference
+	}
+	return OriginalOldest
+}
+
+// Evaluate checks if another file with identical contents is the original.
+func (d *Duplicate) Evaluate(path string) (bool, error) {
+	matched, _, err := d.EvaluateWithDetail(path)
+	return matched, err
+}
+
+// EvaluateWithDetail checks for duplicates and reports the original.
+func (d *Duplicate) EvaluateWithDetail(path string) (bool, string, error) {
+	fs := d.Fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+	cache := d.Cache
+	if cache == nil {
+		cache = state.SharedHashCache()
+	}
+
+	info, err := fs.Stat(path)
+	if err != nil {
+		return false, "", err
+	}
+	// Empty files are all identical, so they are never treated as duplicates
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return false, "", nil
+	}
+
+	candidates, err := d.candidates(fs, path, info.Size())
+	if err != nil {
+		return false, "", err
+	}
+
+	self := duplicateFile{path: path, info: info}
+	original := self
+	for _, c := range candidates {
+		same, err := sameHashes(fs, cache, self, c)
+		if os.IsNotExist(err) {
+			// Removed since the scan, e.g. by an earlier action in this run
+			continue
+		}
+		if err != nil {
+			return false, "", err
+		}
+		if same && d.precedes(c, original) {
+			original = c
+		}
+	}
+
+	if original.path == path {
+		return false, "", nil
+	}
+	return true, "duplicate of " + original.path, nil
+}
+
+// scope returns the directories to search for copies, and whether to search
+// them recursively.
+func (d *Duplicate) scope(path string) ([]string, bool) {
+	if len(d.ReferenceDirs) > 0 {
+		return d.ReferenceDirs, true
+	}
+
+	// The rule location containing the file, preferring the most specific
+	var best string
+	for _, loc := range d.locations {
+		if filepath.Dir(path) == loc || (d.recursive && pathutil.IsBelow(loc, path)) {
+			if len(loc) > len(best) {
+				best = loc
+			}
+		}
+	}
+	if best == "" {
+		return []string{filepath.Dir(path)}, false
+	}
+	return []string{best}, d.recursive
+}
+
+// candidates returns regular files in scope with the given size, excluding path.
+func (d *Duplicate) candidates(fs afero.Fs, path string, size int64) ([]duplicateFile, error) {
+	dirs, recursive := d.scope(path)
+	index, err := d.sizeIndex(fs, dirs, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []duplicateFile
+	for _, f := range index[size] {
+		if f.path != path {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// sizeIndex returns the index of the scope, reusing the one built earlier in
+// the run. Outside a run, a new index is built every time.
+func (d *Duplicate) sizeIndex(fs afero.Fs, dirs []string, recursive bool) (sizeIndex, error) {
+	key := fmt.Sprintf("%q %v", dirs, recursive)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if index, ok := d.index[key]; ok {
+		return index, nil
+	}
+
+	index, err := buildSizeIndex(fs, dirs, recursive)
+	if err != nil {
+		return nil, err
+	}
+	if d.index != nil {
+		d.index[key] = index
+	}
+	return index, nil
+}
+
+// buildSizeIndex lists the non-empty regular files in dirs.
+func buildSizeIndex(fs afero.Fs, dirs []string, recursive bool) (sizeIndex, error) {
+	index := sizeIndex{}
+	seen := map[string]bool{}
+
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		entries, err := afero.ReadDir(fs, dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			p := filepath.Join(dir, entry.Name())
+			if entry.IsDir() {
+				if recursive {
+					if err := walk(p); err != nil && !os.IsNotExist(err) {
+						return err
+					}
+				}
+				continue
+			}
+			if !entry.Mode().IsRegular() || entry.Size() == 0 || seen[p] {
+				continue
+			}
+			seen[p] = true
+			index[entry.Size()] = append(index[entry.Size()], duplicateFile{path: p, info: entry})
+		}
+		return nil
+	}
+
+	for _, dir := range dirs {
+		if err := walk(dir); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// precedes reports whether a should be considered the original over b.
+func (d *Duplicate) precedes(a, b duplicateFile) bool {
+	if d.getOriginalPolicy() == OriginalReference {
+		aRef, bRef := d.inReferenceDir(a.path), d.inReferenceDir(b.path)
+		if aRef != bRef {
+			return aRef
+		}
+	}
+
+	aName, bName := len(filepath.Base(a.path)), len(filepath.Base(b.path))
+	if d.getOriginalPolicy() == OriginalShortestName && aName != bName {
+		return aName < bName
+	}
+
+	if !a.info.ModTime().Equal(b.info.ModTime()) {
+		return a.info.ModTime().Before(b.info.ModTime())
+	}
+	if aName != bName {
+		return aName < bName
+	}
+	return a.path < b.path
+}
+
+// inReferenceDir reports whether path is inside one of the reference directories.
+func (d *Duplicate) inReferenceDir(path string) bool {
+	for _, dir := range d.ReferenceDirs {
+		if pathutil.IsBelow(dir, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameHashes reports whether two files of equal size have identical contents.
+// The full hash is only computed when the fast hashes match.
+func sameHashes(fs afero.Fs, cache *state.HashCache, a, b duplicateFile) (bool, error) {
+	ah, err := fileHashes(fs, cache, a, false)
+	if err != nil {
+		return false, err
+	}
+	bh, err := fileHashes(fs, cache, b, false)
+	if err != nil {
+		return false, err
+	}
+	if ah.Fast != bh.Fast {
+		return false, nil
+	}
+
+	// The fast hash already covered the entire file
+	if a.info.Size() <= 2*fastHashBlock {
+		return true, nil
+	}
+
+	ah, err = fileHashes(fs, cache, a, true)
+	if err != nil {
+		return false, err
+	}
+	bh, err = fileHashes(fs, cache, b, true)
+	if err != nil {
+		return false, err
+	}
+	return ah.Full == bh.Full, nil
+}
+
+// fileHashes returns the hashes of a file, computing and caching any that are missing.
+func fileHashes(fs afero.Fs, cache *state.HashCache, f duplicateFile, full bool) (state.FileHash, error) {
+	h := cache.Get(f.path, f.info.Size(), f.info.ModTime())
+	if h.Fast != "" && (!full || h.Full != "") {
+		return h, nil
+	}
+
+	file, err := fs.Open(f.path)
+	if err != nil {
+		return h, err
+	}
+	defer file.Close()
+
+	if h.Fast == "" {
+		if h.Fast, err = fastHash(file, f.info.Size()); err != nil {
+			return h, err
+		}
+	}
+	if full && h.Full == "" {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return h, err
+		}
+		hasher := sha256.New()
+		if _, err := io.Copy(hasher, file); err != nil {
+			return h, err
+		}
+		h.Full = hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	cache.Put(f.path, h)
+	return h, nil
+}
+
+// fastHash hashes the first and last blocks of a file.
+func fastHash(file afero.File, size int64) (string, error) {
+	hasher := sha256.New()
+	if _, err := io.CopyN(hasher, file, min(size, fastHashBlock)); err != nil {
+		return "", err
+	}
+	if size > fastHashBlock {
+		offset := max(size-fastHashBlock, fastHashBlock)
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return "", err
+		}
+		if _, err := io.CopyN(hasher, file, size-offset); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// deserializeDuplicate creates a Duplicate filter from YAML.
+// Supports:
+//   - "duplicate: oldest" (original policy shorthand)
+//   - "duplicate: {original: shortest_name}"
+//   - "duplicate: {reference_dirs: [~/Pictures], original: reference}"
+func deserializeDuplicate(node yaml.Node) (rules.Evaluable, error) {
+	d := &Duplicate{Fs: afero.NewOsFs()}
+
+	if node.Kind == yaml.ScalarNode {
+		if err := node.Decode(&d.Original); err != nil {
+			return nil, err
+		}
+	} else {
+		var m struct {
+			ReferenceDirs rules.StringList `yaml:"reference_dirs"`
+			Original      OriginalPolicy   `yaml:"original"`
+		}
+		if err := rules.DecodeKnownFields(&node, &m); err != nil {
+			return nil, err
+		}
+		d.Original = m.Original
+
+		for _, dir := range m.ReferenceDirs {
+			dir = pathutil.ExpandTilde(dir)
+			if !filepath.IsAbs(dir) {
+				return nil, fmt.Errorf("reference directory must be an absolute path: %s", dir)
+			}
+			d.ReferenceDirs = append(d.ReferenceDirs, filepath.Clean(dir))
+		}
+	}
+
+	switch d.Original {
+	case "", OriginalOldest, OriginalShortestName:
+	case OriginalReference:
+		if len(d.ReferenceDirs) == 0 {
+			return nil, fmt.Errorf("duplicate original policy %q requires reference_dirs", d.Original)
+		}
+	default:
+		return nil, fmt.Errorf("invalid duplicate original policy %q, must be one of: oldest, shortest_name, reference", d.Original)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,365 @@
+package filters
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/state"
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// writeAged writes a file with a modification time days in the past.
+func writeAged(t *testing.T, fs afero.Fs, path string, content []byte, daysAgo int) {
+	t.Helper()
+	if err := afero.WriteFile(fs, path, content, 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	mtime := time.Now().AddDate(0, 0, -daysAgo)
+	if err := fs.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("failed to set times on %s: %v", path, err)
+	}
+}
+
+func newHashCache(t *testing.T) *state.HashCache {
+	t.Helper()
+	cache, err := state.LoadHashCacheFrom("")
+	if err != nil {
+		t.Fatalf("failed to create hash cache: %v", err)
+	}
+	return cache
+}
+
+func TestDuplicate_Evaluate(t *testing.T) {
+	downloads := testutil.Path("/", "downloads")
+	pictures := testutil.Path("/", "pictures")
+
+	// Large files that only differ in the middle, so the fast hash collides
+	large := bytes.Repeat([]byte("a"), 3*fastHashBlock)
+	largeVariant := bytes.Clone(large)
+	largeVariant[len(largeVariant)/2] = 'b'
+
+	fs := afero.NewMemMapFs()
+	writeAged(t, fs, testutil.Path(downloads, "photo.jpg"), []byte("photo"), 10)
+	writeAged(t, fs, testutil.Path(downloads, "photo (1).jpg"), []byte("photo"), 20)
+	writeAged(t, fs, testutil.Path(downloads, "other.jpg"), []byte("other"), 30)
+	writeAged(t, fs, testutil.Path(downloads, "unique.txt"), []byte("unique"), 5)
+	writeAged(t, fs, testutil.Path(downloads, "empty1.txt"), nil, 5)
+	writeAged(t, fs, testutil.Path(downloads, "empty2.txt"), nil, 6)
+	writeAged(t, fs, testutil.Path(downloads, "large.bin"), large, 1)
+	writeAged(t, fs, testutil.Path(downloads, "large-copy.bin"), large, 2)
+	writeAged(t, fs, testutil.Path(downloads, "large-variant.bin"), largeVariant, 3)
+	writeAged(t, fs, testutil.Path(downloads, "nested", "photo.jpg"), []byte("photo"), 40)
+	writeAged(t, fs, testutil.Path(pictures, "2024", "holiday.jpg"), []byte("photo"), 1)
+
+	tests := []struct {
+		name          string
+		path          string
+		original      OriginalPolicy
+		referenceDirs []string
+		recursive     bool
+		expected      bool
+		detail        string
+	}{
+		{
+			name:     "newer copy is a duplicate of oldest",
+			path:     testutil.Path(downloads, "photo.jpg"),
+			expected: true,
+			detail:   "duplicate of " + testutil.Path(downloads, "photo (1).jpg"),
+		},
+		{
+			name:     "oldest copy is the original",
+			path:     testutil.Path(downloads, "photo (1).jpg"),
+			expected: false,
+		},
+		{
+			name:     "shortest name is the original",
+			path:     testutil.Path(downloads, "photo (1).jpg"),
+			original: OriginalShortestName,
+			expected: true,
+			detail:   "duplicate of " + testutil.Path(downloads, "photo.jpg"),
+		},
+		{
+			name:     "same size, different content",
+			path:     testutil.Path(downloads, "other.jpg"),
+			expected: false,
+		},
+		{
+			name:     "unique file",
+			path:     testutil.Path(downloads, "unique.txt"),
+			expected: false,
+		},
+		{
+			name:     "empty files are never duplicates",
+			path:     testutil.Path(downloads, "empty2.txt"),
+			expected: false,
+		},
+		{
+			name:     "large file compared by full hash",
+			path:     testutil.Path(downloads, "large.bin"),
+			expected: true,
+			detail:   "duplicate of " + testutil.Path(downloads, "large-copy.bin"),
+		},
+		{
+			name:     "large file differing only in the middle",
+			path:     testutil.Path(downloads, "large-variant.bin"),
+			expected: false,
+		},
+		{
+			name:      "recursive rule searches subdirectories",
+			path:      testutil.Path(downloads, "photo (1).jpg"),
+			recursive: true,
+			expected:  true,
+			detail:    "duplicate of " + testutil.Path(downloads, "nested", "photo.jpg"),
+		},
+		{
+			name:          "copy in reference directory is the original",
+			path:          testutil.Path(downloads, "photo (1).jpg"),
+			referenceDirs: []string{pictures},
+			expected:      true,
+			detail:        "duplicate of " + testutil.Path(pictures, "2024", "holiday.jpg"),
+		},
+		{
+			name:          "reference directory with oldest policy",
+			path:          testutil.Path(downloads, "photo (1).jpg"),
+			referenceDirs: []string{pictures},
+			original:      OriginalOldest,
+			expected:      false,
+		},
+		{
+			name:          "no copy in reference directory",
+			path:          testutil.Path(downloads, "unique.txt"),
+			referenceDirs: []string{pictures},
+			expected:      false,
+		},
+		{
+			name:          "missing reference directory",
+			path:          testutil.Path(downloads, "photo.jpg"),
+			referenceDirs: []string{testutil.Path("/", "missing")},
+			expected:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Duplicate{
+				ReferenceDirs: tt.referenceDirs,
+				Original:      tt.original,
+				Fs:            fs,
+				Cache:         newHashCache(t),
+			}
+			d.BindLocations([]string{downloads}, tt.recursive)
+
+			matched, detail, err := d.EvaluateWithDetail(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matched != tt.expected {
+				t.Errorf("matched = %v, want %v", matched, tt.expected)
+			}
+			if detail != tt.detail {
+				t.Errorf("detail = %q, want %q", detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestDuplicate_Evaluate_UsesHashCache(t *testing.T) {
+	dir := testutil.Path("/", "downloads")
+	a := testutil.Path(dir, "a.txt")
+	b := testutil.Path(dir, "b.txt")
+
+	fs := afero.NewMemMapFs()
+	writeAged(t, fs, a, []byte("aaaa"), 2)
+	writeAged(t, fs, b, []byte("bbbb"), 1)
+
+	cache := newHashCache(t)
+	d := &Duplicate{Fs: fs, Cache: cache}
+
+	matched, err := d.Evaluate(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if matched {
+		t.Fatal("files with different content should not match")
+	}
+
+	// Hashes are cached for both files
+	for _, path := range []string{a, b} {
+		info, _ := fs.Stat(path)
+		if h := cache.Get(path, info.Size(), info.ModTime()); h.Fast == "" {
+			t.Errorf("expected cached hash for %s", path)
+		}
+	}
+
+	// A cached hash is trusted while size and modification time are unchanged
+	infoA, _ := fs.Stat(a)
+	infoB, _ := fs.Stat(b)
+	hashB := cache.Get(b, infoB.Size(), infoB.ModTime())
+	cache.Put(a, state.FileHash{Size: infoA.Size(), ModTime: infoA.ModTime().UnixNano(), Fast: hashB.Fast})
+
+	matched, err = d.Evaluate(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !matched {
+		t.Error("expected cached hash to be used")
+	}
+
+	// Modifying the file invalidates the cached hash
+	writeAged(t, fs, a, []byte("aaaa"), 3)
+
+	matched, err = d.Evaluate(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if matched {
+		t.Error("expected stale cached hash to be ignored")
+	}
+}
+
+// dirListingFs counts how often each directory is listed.
+type dirListingFs struct {
+	afero.Fs
+	listings map[string]int
+}
+
+func (f *dirListingFs) Open(name string) (afero.File, error) {
+	if info, err := f.Fs.Stat(name); err == nil && info.IsDir() {
+		f.listings[name]++
+	}
+	return f.Fs.Open(name)
+}
+
+func TestDuplicate_Evaluate_IndexesOncePerRun(t *testing.T) {
+	dir := testutil.Path("/", "downloads")
+	fs := &dirListingFs{Fs: afero.NewMemMapFs(), listings: map[string]int{}}
+	for i, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		writeAged(t, fs.Fs, testutil.Path(dir, name), []byte("same"), 3-i)
+	}
+
+	d := &Duplicate{Fs: fs, Cache: newHashCache(t)}
+	d.BindLocations([]string{dir}, false)
+	d.StartRun()
+
+	var matches []bool
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		matched, err := d.Evaluate(testutil.Path(dir, name))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		matches = append(matches, matched)
+	}
+	if matches[0] || !matches[1] || !matches[2] {
+		t.Errorf("matches = %v, want only the newer copies", matches)
+	}
+	if fs.listings[dir] != 1 {
+		t.Errorf("directory listed %d times, want 1", fs.listings[dir])
+	}
+
+	// The next run sees files added since
+	writeAged(t, fs.Fs, testutil.Path(dir, "d.txt"), []byte("other"), 4)
+	writeAged(t, fs.Fs, testutil.Path(dir, "e.txt"), []byte("other"), 1)
+	d.StartRun()
+	matched, err := d.Evaluate(testutil.Path(dir, "e.txt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !matched {
+		t.Error("expected a file added before the run to be found")
+	}
+}
+
+func TestDeserializeDuplicate(t *testing.T) {
+	pictures := testutil.Path("/", "pictures")
+
+	tests := []struct {
+		name             string
+		yaml             string
+		expectedPolicy   OriginalPolicy
+		expectedRefDirs  []string
+		wantErr          bool
+		wantErrSubstring string
+	}{
+		{
+			name:           "empty mapping uses defaults",
+			yaml:           "duplicate: {}",
+			expectedPolicy: OriginalOldest,
+		},
+		{
+			name:           "policy shorthand",
+			yaml:           "duplicate: shortest_name",
+			expectedPolicy: OriginalShortestName,
+		},
+		{
+			name:            "reference_dirs defaults to reference policy",
+			yaml:            "duplicate:\n  reference_dirs: '" + pictures + "/'",
+			expectedPolicy:  OriginalReference,
+			expectedRefDirs: []string{pictures},
+		},
+		{
+			name:            "reference_dirs with explicit policy",
+			yaml:            "duplicate:\n  reference_dirs: ['" + pictures + "']\n  original: oldest",
+			expectedPolicy:  OriginalOldest,
+			expectedRefDirs: []string{pictures},
+		},
+		{
+			name:             "reference policy requires reference_dirs",
+			yaml:             "duplicate: reference",
+			wantErr:          true,
+			wantErrSubstring: "requires reference_dirs",
+		},
+		{
+			name:             "invalid policy",
+			yaml:             "duplicate: newest",
+			wantErr:          true,
+			wantErrSubstring: "invalid duplicate original policy",
+		},
+		{
+			name:             "relative reference directory",
+			yaml:             "duplicate:\n  reference_dirs: [pictures]",
+			wantErr:          true,
+			wantErrSubstring: "absolute path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f rules.Filter
+			err := yaml.Unmarshal([]byte(tt.yaml), &f)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error but got none")
+				}
+				if !strings.Contains(err.Error(), tt.wantErrSubstring) {
+					t.Errorf("error = %q, want substring %q", err, tt.wantErrSubstring)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			d, ok := f.Inner.(*Duplicate)
+			if !ok {
+				t.Fatalf("inner is not *Duplicate, got %T", f.Inner)
+			}
+			if d.getOriginalPolicy() != tt.expectedPolicy {
+				t.Errorf("policy = %q, want %q", d.getOriginalPolicy(), tt.expectedPolicy)
+			}
+			if len(d.ReferenceDirs) != len(tt.expectedRefDirs) {
+				t.Fatalf("ReferenceDirs = %v, want %v", d.ReferenceDirs, tt.expectedRefDirs)
+			}
+			for i := range d.ReferenceDirs {
+				if d.ReferenceDirs[i] != tt.expectedRefDirs[i] {
+					t.Errorf("ReferenceDirs[%d] = %q, want %q", i, d.ReferenceDirs[i], tt.expectedRefDirs[i])
+				}
+			}
+		})
+	}
+}
@@ -70,7 +70,7 @@ func deserializeExtension(node yaml.Node) (rules.Evaluable, error) {
 	var m struct {
 		Extensions rules.StringList `yaml:"extensions"`
 	}
-	if err := node.Decode(&m); err != nil {
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
 		return nil, err
 	}
 
@@ -93,7 +93,7 @@ type Size struct {
 	AtLeast     *SizeSpec    `yaml:"at_least"`
 	AtMost      *SizeSpec    `yaml:"at_most"`
 	Between     *SizeBetween `yaml:"between"`
-	Fs          afero.Fs
+	Fs          afero.Fs     `yaml:"-"`
 }
 
 // Evaluate checks if the file size matches the criteria.
@@ -224,7 +224,7 @@ func deserializeSize(node yaml.Node) (rules.Evaluable, error) {
 
 	// Otherwise expect a mapping
 	var s Size
-	if err := node.Decode(&s); err != nil {
+	if err := rules.DecodeKnownFields(&node, &s); err != nil {
 		return nil, err
 	}
 	s.Fs = afero.NewOsFs()
@@ -105,7 +105,7 @@ func deserializeFileType(node yaml.Node) (rules.Evaluable, error) {
 	var m struct {
 		Types rules.StringList `yaml:"types"`
 	}
-	if err := node.Decode(&m); err != nil {
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
 		return nil, err
 	}
 
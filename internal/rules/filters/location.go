@@ -0,0 +1,15 @@
+package filters
+
+import "github.com/prettymuchbryce/autotidy/internal/pathutil"
+
+// ruleLocation returns the most specific of the rule's locations that
+// contains path, and false if none does.
+func ruleLocation(locations []string, path string) (string, bool) {
+	var best string
+	for _, loc := range locations {
+		if pathutil.IsBelow(loc, path) && len(loc) > len(best) {
+			best = loc
+		}
+	}
+	return best, best != ""
+}
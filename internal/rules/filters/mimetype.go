@@ -93,7 +93,7 @@ func deserializeMimeType(node yaml.Node) (rules.Evaluable, error) {
 	var m struct {
 		MimeTypes rules.StringList `yaml:"mime_types"`
 	}
-	if err := node.Decode(&m); err != nil {
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
 		return nil, err
 	}
 
@@ -6,6 +6,7 @@ import (
 	"regexp"
 
 	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
 
 	"github.com/bmatcuk/doublestar/v4"
 	"gopkg.in/yaml.v3"
@@ -38,11 +39,45 @@ func (n *Name) Evaluate(path string) (bool, error) {
 	return matched, nil
 }
 
+// CaptureNames implements rules.Capturer, listing the regex's named groups.
+func (n *Name) CaptureNames() []string {
+	if n.Regex == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range n.Regex.SubexpNames() {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Captures implements rules.Capturer, returning the named groups of the
+// regex match, e.g. ${client} for (?P<client>[A-Z]+).
+func (n *Name) Captures(path string) map[string]string {
+	if n.Regex == nil {
+		return nil
+	}
+	submatches := n.Regex.FindStringSubmatch(filepath.Base(path))
+	if submatches == nil {
+		return nil
+	}
+	captures := make(map[string]string)
+	for i, name := range n.Regex.SubexpNames() {
+		if name != "" {
+			captures[name] = submatches[i]
+		}
+	}
+	return captures
+}
+
 // deserializeName creates a Name filter from YAML.
 // Supports:
 //   - "name: foo.jpg" (glob shorthand)
 //   - "name: {glob: foo.jpg}"
 //   - "name: {regex: '^foo\d+\.jpg$'}"
+//   - "name: {regex: '^(?P<client>[A-Z]+)_'}" (named groups become template variables)
 func deserializeName(node yaml.Node) (rules.Evaluable, error) {
 	// Try as plain string first (treated as glob)
 	if node.Kind == yaml.ScalarNode {
@@ -58,7 +93,7 @@ func deserializeName(node yaml.Node) (rules.Evaluable, error) {
 		Glob  string `yaml:"glob"`
 		Regex string `yaml:"regex"`
 	}
-	if err := node.Decode(&m); err != nil {
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
 		return nil, err
 	}
 
@@ -71,7 +106,13 @@ func deserializeName(node yaml.Node) (rules.Evaluable, error) {
 		if err != nil {
 			return nil, fmt.Errorf("invalid regex pattern %q: %w", m.Regex, err)
 		}
-		return &Name{Regex: re}, nil
+		n := &Name{Regex: re}
+		for _, group := range n.CaptureNames() {
+			if utils.IsFileVariable(group) {
+				return nil, fmt.Errorf("capture group %q conflicts with the template variable ${%s}", group, group)
+			}
+		}
+		return n, nil
 	}
 
 	return &Name{Glob: m.Glob}, nil
@@ -363,6 +363,17 @@ func TestDeserializeName_Regex(t *testing.T) {
 			wantErr:     true,
 			errContains: "cannot have both glob and regex",
 		},
+		{
+			name:          "named capture groups",
+			yaml:          "name:\n  regex: '^(?P<client>[A-Z]+)_(?P<year>\\d{4})'",
+			expectedRegex: `^(?P<client>[A-Z]+)_(?P<year>\d{4})`,
+		},
+		{
+			name:        "capture group named like a template variable",
+			yaml:        "name:\n  regex: '^(?P<name>.+)\\.txt$'",
+			wantErr:     true,
+			errContains: "conflicts with the template variable ${name}",
+		},
 	}
 
 	for _, tt := range tests {
@@ -403,3 +414,67 @@ func TestDeserializeName_Regex(t *testing.T) {
 		})
 	}
 }
+
+func TestName_Captures(t *testing.T) {
+	tests := []struct {
+		name          string
+		filter        *Name
+		path          string
+		expectedNames []string
+		expected      map[string]string
+	}{
+		{
+			name:          "named groups",
+			filter:        &Name{Regex: regexp.MustCompile(`^(?P<client>[A-Z]+)_(?P<year>\d{4})_`)},
+			path:          testutil.Path("/", "downloads", "ACME_2024_invoice.pdf"),
+			expectedNames: []string{"client", "year"},
+			expected:      map[string]string{"client": "ACME", "year": "2024"},
+		},
+		{
+			name:          "unnamed groups are ignored",
+			filter:        &Name{Regex: regexp.MustCompile(`^(\w+)-(?P<n>\d+)`)},
+			path:          testutil.Path("/", "scans", "scan-12.pdf"),
+			expectedNames: []string{"n"},
+			expected:      map[string]string{"n": "12"},
+		},
+		{
+			name:          "optional group that did not participate",
+			filter:        &Name{Regex: regexp.MustCompile(`^report(?P<suffix>_final)?\.pdf$`)},
+			path:          testutil.Path("/", "docs", "report.pdf"),
+			expectedNames: []string{"suffix"},
+			expected:      map[string]string{"suffix": ""},
+		},
+		{
+			name:          "no match",
+			filter:        &Name{Regex: regexp.MustCompile(`^(?P<client>[A-Z]+)_`)},
+			path:          testutil.Path("/", "downloads", "invoice.pdf"),
+			expectedNames: []string{"client"},
+			expected:      nil,
+		},
+		{
+			name:     "glob captures nothing",
+			filter:   &Name{Glob: "*.pdf"},
+			path:     testutil.Path("/", "downloads", "invoice.pdf"),
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := tt.filter.CaptureNames()
+			if strings.Join(names, ",") != strings.Join(tt.expectedNames, ",") {
+				t.Errorf("CaptureNames() = %v, want %v", names, tt.expectedNames)
+			}
+
+			captures := tt.filter.Captures(tt.path)
+			if len(captures) != len(tt.expected) {
+				t.Fatalf("Captures() = %v, want %v", captures, tt.expected)
+			}
+			for k, v := range tt.expected {
+				if captures[k] != v {
+					t.Errorf("Captures()[%q] = %q, want %q", k, captures[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package filters
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rules.RegisterFilter("path", deserializePath)
+}
+
+// Path is a filter that matches files by their path relative to the rule
+// location, like "photos/2024/img.jpg". Paths always use forward slashes.
+// Either Glob or Regex should be set, not both.
+type Path struct {
+	Glob  string
+	Regex *regexp.Regexp
+
+	locations []string
+}
+
+// BindLocations records the rule's locations, which paths are relative to.
+func (p *Path) BindLocations(locations []string, recursive bool) {
+	p.locations = locations
+}
+
+// Evaluate checks if the path relative to the rule location matches the pattern.
+// Files outside every location never match.
+func (p *Path) Evaluate(path string) (bool, error) {
+	loc, ok := ruleLocation(p.locations, path)
+	if !ok {
+		return false, nil
+	}
+	rel, err := filepath.Rel(loc, path)
+	if err != nil {
+		return false, nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	if p.Regex != nil {
+		return p.Regex.MatchString(rel), nil
+	}
+
+	matched, err := doublestar.Match(p.Glob, rel)
+	if err != nil {
+		return false, fmt.Errorf("invalid glob pattern %q: %w", p.Glob, err)
+	}
+	return matched, nil
+}
+
+// deserializePath creates a Path filter from YAML.
+// Supports:
+//   - "path: **/node_modules/**" (glob shorthand)
+//   - "path: {glob: 'projects/*/build/**'}"
+//   - "path: {regex: '^\d{4}/'}"
+func deserializePath(node yaml.Node) (rules.Evaluable, error) {
+	// Try as plain string first (treated as glob)
+	if node.Kind == yaml.ScalarNode {
+		var glob string
+		if err := node.Decode(&glob); err != nil {
+			return nil, err
+		}
+		if !doublestar.ValidatePattern(glob) {
+			return nil, fmt.Errorf("invalid glob pattern %q", glob)
+		}
+		return &Path{Glob: glob}, nil
+	}
+
+	// Otherwise expect a mapping with "glob" or "regex" key
+	var m struct {
+		Glob  string `yaml:"glob"`
+		Regex string `yaml:"regex"`
+	}
+	if err := rules.DecodeKnownFields(&node, &m); err != nil {
+		return nil, err
+	}
+
+	if m.Glob != "" && m.Regex != "" {
+		return nil, fmt.Errorf("path filter cannot have both glob and regex")
+	}
+	if m.Glob == "" && m.Regex == "" {
+		return nil, fmt.Errorf("path filter requires glob or regex")
+	}
+
+	if m.Regex != "" {
+		re, err := regexp.Compile(m.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern %q: %w", m.Regex, err)
+		}
+		return &Path{Regex: re}, nil
+	}
+
+	if !doublestar.ValidatePattern(m.Glob) {
+		return nil, fmt.Errorf("invalid glob pattern %q", m.Glob)
+	}
+	return &Path{Glob: m.Glob}, nil
+}
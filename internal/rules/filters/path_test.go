@@ -0,0 +1,169 @@
+package filters
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestPath_Evaluate(t *testing.T) {
+	projects := testutil.Path("/", "home", "user", "projects")
+	locations := []string{projects, testutil.Path(projects, "site")}
+
+	tests := []struct {
+		name     string
+		filter   *Path
+		path     string
+		expected bool
+	}{
+		{
+			name:     "file inside node_modules",
+			filter:   &Path{Glob: "**/node_modules/**"},
+			path:     testutil.Path(projects, "app", "node_modules", "left-pad", "index.js"),
+			expected: true,
+		},
+		{
+			name:     "file outside node_modules",
+			filter:   &Path{Glob: "**/node_modules/**"},
+			path:     testutil.Path(projects, "app", "src", "index.js"),
+			expected: false,
+		},
+		{
+			name:     "directory itself",
+			filter:   &Path{Glob: "**/.git"},
+			path:     testutil.Path(projects, "app", ".git"),
+			expected: true,
+		},
+		{
+			name:     "top level file",
+			filter:   &Path{Glob: "*.txt"},
+			path:     testutil.Path(projects, "notes.txt"),
+			expected: true,
+		},
+		{
+			name:     "single star does not cross directories",
+			filter:   &Path{Glob: "*.txt"},
+			path:     testutil.Path(projects, "app", "notes.txt"),
+			expected: false,
+		},
+		{
+			name:     "relative to the most specific location",
+			filter:   &Path{Glob: "assets/*"},
+			path:     testutil.Path(projects, "site", "assets", "logo.png"),
+			expected: true,
+		},
+		{
+			name:     "regex",
+			filter:   &Path{Regex: regexp.MustCompile(`^\w+/build/`)},
+			path:     testutil.Path(projects, "app", "build", "out.js"),
+			expected: true,
+		},
+		{
+			name:     "regex no match",
+			filter:   &Path{Regex: regexp.MustCompile(`^\w+/build/`)},
+			path:     testutil.Path(projects, "app", "src", "build", "out.js"),
+			expected: false,
+		},
+		{
+			name:     "outside locations",
+			filter:   &Path{Glob: "**"},
+			path:     testutil.Path("/", "tmp", "file.txt"),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.BindLocations(locations, true)
+			matched, err := tt.filter.Evaluate(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matched != tt.expected {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.path, matched, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDeserializePath(t *testing.T) {
+	tests := []struct {
+		name          string
+		yaml          string
+		expectedGlob  string
+		expectedRegex string
+		errContains   string
+	}{
+		{
+			name:         "glob shorthand",
+			yaml:         `path: "**/node_modules/**"`,
+			expectedGlob: "**/node_modules/**",
+		},
+		{
+			name:         "glob mapping",
+			yaml:         "path:\n  glob: 'photos/*/raw/**'",
+			expectedGlob: "photos/*/raw/**",
+		},
+		{
+			name:          "regex mapping",
+			yaml:          "path:\n  regex: '^\\d{4}/'",
+			expectedRegex: `^\d{4}/`,
+		},
+		{
+			name:        "invalid glob",
+			yaml:        `path: "[invalid"`,
+			errContains: "invalid glob pattern",
+		},
+		{
+			name:        "invalid regex",
+			yaml:        "path:\n  regex: '[invalid'",
+			errContains: "invalid regex pattern",
+		},
+		{
+			name:        "both glob and regex",
+			yaml:        "path:\n  glob: '*'\n  regex: '.*'",
+			errContains: "cannot have both glob and regex",
+		},
+		{
+			name:        "neither glob nor regex",
+			yaml:        "path: {}",
+			errContains: "requires glob or regex",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f rules.Filter
+			err := yaml.Unmarshal([]byte(tt.yaml), &f)
+
+			if tt.errContains != "" {
+				if err == nil {
+					t.Fatalf("expected error containing %q, got nil", tt.errContains)
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("error = %q, want it to contain %q", err.Error(), tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			p, ok := f.Inner.(*Path)
+			if !ok {
+				t.Fatalf("inner is not *Path, got %T", f.Inner)
+			}
+			if p.Glob != tt.expectedGlob {
+				t.Errorf("Glob = %q, want %q", p.Glob, tt.expectedGlob)
+			}
+			if tt.expectedRegex != "" && (p.Regex == nil || p.Regex.String() != tt.expectedRegex) {
+				t.Errorf("Regex = %v, want %q", p.Regex, tt.expectedRegex)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package rules
+
+// Match is the per-file context built while a rule's filters evaluate a file
+// and read by its actions while they execute on it. It carries values that
+// filters captured, like named regex groups, as template variables.
+type Match struct {
+	Vars map[string]string
+}
+
+// newMatch returns an empty Match.
+func newMatch() *Match {
+	return &Match{Vars: map[string]string{}}
+}
+
+// merge copies the captured variables of other into m.
+func (m *Match) merge(other *Match) {
+	if m == nil || other == nil {
+		return
+	}
+	for k, v := range other.Vars {
+		m.Vars[k] = v
+	}
+}
+
+// Capturer is implemented by filters that capture values from the files they
+// match, like named regex groups. Captures become template variables for the
+// rule's actions. CaptureNames lists every variable Captures may return, so
+// templates can be validated when the rule is loaded.
+type Capturer interface {
+	Captures(path string) map[string]string
+	CaptureNames() []string
+}
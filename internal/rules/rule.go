@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/prettymuchbryce/autotidy/internal/pathutil"
 
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 )
 
@@ -27,6 +30,17 @@ type Rule struct {
 	Locations StringList    `yaml:"locations"`
 	Actions   []Action      `yaml:"actions"`
 	Filters   *FilterGroups `yaml:"filters"`
+	Exclude   StringList    `yaml:"exclude"`   // Glob patterns of entries to skip
+	MaxDepth  *int          `yaml:"max_depth"` // nil means unlimited
+
+	SkipHidden       bool `yaml:"skip_hidden"`       // Skip entries whose names start with a dot
+	RespectGitignore bool `yaml:"respect_gitignore"` // Skip entries ignored by .gitignore files
+
+	WaitUntilStable *time.Duration `yaml:"wait_until_stable"` // nil uses the daemon default
+	Schedule        *Schedule      `yaml:"schedule"`          // nil only runs on changes
+	Watch           *Watch         `yaml:"watch"`             // nil uses filesystem events
+
+	captureNames []string // Template variables captured by filters, set when loaded
 }
 
 // UnmarshalYAML decodes the rule and normalizes location paths.
@@ -34,7 +48,7 @@ func (r *Rule) UnmarshalYAML(node *yaml.Node) error {
 	// Use an alias type to avoid infinite recursion
 	type RuleAlias Rule
 	var alias RuleAlias
-	if err := node.Decode(&alias); err != nil {
+	if err := DecodeKnownFields(node, &alias); err != nil {
 		return err
 	}
 	*r = Rule(alias)
@@ -46,16 +60,114 @@ func (r *Rule) UnmarshalYAML(node *yaml.Node) error {
 
 		// Require absolute paths
 		if !filepath.IsAbs(loc) {
-			return fmt.Errorf("location must be an absolute path: %s", loc)
+			return errorAt(fieldNode(node, "locations"), fmt.Errorf("location must be an absolute path: %s", loc))
 		}
 
 		// Clean the path (removes trailing slashes, resolves . and ..)
 		r.Locations[i] = filepath.Clean(loc)
 	}
 
+	// Normalize and validate exclude patterns
+	for i, pattern := range r.Exclude {
+		pattern = pathutil.ExpandTilde(pattern)
+		if filepath.IsAbs(pattern) {
+			pattern = filepath.Clean(pattern)
+		}
+		if !doublestar.ValidatePattern(filepath.ToSlash(pattern)) {
+			return errorAt(fieldNode(node, "exclude"), fmt.Errorf("invalid exclude pattern %q", r.Exclude[i]))
+		}
+		r.Exclude[i] = pattern
+	}
+
+	if r.MaxDepth != nil && *r.MaxDepth < 1 {
+		return errorAt(fieldNode(node, "max_depth"), fmt.Errorf("max_depth must be at least 1, got %d", *r.MaxDepth))
+	}
+
+	if r.WaitUntilStable != nil && *r.WaitUntilStable < 0 {
+		return errorAt(fieldNode(node, "wait_until_stable"), fmt.Errorf("wait_until_stable must not be negative, got %s", *r.WaitUntilStable))
+	}
+
+	// Give filters and actions that depend on the rule's locations access to them
+	if r.Filters != nil {
+		r.Filters.EachFilter(func(f *Filter) {
+			if b, ok := f.Inner.(LocationBinder); ok {
+				b.BindLocations(r.Locations, r.IsRecursive())
+			}
+		})
+	}
+	for _, a := range r.Actions {
+		if b, ok := a.Inner.(LocationBinder); ok {
+			b.BindLocations(r.Locations, r.IsRecursive())
+		}
+	}
+
+	// Collect the variables filters capture, for the actions' templates
+	r.captureNames = nil
+	if r.Filters != nil {
+		r.Filters.EachFilter(func(f *Filter) {
+			if c, ok := f.Inner.(Capturer); ok {
+				r.captureNames = append(r.captureNames, c.CaptureNames()...)
+			}
+		})
+	}
+
+	return r.validateTemplates(node)
+}
+
+// validateTemplates checks that every template in the rule's filters and
+// actions only references known variables. Captured variables are only
+// available to actions, since filters run before all captures are known.
+// Errors are reported at the position of the rule's filters or action.
+func (r *Rule) validateTemplates(node *yaml.Node) error {
+	var err error
+	if r.Filters != nil {
+		r.Filters.EachFilter(func(f *Filter) {
+			if err == nil {
+				err = validateTemplated(f.Inner, nil)
+				if err != nil {
+					err = fmt.Errorf("filter %q: %w", f.Name, err)
+				}
+			}
+		})
+		if err != nil {
+			return errorAt(fieldNode(node, "filters"), err)
+		}
+	}
+
+	actionsNode := fieldNode(node, "actions")
+	for i, a := range r.Actions {
+		if err := validateTemplated(a.Inner, r.captureNames); err != nil {
+			return errorAt(actionsNode.Content[i], fmt.Errorf("action %q: %w", a.Name, err))
+		}
+	}
+	return nil
+}
+
+// validateTemplated validates the templates of v, if it has any.
+func validateTemplated(v any, extra []string) error {
+	t, ok := v.(Templated)
+	if !ok {
+		return nil
+	}
+	for _, tmpl := range t.Templates() {
+		if err := tmpl.Validate(extra...); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// newMatch returns the match context for a file. Every captured variable
+// starts out empty, so one from a filter that didn't capture anything, like
+// an unmatched any: branch, expands to nothing.
+func (r *Rule) newMatch() *Match {
+	match := newMatch()
+	for _, name := range r.captureNames {
+		match.Vars[name] = ""
+	}
+	return match
+}
+
 // IsEnabled returns whether the rule is enabled (defaults to true).
 func (r *Rule) IsEnabled() bool {
 	if r.Enabled == nil {
@@ -72,6 +184,15 @@ func (r *Rule) IsRecursive() bool {
 	return *r.Recursive
 }
 
+// StabilityWait returns how long files must stay unchanged before the rule
+// acts on them. Rules without wait_until_stable use the daemon's default.
+func (r *Rule) StabilityWait(daemonDefault time.Duration) time.Duration {
+	if r.WaitUntilStable == nil {
+		return daemonDefault
+	}
+	return *r.WaitUntilStable
+}
+
 // GetTraversalMode returns the traversal mode (defaults to depth-first).
 func (r *Rule) GetTraversalMode() TraversalMode {
 	if r.Traversal == "" {
@@ -81,7 +202,9 @@ func (r *Rule) GetTraversalMode() TraversalMode {
 }
 
 // CoversPath returns true if the path is covered by this rule's locations.
-// Locations are pre-expanded during unmarshaling.
+// Locations are pre-expanded during unmarshaling. Paths outside the rule's
+// scope, like excluded ones, are not covered. Ignore files are not read here,
+// since traversal skips the entries they ignore anyway.
 func (r *Rule) CoversPath(path string) bool {
 	for _, loc := range r.Locations {
 		if path == loc {
@@ -89,15 +212,42 @@ func (r *Rule) CoversPath(path string) bool {
 		}
 
 		// For recursive rules, match any descendant
-		if r.IsRecursive() && strings.HasPrefix(path, loc+string(filepath.Separator)) {
+		if r.IsRecursive() && strings.HasPrefix(path, loc+string(filepath.Separator)) && r.Scope(nil, loc).Includes(path, false) {
 			return true
 		}
 
 		// For non-recursive rules, only match direct children
-		if !r.IsRecursive() && filepath.Dir(path) == loc {
+		if !r.IsRecursive() && filepath.Dir(path) == loc && r.Scope(nil, loc).Includes(path, false) {
 			return true
 		}
 	}
 
 	return false
 }
+
+// locationFor returns the most specific of the rule's locations that covers
+// path, ignoring the rule's scope, and false if none does.
+func (r *Rule) locationFor(path string) (string, bool) {
+	var best string
+	for _, loc := range r.Locations {
+		covers := path == loc ||
+			(r.IsRecursive() && strings.HasPrefix(path, loc+string(filepath.Separator))) ||
+			(!r.IsRecursive() && filepath.Dir(path) == loc)
+		if covers && len(loc) > len(best) {
+			best = loc
+		}
+	}
+	return best, best != ""
+}
+
+// DescendsInto returns true if the rule's traversal reads the children of the
+// directory at path, which lies below one of its locations. Directories that
+// are skipped or at max_depth are not read, and so are not watched either.
+func (r *Rule) DescendsInto(filesystem afero.Fs, path string) bool {
+	for _, loc := range r.Locations {
+		if strings.HasPrefix(path, loc+string(filepath.Separator)) && r.Scope(filesystem, loc).Descends(path) {
+			return true
+		}
+	}
+	return false
+}
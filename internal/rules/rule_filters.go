@@ -17,6 +17,12 @@ type FilterGroups struct {
 // Empty filters (no expressions) match all paths.
 // Short-circuits only when using NullReporter (no reporting needed).
 func (fg *FilterGroups) Evaluate(path string, r report.Reporter) (bool, error) {
+	return fg.EvaluateMatch(path, r, nil)
+}
+
+// EvaluateMatch is like Evaluate, and also records what the filters captured
+// about a passing path in match. A nil match discards captures.
+func (fg *FilterGroups) EvaluateMatch(path string, r report.Reporter, match *Match) (bool, error) {
 	// Empty filters match everything
 	if len(fg.Exprs) == 0 {
 		return true, nil
@@ -26,10 +32,11 @@ func (fg *FilterGroups) Evaluate(path string, r report.Reporter) (bool, error) {
 	_, canShortCircuit := r.(report.NullReporter)
 
 	allPassed := true
+	captured := newMatch()
 
 	// All expressions must match (AND)
 	for _, expr := range fg.Exprs {
-		matched, err := expr.Evaluate(path, r)
+		matched, err := expr.evaluate(path, r, captured)
 		if err != nil {
 			return false, err
 		}
@@ -41,9 +48,19 @@ func (fg *FilterGroups) Evaluate(path string, r report.Reporter) (bool, error) {
 		}
 	}
 
+	if allPassed {
+		match.merge(captured)
+	}
 	return allPassed, nil
 }
 
+// EachFilter calls fn for every filter, including those nested in any: and not:.
+func (fg *FilterGroups) EachFilter(fn func(f *Filter)) {
+	for _, expr := range fg.Exprs {
+		expr.eachFilter(fn)
+	}
+}
+
 // UnmarshalYAML implements custom YAML unmarshaling for FilterGroups.
 // It expects a sequence of filter expressions.
 func (fg *FilterGroups) UnmarshalYAML(node *yaml.Node) error {
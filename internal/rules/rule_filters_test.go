@@ -1,6 +1,7 @@
 package rules
 
 import (
+	"io"
 	"testing"
 
 	"github.com/prettymuchbryce/autotidy/internal/report"
@@ -287,3 +288,110 @@ func TestFilterExpr_Nested(t *testing.T) {
 		})
 	}
 }
+
+// capturingEvaluable is a filter that captures a fixed set of variables.
+type capturingEvaluable struct {
+	mockEvaluable
+	captures map[string]string
+}
+
+func (c *capturingEvaluable) Captures(path string) map[string]string {
+	return c.captures
+}
+
+func (c *capturingEvaluable) CaptureNames() []string {
+	names := make([]string, 0, len(c.captures))
+	for name := range c.captures {
+		names = append(names, name)
+	}
+	return names
+}
+
+// newCapturingFilterExpr creates a FilterExpr with a single capturing filter.
+func newCapturingFilterExpr(result bool, captures map[string]string) *FilterExpr {
+	return &FilterExpr{
+		Filters: []Filter{{Name: "capture", Inner: &capturingEvaluable{
+			mockEvaluable: mockEvaluable{result: result},
+			captures:      captures,
+		}}},
+	}
+}
+
+func TestFilterGroups_EvaluateMatch_Captures(t *testing.T) {
+	tests := []struct {
+		name     string
+		exprs    []*FilterExpr
+		passed   bool
+		expected map[string]string
+	}{
+		{
+			name: "captures from matching filters",
+			exprs: []*FilterExpr{
+				newCapturingFilterExpr(true, map[string]string{"client": "ACME"}),
+				newCapturingFilterExpr(true, map[string]string{"year": "2024"}),
+			},
+			passed:   true,
+			expected: map[string]string{"client": "ACME", "year": "2024"},
+		},
+		{
+			name: "no captures when filters fail",
+			exprs: []*FilterExpr{
+				newCapturingFilterExpr(true, map[string]string{"client": "ACME"}),
+				newMockFilterExpr(false),
+			},
+			passed:   false,
+			expected: map[string]string{},
+		},
+		{
+			name: "first matching any branch",
+			exprs: []*FilterExpr{{
+				Any: []*FilterExpr{
+					newCapturingFilterExpr(false, map[string]string{"client": "NOPE"}),
+					newCapturingFilterExpr(true, map[string]string{"client": "ACME"}),
+					newCapturingFilterExpr(true, map[string]string{"client": "LATER"}),
+				},
+			}},
+			passed:   true,
+			expected: map[string]string{"client": "ACME"},
+		},
+		{
+			name: "nothing captured from not",
+			exprs: []*FilterExpr{{
+				Not: []*FilterExpr{newCapturingFilterExpr(false, map[string]string{"client": "ACME"})},
+			}},
+			passed:   true,
+			expected: map[string]string{},
+		},
+	}
+
+	// Captures must not depend on whether results are reported
+	reporters := map[string]report.Reporter{
+		"null":       report.NullReporter{},
+		"structured": report.NewStructuredWithWriter(io.Discard, true),
+	}
+
+	for _, tt := range tests {
+		for reporterName, r := range reporters {
+			t.Run(tt.name+"/"+reporterName, func(t *testing.T) {
+				fg := &FilterGroups{Exprs: tt.exprs}
+				match := newMatch()
+
+				passed, err := fg.EvaluateMatch(testutil.Path("/", "test", "path"), r, match)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if passed != tt.passed {
+					t.Errorf("passed = %v, want %v", passed, tt.passed)
+				}
+				if len(match.Vars) != len(tt.expected) {
+					t.Fatalf("Vars = %v, want %v", match.Vars, tt.expected)
+				}
+				for k, v := range tt.expected {
+					if match.Vars[k] != v {
+						t.Errorf("Vars[%q] = %q, want %q", k, match.Vars[k], v)
+					}
+				}
+			})
+		}
+	}
+}
@@ -5,9 +5,14 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/journal"
+	"github.com/prettymuchbryce/autotidy/internal/pathutil"
 	"github.com/prettymuchbryce/autotidy/internal/report"
 )
 
@@ -39,7 +44,16 @@ type RuleRunner struct {
 	rule              *Rule
 	fs                fs.FileSystem
 	reporter          report.Reporter
+	journal           *journal.Journal
+	runID             string
 	lastCompletedTime time.Time
+
+	// acted records whether the current execution has executed any action.
+	acted bool
+
+	// hold decides whether a matching item is held back from this run, e.g.
+	// because it is still being written. Nil holds nothing.
+	hold func(path string) bool
 }
 
 // NewRuleRunner creates a RuleRunner with the given dependencies.
@@ -55,12 +69,27 @@ func NewRuleRunner(rule *Rule, fs fs.FileSystem, reporter report.Reporter) *Rule
 	}
 }
 
+// SetJournal sets the journal that records every action that modifies a file.
+// A nil journal (the default) disables recording.
+func (rr *RuleRunner) SetJournal(j *journal.Journal) {
+	rr.journal = j
+}
+
+// SetHold sets the function deciding whether an item that passed the filters
+// is held back instead of having actions executed on it. Held items are left
+// untouched, to be picked up by a later run.
+func (rr *RuleRunner) SetHold(hold func(path string) bool) {
+	rr.hold = hold
+}
+
 // Rule returns the underlying rule configuration.
 func (rr *RuleRunner) Rule() *Rule {
 	return rr.rule
 }
 
-// LastCompletedTime returns the time when the rule last completed execution.
+// LastCompletedTime returns the time when the rule last completed an execution
+// that acted on files. Executions that matched nothing don't count, since they
+// made no changes whose events would need to be ignored.
 func (rr *RuleRunner) LastCompletedTime() time.Time {
 	return rr.lastCompletedTime
 }
@@ -68,6 +97,30 @@ func (rr *RuleRunner) LastCompletedTime() time.Time {
 // Execute runs the rule against all files in its locations.
 // Returns execution statistics.
 func (rr *RuleRunner) Execute() (*ExecutionStats, error) {
+	return rr.execute(rr.locationSnapshots)
+}
+
+// ExecutePaths runs the rule against only the given paths, and everything
+// below the directories among them, instead of its whole locations. Used to
+// process just the paths that changed. Paths the rule doesn't cover and paths
+// that no longer exist are skipped. A path that is one of the rule's
+// locations runs the rule against the whole location.
+func (rr *RuleRunner) ExecutePaths(paths []string) (*ExecutionStats, error) {
+	return rr.execute(func() []snapshot {
+		return rr.pathSnapshots(paths)
+	})
+}
+
+// snapshot is a tree to traverse in a run. Snapshots are built before any
+// action executes, so actions don't affect what the run visits.
+type snapshot struct {
+	path      string // Path of the tree's root node
+	tree      *Node
+	visitRoot bool // Whether the root node is evaluated too, not only its descendants
+}
+
+// execute runs the rule against the snapshots built by buildSnapshots.
+func (rr *RuleRunner) execute(buildSnapshots func() []snapshot) (*ExecutionStats, error) {
 	rule := rr.rule
 	stats := &ExecutionStats{
 		StartTime: time.Now(),
@@ -81,36 +134,29 @@ func (rr *RuleRunner) Execute() (*ExecutionStats, error) {
 
 	slog.Info("Executing rule", "rule", rule.Name)
 
-	// Start reporting for this rule
-	rr.reporter.StartRule(rule.Name)
+	// Group journal entries from this execution into a single run
+	rr.runID = journal.NewRunID(stats.StartTime)
+	rr.acted = false
 
-	// Build snapshots for all locations first
-	type locationSnapshot struct {
-		loc  string
-		tree *Node
+	// Reset per-run state, like the ${counter} sequence
+	if rule.Filters != nil {
+		rule.Filters.EachFilter(func(f *Filter) {
+			if s, ok := f.Inner.(RunScoped); ok {
+				s.StartRun()
+			}
+		})
 	}
-	var snapshots []locationSnapshot
-
-	for _, loc := range rule.Locations {
-		info, err := rr.fs.Stat(loc)
-		if err != nil {
-			slog.Warn("location does not exist", "rule", rule.Name, "location", loc, "error", err)
-			continue
-		}
-
-		if !info.IsDir() {
-			slog.Error("location is not a directory", "rule", rule.Name, "location", loc)
-			continue
+	for _, action := range rule.Actions {
+		if s, ok := action.Inner.(RunScoped); ok {
+			s.StartRun()
 		}
+	}
 
-		// Build tree from directory
-		tree := BuildSnapshot(rr.fs, loc, rule.IsRecursive())
-		if tree == nil {
-			continue
-		}
+	// Start reporting for this rule
+	rr.reporter.StartRule(rule.Name)
 
-		snapshots = append(snapshots, locationSnapshot{loc: loc, tree: tree})
-	}
+	// Build all snapshots first
+	snapshots := buildSnapshots()
 
 	// Traverse and execute actions
 	// Create a visitor that wraps executeOnItem and tracks stats
@@ -130,21 +176,26 @@ func (rr *RuleRunner) Execute() (*ExecutionStats, error) {
 		stats.FilesProcessed++
 
 		ctrl := TraverseControl{NewPath: result.NewPath}
-		if result.Deleted {
+		if result.Deleted || result.Pending {
 			ctrl.Instruction = SkipChildren
 		}
 		return ctrl, struct{}{}, nil
 	}
 
 	for _, snap := range snapshots {
-		// Traverse tree (skipping root directory)
+		// Traverse tree, skipping the root directory of locations
 		mode := rule.GetTraversalMode()
+		parent := filepath.Dir(snap.path)
 		var err error
-		switch mode {
-		case TraversalDepthFirst:
-			_, err = TraverseChildrenDFS(snap.tree, filepath.Dir(snap.loc), visitor)
-		case TraversalBreadthFirst:
-			_, err = TraverseChildrenBFS(snap.tree, filepath.Dir(snap.loc), visitor)
+		switch {
+		case mode == TraversalDepthFirst && snap.visitRoot:
+			_, err = traverseDFS(snap.tree, parent, visitor)
+		case mode == TraversalDepthFirst:
+			_, err = TraverseChildrenDFS(snap.tree, parent, visitor)
+		case mode == TraversalBreadthFirst && snap.visitRoot:
+			_, err = traverseBFS(snap.tree, parent, visitor)
+		case mode == TraversalBreadthFirst:
+			_, err = TraverseChildrenBFS(snap.tree, parent, visitor)
 		default:
 			slog.Error("unknown traversal mode", "rule", rule.Name, "mode", mode)
 			continue
@@ -155,16 +206,113 @@ func (rr *RuleRunner) Execute() (*ExecutionStats, error) {
 		}
 	}
 
+	// Complete the work actions deferred to the end of the run
+	for _, action := range rule.Actions {
+		if f, ok := action.Inner.(RunFinisher); ok {
+			for _, item := range f.FinishRun() {
+				rr.finishItem(action.Name, item)
+				if item.Err != nil {
+					stats.ErrorCount++
+				}
+			}
+		}
+	}
+
 	// End reporting for this rule
 	rr.reporter.EndRule()
 
 	// Record completion time and duration
-	rr.lastCompletedTime = time.Now()
+	if rr.acted {
+		rr.lastCompletedTime = time.Now()
+	}
 	stats.Duration = time.Since(stats.StartTime)
 
 	return stats, nil
 }
 
+// locationSnapshots builds a snapshot of each of the rule's locations.
+func (rr *RuleRunner) locationSnapshots() []snapshot {
+	var snapshots []snapshot
+	for _, loc := range rr.rule.Locations {
+		if snap, ok := rr.locationSnapshot(loc); ok {
+			snapshots = append(snapshots, snap)
+		}
+	}
+	return snapshots
+}
+
+// locationSnapshot builds a snapshot of one of the rule's locations.
+func (rr *RuleRunner) locationSnapshot(loc string) (snapshot, bool) {
+	rule := rr.rule
+	info, err := rr.fs.Stat(loc)
+	if err != nil {
+		slog.Warn("location does not exist", "rule", rule.Name, "location", loc, "error", err)
+		return snapshot{}, false
+	}
+
+	if !info.IsDir() {
+		slog.Error("location is not a directory", "rule", rule.Name, "location", loc)
+		return snapshot{}, false
+	}
+
+	// Build tree from directory
+	tree := BuildScopedSnapshot(rr.fs, loc, rule.Scope(rr.fs, loc))
+	if tree == nil {
+		return snapshot{}, false
+	}
+	return snapshot{path: loc, tree: tree}, true
+}
+
+// pathSnapshots builds a snapshot of each of the given paths that the rule
+// covers. Directories include everything below them within the rule's scope,
+// so paths inside a directory that is also given are only visited once.
+func (rr *RuleRunner) pathSnapshots(paths []string) []snapshot {
+	rule := rr.rule
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	var snapshots []snapshot
+	var dirs []string // Roots of directory snapshots
+	for _, path := range sorted {
+		if slices.ContainsFunc(dirs, func(dir string) bool { return path == dir || pathutil.IsBelow(dir, path) }) {
+			continue
+		}
+
+		loc, ok := rule.locationFor(path)
+		if !ok {
+			continue
+		}
+		if path == loc {
+			if snap, ok := rr.locationSnapshot(loc); ok {
+				snapshots = append(snapshots, snap)
+				dirs = append(dirs, loc)
+			}
+			continue
+		}
+
+		info, err := rr.fs.Stat(path)
+		if err != nil {
+			// Removed or renamed since the event
+			continue
+		}
+		scope := rule.Scope(rr.fs, loc)
+		if !scope.Includes(path, info.IsDir()) {
+			continue
+		}
+
+		tree := &Node{Name: filepath.Base(path), IsDir: info.IsDir()}
+		if info.IsDir() && scope.Descends(path) {
+			tree = BuildScopedSnapshot(rr.fs, path, scope)
+			if tree == nil {
+				continue
+			}
+			dirs = append(dirs, path)
+		}
+		snapshots = append(snapshots, snapshot{path: path, tree: tree, visitRoot: true})
+	}
+	return snapshots
+}
+
 // executeOnItem evaluates filters and executes actions on a single item.
 // Returns (result, hadError, err) where:
 //   - result is nil if filters didn't match or no actions modified the file
@@ -173,17 +321,18 @@ func (rr *RuleRunner) Execute() (*ExecutionStats, error) {
 func (rr *RuleRunner) executeOnItem(path string) (*ExecutionResult, bool, error) {
 	rule := rr.rule
 	currentPath := path
-	var deleted bool
+	var deleted, pending bool
+	match := rule.newMatch()
 
 	// Start reporting for this file
 	rr.reporter.StartFile(path)
 
 	// Evaluate filters if present
 	if rule.Filters != nil {
-		passed, err := rule.Filters.Evaluate(currentPath, rr.reporter)
+		passed, err := rule.Filters.EvaluateMatch(currentPath, rr.reporter, match)
 		if err != nil {
-			if isFilesystemError(err) {
-				slog.Warn("filesystem error during filter evaluation, skipping item", "rule", rule.Name, "path", currentPath, "error", err)
+			if isFilesystemError(err) || isItemError(err) {
+				slog.Warn("filter evaluation failed, skipping item", "rule", rule.Name, "path", currentPath, "error", err)
 				rr.reporter.EndFile()
 				return nil, true, nil
 			}
@@ -199,12 +348,19 @@ func (rr *RuleRunner) executeOnItem(path string) (*ExecutionResult, bool, error)
 		rr.reporter.MarkFiltersPassed()
 	}
 
+	if rr.hold != nil && rr.hold(currentPath) {
+		slog.Debug("holding item until a later run", "rule", rule.Name, "path", currentPath)
+		rr.reporter.EndFile()
+		return nil, false, nil
+	}
+
 	// Execute all actions, tracking path changes
+	rr.acted = true
 	for _, action := range rule.Actions {
-		result, err := action.Execute(currentPath, rr.fs)
+		result, err := action.ExecuteMatch(currentPath, rr.fs, match)
 		if err != nil {
-			if isFilesystemError(err) {
-				slog.Warn("filesystem error during action, skipping item", "rule", rule.Name, "action", action.Name, "path", currentPath, "error", err)
+			if isFilesystemError(err) || isItemError(err) {
+				slog.Warn("action failed, skipping item", "rule", rule.Name, "action", action.Name, "path", currentPath, "error", err)
 				rr.reporter.ReportAction(action.Name, report.ActionResult{
 					Outcome: report.OutcomeFailed,
 					Error:   err.Error(),
@@ -215,19 +371,9 @@ func (rr *RuleRunner) executeOnItem(path string) (*ExecutionResult, bool, error)
 			return nil, false, err
 		}
 
-		// Report action result
-		if result == nil {
-			// Action completed but no changes
-			rr.reporter.ReportAction(action.Name, report.ActionResult{Outcome: report.OutcomeSuccess})
-		} else if result.ConflictAlreadyExists {
-			rr.reporter.ReportAction(action.Name, report.ActionResult{Outcome: report.OutcomeSkipped})
-		} else if result.Deleted {
-			rr.reporter.ReportAction(action.Name, report.ActionResult{Outcome: report.OutcomeDeleted})
-		} else if result.NewPath != "" {
-			rr.reporter.ReportAction(action.Name, report.ActionResult{
-				Outcome: report.OutcomeMoved,
-				NewPath: result.NewPath,
-			})
+		rr.reportResult(action.Name, result)
+		if result != nil && !result.ConflictAlreadyExists && !result.Pending {
+			rr.recordAction(action.Name, currentPath, result)
 		}
 
 		if result != nil {
@@ -235,6 +381,10 @@ func (rr *RuleRunner) executeOnItem(path string) (*ExecutionResult, bool, error)
 				deleted = true
 				break // Stop processing actions on deleted file
 			}
+			if result.Pending {
+				pending = true
+				break // The file may be gone once the run finishes
+			}
 			if result.ConflictAlreadyExists {
 				break // Stop processing actions when destination exists
 			}
@@ -248,16 +398,129 @@ func (rr *RuleRunner) executeOnItem(path string) (*ExecutionResult, bool, error)
 	rr.reporter.EndFile()
 
 	// Return nil if nothing changed
-	if currentPath == path && !deleted {
+	if currentPath == path && !deleted && !pending {
 		return nil, false, nil
 	}
 
 	return &ExecutionResult{
 		NewPath: currentPath,
 		Deleted: deleted,
+		Pending: pending,
 	}, false, nil
 }
 
+// reportResult reports the result of an action on the current file.
+func (rr *RuleRunner) reportResult(actionName string, result *ExecutionResult) {
+	switch {
+	case result == nil:
+		// Action completed but no changes
+		rr.reporter.ReportAction(actionName, report.ActionResult{Outcome: report.OutcomeSuccess})
+	case result.ConflictAlreadyExists:
+		rr.reporter.ReportAction(actionName, report.ActionResult{
+			Outcome: report.OutcomeSkipped,
+			Detail:  result.Detail,
+		})
+	case result.Deleted:
+		rr.reporter.ReportAction(actionName, report.ActionResult{
+			Outcome: report.OutcomeDeleted,
+			Detail:  result.Detail,
+			Files:   result.Created,
+		})
+	case result.NewPath != "":
+		detail := result.Detail
+		if len(result.Trashed) > 0 {
+			detail = "trashed existing " + strings.Join(result.Trashed, ", ")
+		}
+		rr.reporter.ReportAction(actionName, report.ActionResult{
+			Outcome: report.OutcomeMoved,
+			NewPath: result.NewPath,
+			Detail:  detail,
+			Files:   result.Created,
+		})
+	default:
+		// Action completed without moving the file, e.g. added it to an archive
+		rr.reporter.ReportAction(actionName, report.ActionResult{
+			Outcome: report.OutcomeSuccess,
+			Detail:  result.Detail,
+			Files:   result.Created,
+		})
+	}
+}
+
+// finishItem reports and journals the outcome of work an action deferred to
+// the end of the run, in a report entry of its own for the file.
+func (rr *RuleRunner) finishItem(actionName string, item FinishedItem) {
+	rr.reporter.StartFile(item.Path)
+	if item.Err != nil {
+		slog.Error("error finishing action", "rule", rr.rule.Name, "action", actionName, "path", item.Path, "error", item.Err)
+		rr.reporter.ReportAction(actionName, report.ActionResult{
+			Outcome: report.OutcomeFailed,
+			Error:   item.Err.Error(),
+		})
+	} else {
+		rr.reportResult(actionName, item.Result)
+		if item.Result != nil {
+			rr.recordAction(actionName, item.Path, item.Result)
+		}
+	}
+	rr.reporter.EndFile()
+}
+
+// recordAction appends an executed action to the journal, if one is set and
+// undo supports the action. Files trashed to resolve a conflict are recorded
+// first, so undoing in reverse order moves the file back before restoring them.
+func (rr *RuleRunner) recordAction(actionName, path string, result *ExecutionResult) {
+	for _, trashed := range result.Trashed {
+		rr.recordEntry(journal.Entry{
+			Run:    rr.runID,
+			Rule:   rr.rule.Name,
+			Action: "trash",
+			Src:    trashed,
+		})
+	}
+
+	// Actions undo can't revert are left out, so they don't make undo fail
+	if !journal.Supports(actionName) {
+		return
+	}
+
+	// Actions that create several files, like extract, record one entry per file
+	if len(result.Created) > 0 {
+		for _, created := range result.Created {
+			rr.recordEntry(journal.Entry{
+				Run:    rr.runID,
+				Rule:   rr.rule.Name,
+				Action: actionName,
+				Src:    path,
+				Dest:   created,
+			})
+		}
+		return
+	}
+
+	rr.recordEntry(journal.Entry{
+		Run:    rr.runID,
+		Rule:   rr.rule.Name,
+		Action: actionName,
+		Src:    path,
+		Dest:   result.NewPath,
+	})
+}
+
+// recordEntry appends a single entry to the journal, logging any failure.
+func (rr *RuleRunner) recordEntry(e journal.Entry) {
+	err := rr.journal.Record(e)
+	if err != nil {
+		slog.Warn("failed to record action in journal", "rule", e.Rule, "action", e.Action, "path", e.Src, "error", err)
+	}
+}
+
+// isItemError returns true if the error only affects the current item.
+func isItemError(err error) bool {
+	var itemErr *ItemError
+	return errors.As(err, &itemErr)
+}
+
 // isFilesystemError returns true if the error is a filesystem-related error.
 // These errors should be logged as warnings rather than stopping execution.
 func isFilesystemError(err error) bool {
@@ -1,10 +1,14 @@
 package rules
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/journal"
 	"github.com/prettymuchbryce/autotidy/internal/testutil"
 
 	"github.com/spf13/afero"
@@ -71,6 +75,279 @@ func TestRuleRunner_Execute_Directory(t *testing.T) {
 	}
 }
 
+func TestRuleRunner_LastCompletedTime(t *testing.T) {
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll("/root", 0755)
+
+	r := &Rule{
+		Name:      "test-rule",
+		Locations: StringList{"/root"},
+		Actions:   []Action{{Name: "mock", Inner: &testExecutable{result: &ExecutionResult{}}}},
+	}
+	runner := NewRuleRunner(r, filesystem, nil)
+
+	// A run that acts on nothing made no changes
+	if _, err := runner.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !runner.LastCompletedTime().IsZero() {
+		t.Errorf("expected no completion time after a run without matches, got %s", runner.LastCompletedTime())
+	}
+
+	afero.WriteFile(filesystem, "/root/a.txt", []byte("a"), 0644)
+	if _, err := runner.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runner.LastCompletedTime().IsZero() {
+		t.Error("expected completion time after a run that acted on a file")
+	}
+}
+
+func TestRuleRunner_ExecutePaths(t *testing.T) {
+	root := testutil.Path("/", "root")
+	other := testutil.Path("/", "other")
+
+	tests := []struct {
+		name      string
+		recursive bool
+		paths     []string
+		expected  []string
+	}{
+		{
+			name:      "only the given files",
+			recursive: true,
+			paths:     []string{testutil.Path(root, "b.txt")},
+			expected:  []string{testutil.Path(root, "b.txt")},
+		},
+		{
+			name:      "directory includes its contents",
+			recursive: true,
+			paths:     []string{testutil.Path(root, "sub")},
+			expected:  []string{testutil.Path(root, "sub", "c.txt"), testutil.Path(root, "sub")},
+		},
+		{
+			name:      "paths inside a given directory are visited once",
+			recursive: true,
+			paths:     []string{testutil.Path(root, "sub", "c.txt"), testutil.Path(root, "sub"), testutil.Path(root, "sub")},
+			expected:  []string{testutil.Path(root, "sub", "c.txt"), testutil.Path(root, "sub")},
+		},
+		{
+			name:      "location runs the whole location",
+			recursive: true,
+			paths:     []string{root, testutil.Path(root, "a.txt")},
+			expected:  []string{testutil.Path(root, "a.txt"), testutil.Path(root, "b.txt"), testutil.Path(root, "sub", "c.txt"), testutil.Path(root, "sub")},
+		},
+		{
+			name:      "removed and uncovered paths are skipped",
+			recursive: true,
+			paths:     []string{testutil.Path(root, "gone.txt"), testutil.Path(other, "x.txt")},
+			expected:  nil,
+		},
+		{
+			name:      "non-recursive rule skips nested paths",
+			recursive: false,
+			paths:     []string{testutil.Path(root, "sub"), testutil.Path(root, "sub", "c.txt")},
+			expected:  []string{testutil.Path(root, "sub")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filesystem := fs.NewMem()
+			afero.WriteFile(filesystem, testutil.Path(root, "a.txt"), []byte("a"), 0644)
+			afero.WriteFile(filesystem, testutil.Path(root, "b.txt"), []byte("b"), 0644)
+			afero.WriteFile(filesystem, testutil.Path(root, "sub", "c.txt"), []byte("c"), 0644)
+			afero.WriteFile(filesystem, testutil.Path(other, "x.txt"), []byte("x"), 0644)
+
+			var processed []string
+			r := &Rule{
+				Name:      "test-rule",
+				Locations: StringList{root},
+				Recursive: boolPtr(tt.recursive),
+				Actions: []Action{{Name: "mock", Inner: &testExecutable{
+					onExecute: func(path string) { processed = append(processed, path) },
+				}}},
+			}
+			runner := NewRuleRunner(r, filesystem, nil)
+
+			if _, err := runner.ExecutePaths(tt.paths); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(processed, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("processed = %v, want %v", processed, tt.expected)
+			}
+		})
+	}
+}
+
+// runScopedExecutable counts how often a run was started.
+type runScopedExecutable struct {
+	testExecutable
+	runs int
+}
+
+func (e *runScopedExecutable) StartRun() {
+	e.runs++
+}
+
+// runScopedEvaluable counts how often a run was started.
+type runScopedEvaluable struct {
+	mockEvaluable
+	runs int
+}
+
+func (e *runScopedEvaluable) StartRun() {
+	e.runs++
+}
+
+func TestRuleRunner_Execute_StartsRun(t *testing.T) {
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll("/root", 0755)
+
+	scoped := &runScopedExecutable{}
+	scopedFilter := &runScopedEvaluable{}
+	r := &Rule{
+		Name:      "test-rule",
+		Locations: StringList{"/root"},
+		Filters: &FilterGroups{Exprs: []*FilterExpr{{
+			Not: []*FilterExpr{{Filters: []Filter{{Name: "scoped", Inner: scopedFilter}}}},
+		}}},
+		Actions: []Action{{Name: "scoped", Inner: scoped}},
+	}
+	runner := NewRuleRunner(r, filesystem, nil)
+
+	for i := 0; i < 2; i++ {
+		if _, err := runner.Execute(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if scoped.runs != 2 {
+		t.Errorf("StartRun called %d times, want 2", scoped.runs)
+	}
+	if scopedFilter.runs != 2 {
+		t.Errorf("StartRun called %d times on the nested filter, want 2", scopedFilter.runs)
+	}
+}
+
+// finishingExecutable defers its work on every file to the end of the run,
+// and records the order of its executions and finished runs.
+type finishingExecutable struct {
+	calls    []string
+	finished []FinishedItem
+}
+
+func (e *finishingExecutable) Execute(path string, filesystem fs.FileSystem) (*ExecutionResult, error) {
+	e.calls = append(e.calls, filepath.Base(path))
+	return &ExecutionResult{Pending: true}, nil
+}
+
+func (e *finishingExecutable) FinishRun() []FinishedItem {
+	e.calls = append(e.calls, "finish")
+	return e.finished
+}
+
+func TestRuleRunner_Execute_FinishesRun(t *testing.T) {
+	filesystem := fs.NewMem()
+	filesystem.MkdirAll("/root", 0755)
+	afero.WriteFile(filesystem, "/root/a.txt", []byte("a"), 0644)
+	afero.WriteFile(filesystem, "/root/b.txt", []byte("b"), 0644)
+
+	finishing := &finishingExecutable{finished: []FinishedItem{
+		{Path: "/root/a.txt", Result: &ExecutionResult{Deleted: true}},
+		{Path: "/root/b.txt", Err: errors.New("write failed")},
+	}}
+	var laterCalls int
+	later := &testExecutable{onExecute: func(path string) { laterCalls++ }}
+	r := &Rule{
+		Name:      "test-rule",
+		Locations: StringList{"/root"},
+		Actions:   []Action{{Name: "finishing", Inner: finishing}, {Name: "later", Inner: later}},
+	}
+	runner := NewRuleRunner(r, filesystem, nil)
+
+	stats, err := runner.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(finishing.calls, ","); got != "a.txt,b.txt,finish" {
+		t.Errorf("calls = %s, want a.txt,b.txt,finish", got)
+	}
+	if laterCalls != 0 {
+		t.Errorf("expected no actions after a pending result, got %d calls", laterCalls)
+	}
+	if stats.ErrorCount != 1 {
+		t.Errorf("ErrorCount = %d, want 1 for the failed item", stats.ErrorCount)
+	}
+}
+
+// matchExecutable records the match context it was executed with.
+type matchExecutable struct {
+	testExecutable
+	vars map[string]string
+}
+
+func (e *matchExecutable) ExecuteMatch(path string, filesystem fs.FileSystem, match *Match) (*ExecutionResult, error) {
+	e.vars = match.Vars
+	return e.Execute(path, filesystem)
+}
+
+func TestRuleRunner_ExecuteOnItem_PassesCapturesToActions(t *testing.T) {
+	action := &matchExecutable{}
+	r := &Rule{
+		Name: "test-rule",
+		Filters: &FilterGroups{Exprs: []*FilterExpr{
+			newCapturingFilterExpr(true, map[string]string{"client": "ACME"}),
+		}},
+		Actions:      []Action{{Name: "match", Inner: action}},
+		captureNames: []string{"client", "year"},
+	}
+	runner := NewRuleRunner(r, fs.NewNoop(), nil)
+
+	if _, _, err := runner.executeOnItem(testutil.Path("/", "ACME_invoice.pdf")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Variables that weren't captured are empty rather than missing
+	if action.vars["client"] != "ACME" {
+		t.Errorf("client = %q, want %q", action.vars["client"], "ACME")
+	}
+	if year, ok := action.vars["year"]; !ok || year != "" {
+		t.Errorf("year = %q (set: %v), want empty", year, ok)
+	}
+}
+
+func TestRuleRunner_ExecuteOnItem_HeldItemsAreSkipped(t *testing.T) {
+	var executed []string
+	r := &Rule{
+		Name: "test-rule",
+		Actions: []Action{{Name: "record", Inner: &testExecutable{
+			onExecute: func(path string) { executed = append(executed, path) },
+		}}},
+	}
+	runner := NewRuleRunner(r, fs.NewNoop(), nil)
+
+	downloading := testutil.Path("/", "video.mp4")
+	done := testutil.Path("/", "report.pdf")
+	runner.SetHold(func(path string) bool {
+		return path == downloading
+	})
+
+	for _, path := range []string{downloading, done} {
+		result, _, err := runner.executeOnItem(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if path == downloading && result != nil {
+			t.Errorf("expected no result for held item, got %+v", result)
+		}
+	}
+
+	if len(executed) != 1 || executed[0] != done {
+		t.Errorf("executed = %v, want [%s]", executed, done)
+	}
+}
+
 func TestRuleRunner_ExecuteOnItem_ActionChaining(t *testing.T) {
 	// When an action returns a new path, subsequent actions should use that path
 	originalPath := testutil.Path("/", "original", "file.txt")
@@ -267,6 +544,32 @@ func TestRuleRunner_ExecuteOnItem_NonFilesystemErrorStops(t *testing.T) {
 	}
 }
 
+func TestRuleRunner_ExecuteOnItem_ItemErrorSkipsItem(t *testing.T) {
+	// Failures that only affect one item, like a command exiting non-zero,
+	// skip the item without stopping the rule
+	action := Action{
+		Name:  "exec",
+		Inner: &testExecutable{err: &ItemError{Err: errors.New("ocrmypdf exited with status 2")}},
+	}
+
+	r := &Rule{
+		Name:    "test-rule",
+		Actions: []Action{action},
+	}
+	runner := NewRuleRunner(r, fs.NewNoop(), nil)
+
+	result, hadError, err := runner.executeOnItem("/original/file.txt")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !hadError {
+		t.Error("expected hadError to be true")
+	}
+	if result != nil {
+		t.Errorf("expected nil result for skipped item, got %v", result)
+	}
+}
+
 // fsErrorEvaluable returns a filesystem error for filter testing
 type fsErrorEvaluable struct{}
 
@@ -368,3 +671,48 @@ func TestRuleRunner_ExecuteOnItem_ConflictAlreadyExistsStopsSubsequentActions(t
 		t.Errorf("expected nil result (no changes), got %+v", result)
 	}
 }
+
+func TestRuleRunner_ExecuteOnItem_RecordsJournal(t *testing.T) {
+	originalPath := testutil.Path("/", "original", "file.txt")
+	movedPath := testutil.Path("/", "moved", "file.txt")
+
+	moveAction := &Action{
+		Name:  "move",
+		Inner: &testExecutable{result: &ExecutionResult{NewPath: movedPath}},
+	}
+	execAction := &Action{
+		Name:  "exec",
+		Inner: &testExecutable{result: &ExecutionResult{}},
+	}
+	skipAction := &Action{
+		Name:  "copy",
+		Inner: &testExecutable{result: &ExecutionResult{ConflictAlreadyExists: true}},
+	}
+
+	r := &Rule{
+		Name:    "test-rule",
+		Actions: []Action{*moveAction, *execAction, *skipAction},
+	}
+	jrnl := journal.OpenAt(filepath.Join(t.TempDir(), "journal.jsonl"))
+	runner := NewRuleRunner(r, fs.NewNoop(), nil)
+	runner.SetJournal(jrnl)
+
+	if _, _, err := runner.executeOnItem(originalPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := jrnl.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Skipped actions changed nothing, and undo can't revert exec, so neither is recorded
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 journal entry, got %d", len(entries))
+	}
+
+	e := entries[0]
+	if e.Rule != "test-rule" || e.Action != "move" || e.Src != originalPath || e.Dest != movedPath {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
@@ -0,0 +1,80 @@
+//go:build !windows
+
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+)
+
+// crossDeviceFs fails renames like a move between two filesystems would,
+// except for the final rename of a partial copy into place.
+type crossDeviceFs struct {
+	*afero.OsFs
+}
+
+func (f crossDeviceFs) Rename(oldname, newname string) error {
+	if strings.HasSuffix(oldname, ".autotidy-partial") {
+		return f.OsFs.Rename(oldname, newname)
+	}
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: syscall.EXDEV}
+}
+
+// movingExecutable renames files into dir.
+type movingExecutable struct {
+	dir string
+}
+
+func (e *movingExecutable) Execute(path string, filesystem fs.FileSystem) (*ExecutionResult, error) {
+	dest := filepath.Join(e.dir, filepath.Base(path))
+	if err := filesystem.Rename(path, dest); err != nil {
+		return nil, err
+	}
+	return &ExecutionResult{NewPath: dest}, nil
+}
+
+func TestRuleRunner_Execute_CrossDeviceMoveFailureSkipsItem(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dest := filepath.Join(dir, "dest")
+	os.MkdirAll(src, 0755)
+	os.MkdirAll(dest, 0755)
+	os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(src, "c.txt"), []byte("c"), 0644)
+	if err := syscall.Mkfifo(filepath.Join(src, "b.pipe"), 0644); err != nil {
+		t.Skipf("cannot create FIFO: %v", err)
+	}
+
+	r := &Rule{
+		Name:      "test-rule",
+		Locations: StringList{src},
+		Actions:   []Action{{Name: "move", Inner: &movingExecutable{dir: dest}}},
+	}
+	filesystem := &fs.RealFileSystem{Fs: crossDeviceFs{OsFs: &afero.OsFs{}}}
+	runner := NewRuleRunner(r, filesystem, nil)
+
+	stats, err := runner.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.ErrorCount != 1 {
+		t.Errorf("ErrorCount = %d, want 1 for the FIFO", stats.ErrorCount)
+	}
+
+	// Files after the FIFO are still moved
+	for _, name := range []string{"a.txt", "c.txt"} {
+		if _, err := os.Stat(filepath.Join(dest, name)); err != nil {
+			t.Errorf("expected %s to be moved: %v", name, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(src, "b.pipe")); err != nil {
+		t.Errorf("FIFO should be left in place: %v", err)
+	}
+}
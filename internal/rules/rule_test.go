@@ -1,15 +1,25 @@
 package rules
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/prettymuchbryce/autotidy/internal/testutil"
+	"github.com/prettymuchbryce/autotidy/internal/utils"
+
+	"gopkg.in/yaml.v3"
 )
 
 func boolPtr(b bool) *bool {
 	return &b
 }
 
+func intPtr(i int) *int {
+	return &i
+}
+
 func TestRule_IsEnabled(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -52,6 +62,30 @@ func TestRule_IsRecursive(t *testing.T) {
 	}
 }
 
+func TestRule_StabilityWait(t *testing.T) {
+	tenSeconds := 10 * time.Second
+	zero := time.Duration(0)
+
+	tests := []struct {
+		name     string
+		wait     *time.Duration
+		expected time.Duration
+	}{
+		{"nil uses daemon default", nil, 5 * time.Second},
+		{"explicit wait", &tenSeconds, 10 * time.Second},
+		{"zero disables", &zero, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Rule{WaitUntilStable: tt.wait}
+			if got := r.StabilityWait(5 * time.Second); got != tt.expected {
+				t.Errorf("StabilityWait() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestRule_GetTraversalMode(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -80,6 +114,8 @@ func TestRule_CoversPath(t *testing.T) {
 		name      string
 		locations StringList
 		recursive bool
+		exclude   StringList
+		maxDepth  *int
 		path      string
 		expected  bool
 	}{
@@ -132,6 +168,54 @@ func TestRule_CoversPath(t *testing.T) {
 			path:      testutil.Path(docs, "file.txt"),
 			expected:  false,
 		},
+		{
+			name:      "excluded directory",
+			locations: StringList{docs},
+			recursive: true,
+			exclude:   StringList{"**/node_modules"},
+			path:      testutil.Path(docs, "app", "node_modules"),
+			expected:  false,
+		},
+		{
+			name:      "inside excluded directory",
+			locations: StringList{docs},
+			recursive: true,
+			exclude:   StringList{"**/node_modules"},
+			path:      testutil.Path(docs, "app", "node_modules", "pkg", "index.js"),
+			expected:  false,
+		},
+		{
+			name:      "excluded file non-recursive",
+			locations: StringList{docs},
+			recursive: false,
+			exclude:   StringList{"*.tmp"},
+			path:      testutil.Path(docs, "file.tmp"),
+			expected:  false,
+		},
+		{
+			name:      "location itself never excluded",
+			locations: StringList{docs},
+			recursive: true,
+			exclude:   StringList{"**"},
+			path:      docs,
+			expected:  true,
+		},
+		{
+			name:      "within max depth",
+			locations: StringList{docs},
+			recursive: true,
+			maxDepth:  intPtr(2),
+			path:      testutil.Path(docs, "sub", "file.txt"),
+			expected:  true,
+		},
+		{
+			name:      "beyond max depth",
+			locations: StringList{docs},
+			recursive: true,
+			maxDepth:  intPtr(2),
+			path:      testutil.Path(docs, "sub", "deep", "file.txt"),
+			expected:  false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -139,6 +223,8 @@ func TestRule_CoversPath(t *testing.T) {
 			r := &Rule{
 				Locations: tt.locations,
 				Recursive: boolPtr(tt.recursive),
+				Exclude:   tt.exclude,
+				MaxDepth:  tt.maxDepth,
 			}
 			if got := r.CoversPath(tt.path); got != tt.expected {
 				t.Errorf("CoversPath(%q) = %v, want %v", tt.path, got, tt.expected)
@@ -146,3 +232,222 @@ func TestRule_CoversPath(t *testing.T) {
 		})
 	}
 }
+
+func TestRule_DescendsInto(t *testing.T) {
+	docs := testutil.Path("/", "home", "user", "docs")
+	r := &Rule{
+		Locations: StringList{docs},
+		Recursive: boolPtr(true),
+		Exclude:   StringList{"**/.git", testutil.Path(docs, "archive")},
+		MaxDepth:  intPtr(3),
+	}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{testutil.Path(docs, "work"), true},
+		{testutil.Path(docs, "work", "2024"), true},
+		{testutil.Path(docs, "work", "2024", "q1"), false}, // At max depth
+		{testutil.Path(docs, "work", ".git"), false},
+		{testutil.Path(docs, "archive"), false},
+		{testutil.Path(docs, "archive", "old"), false},
+		{docs, false}, // Not below a location
+	}
+
+	for _, tt := range tests {
+		if got := r.DescendsInto(nil, tt.path); got != tt.expected {
+			t.Errorf("DescendsInto(%q) = %v, want %v", tt.path, got, tt.expected)
+		}
+	}
+
+	r.Recursive = boolPtr(false)
+	if r.DescendsInto(nil, testutil.Path(docs, "work")) {
+		t.Error("non-recursive rule should not descend into subdirectories")
+	}
+}
+
+// locationBinderEvaluable records the locations it was bound to.
+type locationBinderEvaluable struct {
+	locations []string
+	recursive bool
+}
+
+func (l *locationBinderEvaluable) Evaluate(path string) (bool, error) {
+	return true, nil
+}
+
+func (l *locationBinderEvaluable) BindLocations(locations []string, recursive bool) {
+	l.locations = locations
+	l.recursive = recursive
+}
+
+func TestRule_UnmarshalYAML_BindsLocations(t *testing.T) {
+	RegisterFilter("test_location_binder", func(yaml.Node) (Evaluable, error) {
+		return &locationBinderEvaluable{}, nil
+	})
+
+	docs := testutil.Path("/", "home", "user", "docs")
+	input := fmt.Sprintf(`
+name: test
+locations: '%s/'
+recursive: true
+filters:
+  - test_location_binder: true
+  - not:
+      - test_location_binder: true
+`, docs)
+
+	var r Rule
+	if err := yaml.Unmarshal([]byte(input), &r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var bound int
+	r.Filters.EachFilter(func(f *Filter) {
+		b := f.Inner.(*locationBinderEvaluable)
+		// Locations are bound after they are normalized
+		if len(b.locations) != 1 || b.locations[0] != docs {
+			t.Errorf("locations = %v, want [%s]", b.locations, docs)
+		}
+		if !b.recursive {
+			t.Error("expected recursive to be bound")
+		}
+		bound++
+	})
+	if bound != 2 {
+		t.Errorf("bound %d filters, want 2", bound)
+	}
+}
+
+// templatedExecutable is an action with a single template parameter.
+type templatedExecutable struct {
+	testExecutable
+	template utils.Template
+}
+
+func (e *templatedExecutable) Templates() []utils.Template {
+	return []utils.Template{e.template}
+}
+
+func TestRule_UnmarshalYAML_ValidatesTemplates(t *testing.T) {
+	RegisterAction("test_templated", func(node yaml.Node) (Executable, error) {
+		var tmpl string
+		if err := node.Decode(&tmpl); err != nil {
+			return nil, err
+		}
+		return &templatedExecutable{template: utils.Template(tmpl)}, nil
+	})
+	RegisterFilter("test_capture", func(node yaml.Node) (Evaluable, error) {
+		var name string
+		if err := node.Decode(&name); err != nil {
+			return nil, err
+		}
+		return &capturingEvaluable{captures: map[string]string{name: ""}}, nil
+	})
+
+	tests := []struct {
+		name        string
+		template    string
+		errContains string
+	}{
+		{name: "known variables", template: "~/Archive/${relpath}/${name}_${hash:8}${ext}"},
+		{name: "unknown variable", template: "~/Archive/${nmae}", errContains: `action "test_templated": unknown variable ${nmae}`},
+		{name: "invalid argument", template: "${counter:x}", errContains: "width must be between"},
+		{name: "unknown pipe", template: "${name|camel}", errContains: `unknown pipe "camel"`},
+		{name: "captured variable", template: "~/Clients/${client|upper}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := fmt.Sprintf(`
+name: test
+locations: %s
+filters:
+  - test_capture: client
+actions:
+  - test_templated: '%s'
+`, testutil.Path("/", "home", "user"), tt.template)
+
+			var r Rule
+			err := yaml.Unmarshal([]byte(input), &r)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", tt.errContains)
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %q, want it to contain %q", err.Error(), tt.errContains)
+			}
+		})
+	}
+}
+
+func TestRule_UnmarshalYAML_Exclude(t *testing.T) {
+	home := testutil.Path("/", "home", "user")
+
+	tests := []struct {
+		name            string
+		fields          string
+		expectedExclude []string
+		errContains     string
+	}{
+		{
+			name:            "single pattern",
+			fields:          `exclude: "**/node_modules"`,
+			expectedExclude: []string{"**/node_modules"},
+		},
+		{
+			name:            "absolute pattern is cleaned",
+			fields:          fmt.Sprintf("exclude: ['%s/', '*.tmp']", testutil.Path(home, "Library")),
+			expectedExclude: []string{testutil.Path(home, "Library"), "*.tmp"},
+		},
+		{
+			name:        "invalid pattern",
+			fields:      `exclude: "[abc"`,
+			errContains: `invalid exclude pattern "[abc"`,
+		},
+		{
+			name:   "max depth",
+			fields: "max_depth: 2",
+		},
+		{
+			name:        "zero max depth",
+			fields:      "max_depth: 0",
+			errContains: "max_depth must be at least 1",
+		},
+		{
+			name:        "negative stability wait",
+			fields:      "wait_until_stable: -5s",
+			errContains: "wait_until_stable must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := fmt.Sprintf("name: test\nlocations: %s\nrecursive: true\n%s\n", home, tt.fields)
+
+			var r Rule
+			err := yaml.Unmarshal([]byte(input), &r)
+			if tt.errContains != "" {
+				if err == nil {
+					t.Fatalf("expected error containing %q, got nil", tt.errContains)
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("error = %q, want it to contain %q", err.Error(), tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectedExclude != nil && strings.Join(r.Exclude, ",") != strings.Join(tt.expectedExclude, ",") {
+				t.Errorf("Exclude = %v, want %v", r.Exclude, tt.expectedExclude)
+			}
+		})
+	}
+}
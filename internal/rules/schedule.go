@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/prettymuchbryce/autotidy/internal/cron"
+)
+
+// Schedule runs a rule at set times, in addition to when its locations
+// change. It is either a cron expression or a fixed interval.
+type Schedule struct {
+	Cron  string        `yaml:"cron"`
+	Every time.Duration `yaml:"every"`
+
+	cron *cron.Schedule
+}
+
+// UnmarshalYAML decodes a schedule, given either as a cron expression like
+// "0 3 * * *" or as a mapping with cron or every.
+func (s *Schedule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = Schedule{Cron: node.Value}
+	} else {
+		// Use an alias type to avoid infinite recursion
+		type ScheduleAlias Schedule
+		var alias ScheduleAlias
+		if err := DecodeKnownFields(node, &alias); err != nil {
+			return err
+		}
+		*s = Schedule(alias)
+	}
+
+	switch {
+	case s.Cron != "" && s.Every != 0:
+		return fmt.Errorf("schedule must have either cron or every, not both")
+	case s.Cron != "":
+		c, err := cron.Parse(s.Cron)
+		if err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+		s.cron = c
+	case s.Every < 0:
+		return fmt.Errorf("schedule every must be positive, got %s", s.Every)
+	case s.Every == 0:
+		return fmt.Errorf("schedule must have cron or every")
+	}
+	return nil
+}
+
+// Next returns when the schedule next fires after from.
+func (s *Schedule) Next(from time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(from)
+	}
+	return from.Add(s.Every)
+}
+
+// String describes the schedule, e.g. "every 6h" or "0 3 * * *". Changing a
+// rule's schedule changes its description.
+func (s *Schedule) String() string {
+	if s.Cron != "" {
+		return s.Cron
+	}
+	return "every " + formatInterval(s.Every)
+}
+
+// formatInterval formats a duration without trailing zero units, e.g. "6h"
+// instead of "6h0m0s".
+func formatInterval(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
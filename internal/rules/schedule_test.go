@@ -0,0 +1,91 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSchedule_UnmarshalYAML(t *testing.T) {
+	from := time.Date(2024, 1, 10, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		input          string
+		expectedString string
+		expectedNext   time.Time
+		errContains    string
+	}{
+		{
+			name:           "cron expression",
+			input:          `"0 3 * * *"`,
+			expectedString: "0 3 * * *",
+			expectedNext:   time.Date(2024, 1, 11, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "cron mapping",
+			input:          `cron: "@hourly"`,
+			expectedString: "@hourly",
+			expectedNext:   time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "interval",
+			input:          "every: 6h",
+			expectedString: "every 6h",
+			expectedNext:   from.Add(6 * time.Hour),
+		},
+		{
+			name:           "interval with minutes",
+			input:          "every: 90m",
+			expectedString: "every 1h30m",
+			expectedNext:   from.Add(90 * time.Minute),
+		},
+		{
+			name:        "invalid cron expression",
+			input:       `"0 3 * *"`,
+			errContains: "invalid schedule: cron expression must have 5 fields",
+		},
+		{
+			name:        "cron and interval",
+			input:       "{cron: '@daily', every: 1h}",
+			errContains: "either cron or every, not both",
+		},
+		{
+			name:        "negative interval",
+			input:       "every: -1h",
+			errContains: "schedule every must be positive",
+		},
+		{
+			name:        "empty mapping",
+			input:       "{}",
+			errContains: "schedule must have cron or every",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s Schedule
+			err := yaml.Unmarshal([]byte(tt.input), &s)
+			if tt.errContains != "" {
+				if err == nil {
+					t.Fatalf("expected error containing %q, got nil", tt.errContains)
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("error = %v, want containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.String() != tt.expectedString {
+				t.Errorf("String() = %q, want %q", s.String(), tt.expectedString)
+			}
+			if next := s.Next(from); !next.Equal(tt.expectedNext) {
+				t.Errorf("Next() = %s, want %s", next, tt.expectedNext)
+			}
+		})
+	}
+}
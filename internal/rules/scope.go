@@ -0,0 +1,202 @@
+package rules
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/afero"
+
+	"github.com/prettymuchbryce/autotidy/internal/ignore"
+)
+
+// IgnoreFileName is the name of the files, in gitignore syntax, that list
+// entries every rule skips in the directory containing them and below.
+const IgnoreFileName = ".autotidyignore"
+
+// Scope limits which entries below one of a rule's locations are part of the
+// rule, based on its exclude patterns, max_depth, skip_hidden and ignore
+// files. Traversal and watches skip directories outside the scope entirely,
+// instead of reading them and filtering afterwards.
+type Scope struct {
+	rule     *Rule
+	location string
+
+	// fs reads ignore files. When nil, ignore files are not consulted.
+	fs afero.Fs
+
+	// ignoreFiles caches the ignore files found in each directory.
+	ignoreFiles map[string][]*ignore.File
+
+	// dirs caches whether each directory is part of the scope.
+	dirs map[string]bool
+}
+
+// Scope returns the scope of the rule below location. Ignore files are read
+// from filesystem as they are needed; pass nil to skip them.
+func (r *Rule) Scope(filesystem afero.Fs, location string) *Scope {
+	return &Scope{
+		rule:        r,
+		location:    location,
+		fs:          filesystem,
+		ignoreFiles: make(map[string][]*ignore.File),
+		dirs:        make(map[string]bool),
+	}
+}
+
+// Includes reports whether the entry at path is part of the scope: it is not
+// skipped itself, does not lie inside a skipped directory, and is not deeper
+// than max_depth. The location itself is always included. Whether the entry
+// is a directory only matters for ignore file patterns like "build/".
+func (s *Scope) Includes(path string, isDir bool) bool {
+	if path == s.location {
+		return true
+	}
+	rel, ok := s.relative(path)
+	if !ok {
+		return false
+	}
+	if s.rule.MaxDepth != nil && strings.Count(rel, "/")+1 > *s.rule.MaxDepth {
+		return false
+	}
+
+	// Skipping a directory skips everything inside it
+	parent := filepath.Dir(path)
+	if parent != s.location && !s.includesDir(parent) {
+		return false
+	}
+	return s.includesEntry(path, rel, isDir)
+}
+
+// Descends reports whether the children of the directory at path are part of
+// the scope, so the directory should be read and watched.
+func (s *Scope) Descends(path string) bool {
+	if path == s.location {
+		return true
+	}
+	if !s.rule.IsRecursive() || !s.Includes(path, true) {
+		return false
+	}
+	if s.rule.MaxDepth != nil {
+		rel, _ := s.relative(path)
+		return strings.Count(rel, "/")+1 < *s.rule.MaxDepth
+	}
+	return true
+}
+
+// includesDir is Includes for a directory, cached since every entry inside
+// the directory checks it.
+func (s *Scope) includesDir(path string) bool {
+	if included, ok := s.dirs[path]; ok {
+		return included
+	}
+	included := s.Includes(path, true)
+	s.dirs[path] = included
+	return included
+}
+
+// includesEntry checks the entry itself, assuming its parent is included.
+func (s *Scope) includesEntry(path, rel string, isDir bool) bool {
+	name := filepath.Base(path)
+	if s.rule.SkipHidden && strings.HasPrefix(name, ".") {
+		return false
+	}
+	if s.rule.RespectGitignore && name == ".git" {
+		return false
+	}
+	// Ignore files configure autotidy, so rules never act on them
+	if !isDir && slices.Contains(s.ignoreFileNames(), name) {
+		return false
+	}
+	return !s.excluded(rel) && !s.ignored(path, isDir)
+}
+
+// relative returns the slash-separated path of path relative to the location,
+// and false if path is not below the location.
+func (s *Scope) relative(path string) (string, bool) {
+	if !strings.HasPrefix(path, s.location+string(filepath.Separator)) {
+		return "", false
+	}
+	rel, err := filepath.Rel(s.location, path)
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// excluded reports whether the entry at the slash-separated path rel matches
+// one of the rule's exclude patterns. Absolute patterns match the full path,
+// others the path relative to the location.
+func (s *Scope) excluded(rel string) bool {
+	full := filepath.ToSlash(filepath.Join(s.location, filepath.FromSlash(rel)))
+	for _, pattern := range s.rule.Exclude {
+		target := rel
+		if filepath.IsAbs(pattern) {
+			target = full
+			pattern = filepath.ToSlash(pattern)
+		}
+		if matched, _ := doublestar.Match(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ignored reports whether an ignore file in one of the entry's ancestor
+// directories, up to the location, ignores it. Like git, files in deeper
+// directories take precedence over those above them.
+func (s *Scope) ignored(path string, isDir bool) bool {
+	if s.fs == nil {
+		return false
+	}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return false
+		}
+		for _, f := range s.loadIgnoreFiles(dir) {
+			if matched, ignored := f.Match(filepath.ToSlash(rel), isDir); matched {
+				return ignored
+			}
+		}
+		if dir == s.location || filepath.Dir(dir) == dir {
+			return false
+		}
+	}
+}
+
+// ignoreFileNames returns the names of the ignore files that apply to the
+// rule, in order of precedence.
+func (s *Scope) ignoreFileNames() []string {
+	if s.rule.RespectGitignore {
+		return []string{IgnoreFileName, ".gitignore"}
+	}
+	return []string{IgnoreFileName}
+}
+
+// loadIgnoreFiles returns the ignore files in dir that apply to the rule, in
+// order of precedence.
+func (s *Scope) loadIgnoreFiles(dir string) []*ignore.File {
+	if files, ok := s.ignoreFiles[dir]; ok {
+		return files
+	}
+
+	var files []*ignore.File
+	for _, name := range s.ignoreFileNames() {
+		path := filepath.Join(dir, name)
+		content, err := afero.ReadFile(s.fs, path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("failed to read ignore file", "path", path, "error", err)
+			}
+			continue
+		}
+		files = append(files, ignore.Parse(content))
+	}
+	s.ignoreFiles[dir] = files
+	return files
+}
@@ -39,6 +39,13 @@ type Visitor[T any] func(path string) (TraverseControl, T, error)
 // BuildSnapshot builds a snapshot of a directory tree.
 // If recursive is false, only includes direct children.
 func BuildSnapshot(fs afero.Fs, root string, recursive bool) *Node {
+	return BuildScopedSnapshot(fs, root, (&Rule{Recursive: &recursive}).Scope(nil, root))
+}
+
+// BuildScopedSnapshot builds a snapshot of the part of a directory tree within
+// scope. Entries outside the scope are left out, and directories whose
+// children are outside it are never read.
+func BuildScopedSnapshot(fs afero.Fs, root string, scope *Scope) *Node {
 	info, err := fs.Stat(root)
 	if err != nil {
 		slog.Warn("failed to stat path", "path", root, "error", err)
@@ -67,8 +74,11 @@ func BuildSnapshot(fs afero.Fs, root string, recursive bool) *Node {
 
 	for _, entry := range entries {
 		childPath := filepath.Join(root, entry.Name())
-		if entry.IsDir() && recursive {
-			child := BuildSnapshot(fs, childPath, recursive)
+		if !scope.Includes(childPath, entry.IsDir()) {
+			continue
+		}
+		if entry.IsDir() && scope.Descends(childPath) {
+			child := BuildScopedSnapshot(fs, childPath, scope)
 			if child != nil {
 				node.Children = append(node.Children, child)
 			}
@@ -1,6 +1,9 @@
 package rules
 
 import (
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/spf13/afero"
@@ -249,3 +252,174 @@ func TestTraverseChildren_CollectsValues(t *testing.T) {
 	}
 }
 
+
+// openRecordingFs records every path that is opened, e.g. to read a directory.
+type openRecordingFs struct {
+	afero.Fs
+	opened []string
+}
+
+func (f *openRecordingFs) Open(name string) (afero.File, error) {
+	f.opened = append(f.opened, name)
+	return f.Fs.Open(name)
+}
+
+func TestBuildScopedSnapshot(t *testing.T) {
+	root := testutil.Path("/", "root")
+
+	tests := []struct {
+		name          string
+		exclude       StringList
+		maxDepth      *int
+		expectedPaths []string // relative to root, sorted
+		neverRead     []string // directories that must not be opened
+	}{
+		{
+			name:          "no limits",
+			expectedPaths: []string{"a.tmp", "a.txt", "app", "app/index.js", "app/node_modules", "app/node_modules/pkg", "app/node_modules/pkg/lib.js"},
+		},
+		{
+			name:          "excluded directory is pruned",
+			exclude:       StringList{"**/node_modules"},
+			expectedPaths: []string{"a.tmp", "a.txt", "app", "app/index.js"},
+			neverRead:     []string{"app/node_modules"},
+		},
+		{
+			name:          "excluded files",
+			exclude:       StringList{"**/*.tmp", "**/*.js"},
+			expectedPaths: []string{"a.txt", "app", "app/node_modules", "app/node_modules/pkg"},
+		},
+		{
+			name:          "absolute exclude",
+			exclude:       StringList{testutil.Path(root, "app")},
+			expectedPaths: []string{"a.tmp", "a.txt"},
+			neverRead:     []string{"app"},
+		},
+		{
+			name:          "max depth",
+			maxDepth:      intPtr(2),
+			expectedPaths: []string{"a.tmp", "a.txt", "app", "app/index.js", "app/node_modules"},
+			neverRead:     []string{"app/node_modules"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := &openRecordingFs{Fs: afero.NewMemMapFs()}
+			afero.WriteFile(fs, testutil.Path(root, "a.txt"), []byte("a"), 0644)
+			afero.WriteFile(fs, testutil.Path(root, "a.tmp"), []byte("a"), 0644)
+			afero.WriteFile(fs, testutil.Path(root, "app", "index.js"), []byte("a"), 0644)
+			afero.WriteFile(fs, testutil.Path(root, "app", "node_modules", "pkg", "lib.js"), []byte("a"), 0644)
+
+			r := &Rule{Locations: StringList{root}, Recursive: boolPtr(true), Exclude: tt.exclude, MaxDepth: tt.maxDepth}
+			node := BuildScopedSnapshot(fs, root, r.Scope(fs, root))
+			if node == nil {
+				t.Fatal("expected node, got nil")
+			}
+
+			paths, err := TraverseChildrenBFS(node, testutil.Path("/"), func(path string) (TraverseControl, string, error) {
+				rel, _ := filepath.Rel(root, path)
+				return TraverseControl{}, filepath.ToSlash(rel), nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sort.Strings(paths)
+			if strings.Join(paths, ",") != strings.Join(tt.expectedPaths, ",") {
+				t.Errorf("paths = %v, want %v", paths, tt.expectedPaths)
+			}
+
+			for _, dir := range tt.neverRead {
+				full := filepath.Join(root, filepath.FromSlash(dir))
+				for _, opened := range fs.opened {
+					if opened == full {
+						t.Errorf("excluded directory %s was read", dir)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBuildScopedSnapshot_IgnoreFiles(t *testing.T) {
+	root := testutil.Path("/", "root")
+
+	tests := []struct {
+		name             string
+		skipHidden       bool
+		respectGitignore bool
+		expectedPaths    []string // relative to root, sorted
+		neverRead        []string // directories that must not be opened
+	}{
+		{
+			name:          "autotidyignore only",
+			expectedPaths: []string{".git", ".git/HEAD", ".gitignore", ".hidden", "app", "app/build", "app/build/out.js", "app/main.go", "notes.txt"},
+			neverRead:     []string{"app/vendor"},
+		},
+		{
+			name:          "skip hidden",
+			skipHidden:    true,
+			expectedPaths: []string{"app", "app/build", "app/build/out.js", "app/main.go", "notes.txt"},
+			neverRead:     []string{".git", "app/vendor"},
+		},
+		{
+			name:             "respect gitignore",
+			respectGitignore: true,
+			expectedPaths:    []string{".hidden", "app", "app/main.go", "notes.txt"},
+			neverRead:        []string{".git", "app/build", "app/vendor"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := &openRecordingFs{Fs: afero.NewMemMapFs()}
+			files := map[string]string{
+				".autotidyignore":     "*.tmp\n",
+				".gitignore":          "build/\n",
+				".hidden":             "",
+				".git/HEAD":           "",
+				"notes.txt":           "",
+				"draft.tmp":           "",
+				"app/.autotidyignore": "vendor/\n",
+				"app/main.go":         "",
+				"app/vendor/lib.go":   "",
+				"app/build/out.js":    "",
+			}
+			for name, content := range files {
+				afero.WriteFile(fs, filepath.Join(root, filepath.FromSlash(name)), []byte(content), 0644)
+			}
+
+			r := &Rule{
+				Locations:        StringList{root},
+				Recursive:        boolPtr(true),
+				SkipHidden:       tt.skipHidden,
+				RespectGitignore: tt.respectGitignore,
+			}
+			node := BuildScopedSnapshot(fs, root, r.Scope(fs, root))
+			if node == nil {
+				t.Fatal("expected node, got nil")
+			}
+
+			paths, err := TraverseChildrenBFS(node, testutil.Path("/"), func(path string) (TraverseControl, string, error) {
+				rel, _ := filepath.Rel(root, path)
+				return TraverseControl{}, filepath.ToSlash(rel), nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sort.Strings(paths)
+			if strings.Join(paths, ",") != strings.Join(tt.expectedPaths, ",") {
+				t.Errorf("paths = %v, want %v", paths, tt.expectedPaths)
+			}
+
+			for _, dir := range tt.neverRead {
+				full := filepath.Join(root, filepath.FromSlash(dir))
+				for _, opened := range fs.opened {
+					if opened == full {
+						t.Errorf("ignored directory %s was read", dir)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package rules
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Watch modes selecting how the daemon notices changes in a rule's locations.
+const (
+	WatchEvents = "events" // Filesystem events from the operating system
+	WatchPoll   = "poll"   // Periodically listing directories
+)
+
+// MinPollInterval is the shortest interval directories can be polled at.
+const MinPollInterval = time.Second
+
+// Watch configures how the daemon notices changes in a rule's locations.
+// Polling works on network and FUSE mounts, which deliver no events.
+type Watch struct {
+	Mode     string
+	Interval time.Duration // Poll interval, 0 uses the daemon default
+}
+
+// UnmarshalYAML decodes a watch mode, given either as "events" or "poll", or
+// as a mapping like {poll: 30s} to poll at a specific interval.
+func (w *Watch) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*w = Watch{Mode: node.Value}
+		if w.Mode != WatchEvents && w.Mode != WatchPoll {
+			return fmt.Errorf("invalid watch mode %q (must be %q or %q)", w.Mode, WatchEvents, WatchPoll)
+		}
+		return nil
+	}
+
+	var m struct {
+		Poll time.Duration `yaml:"poll"`
+	}
+	if err := DecodeKnownFields(node, &m); err != nil {
+		return err
+	}
+	if m.Poll < MinPollInterval {
+		return fmt.Errorf("watch poll interval must be at least %s, got %s", MinPollInterval, m.Poll)
+	}
+	*w = Watch{Mode: WatchPoll, Interval: m.Poll}
+	return nil
+}
+
+// PollInterval returns how often the daemon polls the rule's locations, or 0
+// if it relies on filesystem events. Rules polled without an interval use the
+// daemon's default.
+func (r *Rule) PollInterval(daemonDefault time.Duration) time.Duration {
+	if r.Watch == nil || r.Watch.Mode != WatchPoll {
+		return 0
+	}
+	if r.Watch.Interval == 0 {
+		return daemonDefault
+	}
+	return r.Watch.Interval
+}
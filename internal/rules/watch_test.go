@@ -0,0 +1,53 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestWatch_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name                 string
+		input                string
+		expectedPollInterval time.Duration
+		errContains          string
+	}{
+		{name: "events", input: "events", expectedPollInterval: 0},
+		{name: "poll with daemon default", input: "poll", expectedPollInterval: 10 * time.Second},
+		{name: "poll with interval", input: "{poll: 30s}", expectedPollInterval: 30 * time.Second},
+		{name: "unknown mode", input: "inotify", errContains: `invalid watch mode "inotify"`},
+		{name: "interval too short", input: "{poll: 100ms}", errContains: "watch poll interval must be at least 1s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r Rule
+			err := yaml.Unmarshal([]byte("name: test\nwatch: "+tt.input+"\n"), &r)
+			if tt.errContains != "" {
+				if err == nil {
+					t.Fatalf("expected error containing %q, got nil", tt.errContains)
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("error = %v, want containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := r.PollInterval(10 * time.Second); got != tt.expectedPollInterval {
+				t.Errorf("PollInterval() = %s, want %s", got, tt.expectedPollInterval)
+			}
+		})
+	}
+}
+
+func TestRule_PollInterval_NoWatch(t *testing.T) {
+	r := &Rule{}
+	if got := r.PollInterval(10 * time.Second); got != 0 {
+		t.Errorf("PollInterval() = %s, want 0 for rules without watch", got)
+	}
+}
@@ -0,0 +1,166 @@
+package state
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/ipc"
+)
+
+// maxHashCacheEntries bounds the hash cache. It is cleared when full.
+const maxHashCacheEntries = 100000
+
+// FileHash holds the cached content hashes of one version of a file.
+type FileHash struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`       // Unix nanoseconds
+	Fast    string `json:"fast,omitempty"` // Hash of the first and last blocks
+	Full    string `json:"full,omitempty"` // Hash of the entire contents
+}
+
+// HashCache persists file content hashes across runs, keyed by path.
+// A cached entry is only used while the file's size and modification time
+// are unchanged.
+type HashCache struct {
+	mu    sync.Mutex
+	path  string
+	dirty bool
+	Files map[string]FileHash `json:"files"`
+}
+
+// LoadHashCache loads the hash cache from the default path.
+// If the file doesn't exist, returns an empty cache.
+func LoadHashCache() (*HashCache, error) {
+	path, err := ipc.HashCachePath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadHashCacheFrom(path)
+}
+
+// LoadHashCacheFrom loads the hash cache from the specified path.
+// If the file doesn't exist, returns an empty cache.
+// An empty path creates a cache that is never persisted.
+func LoadHashCacheFrom(path string) (*HashCache, error) {
+	c := &HashCache{
+		path:  path,
+		Files: make(map[string]FileHash),
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		// Hashes can always be recomputed, so start fresh
+		slog.Warn("failed to parse hash cache, starting fresh", "error", err)
+		c.Files = make(map[string]FileHash)
+		return c, nil
+	}
+
+	if c.Files == nil {
+		c.Files = make(map[string]FileHash)
+	}
+
+	return c, nil
+}
+
+// Get returns the cached hashes for a version of a file.
+// If nothing is cached for that size and modification time, the returned
+// entry only has Size and ModTime set.
+func (c *HashCache) Get(path string, size int64, modTime time.Time) FileHash {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.Files[path]
+	if !ok || h.Size != size || h.ModTime != modTime.UnixNano() {
+		return FileHash{Size: size, ModTime: modTime.UnixNano()}
+	}
+	return h
+}
+
+// Put stores the hashes for a version of a file, replacing any older version.
+func (c *HashCache) Put(path string, h FileHash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.Files[path]; !ok && len(c.Files) >= maxHashCacheEntries {
+		c.Files = make(map[string]FileHash)
+	}
+	c.Files[path] = h
+	c.dirty = true
+}
+
+// Save persists the cache to disk if it changed since it was loaded.
+func (c *HashCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty || c.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash can't leave a truncated cache
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// sharedHashCache is the process-wide cache used by filters.
+var sharedHashCache struct {
+	once  sync.Once
+	cache atomic.Pointer[HashCache]
+}
+
+// SharedHashCache returns the process-wide hash cache, loading it from the
+// default path on first use. If it can't be loaded, an in-memory cache is used.
+func SharedHashCache() *HashCache {
+	sharedHashCache.once.Do(func() {
+		c, err := LoadHashCache()
+		if err != nil {
+			slog.Warn("failed to load hash cache, hashes will not be persisted", "error", err)
+			c, _ = LoadHashCacheFrom("")
+		}
+		sharedHashCache.cache.Store(c)
+	})
+	return sharedHashCache.cache.Load()
+}
+
+// SaveSharedHashCache persists the process-wide hash cache.
+// Does nothing if the cache was never used.
+func SaveSharedHashCache() error {
+	c := sharedHashCache.cache.Load()
+	if c == nil {
+		return nil
+	}
+	return c.Save()
+}
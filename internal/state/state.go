@@ -17,6 +17,10 @@ type RuleState struct {
 	LastDuration   time.Duration `json:"last_duration"`
 	FilesProcessed int           `json:"files_processed"`
 	ErrorCount     int           `json:"error_count"`
+
+	// Next scheduled run, and the schedule it was computed from
+	NextRunAt time.Time `json:"next_run_at,omitzero"`
+	Schedule  string    `json:"schedule,omitempty"`
 }
 
 // State tracks daemon state that persists across restarts.
@@ -72,15 +76,41 @@ func (s *State) UpdateRuleStats(ruleName string, runAt time.Time, duration time.
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.Rules[ruleName] = RuleState{
-		LastRunAt:      runAt,
-		LastDuration:   duration,
-		FilesProcessed: filesProcessed,
-		ErrorCount:     errorCount,
-	}
+	rs := s.Rules[ruleName]
+	rs.LastRunAt = runAt
+	rs.LastDuration = duration
+	rs.FilesProcessed = filesProcessed
+	rs.ErrorCount = errorCount
+	s.Rules[ruleName] = rs
+	return s.save()
+}
+
+// SetNextRun records when a scheduled rule runs next, and the description of
+// the schedule it was computed from, and persists to disk.
+func (s *State) SetNextRun(ruleName, schedule string, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs := s.Rules[ruleName]
+	rs.NextRunAt = next
+	rs.Schedule = schedule
+	s.Rules[ruleName] = rs
 	return s.save()
 }
 
+// NextRun returns the persisted next run of a scheduled rule. It returns
+// false if none was recorded, or it was computed from a different schedule.
+func (s *State) NextRun(ruleName, schedule string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rs, ok := s.Rules[ruleName]
+	if !ok || rs.NextRunAt.IsZero() || rs.Schedule != schedule {
+		return time.Time{}, false
+	}
+	return rs.NextRunAt, true
+}
+
 // save persists the state to disk. Must be called with mu held.
 func (s *State) save() error {
 	if s.path == "" {
@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func init() {
+	RegisterPipe("lower", 0, 0, pipeLower)
+	RegisterPipe("upper", 0, 0, pipeUpper)
+	RegisterPipe("slug", 0, 0, pipeSlug)
+	RegisterPipe("trim", 0, 1, pipeTrim)
+	RegisterPipe("replace", 2, 2, pipeReplace)
+	RegisterPipe("truncate", 1, 1, pipeTruncate)
+}
+
+// PipeFunc transforms a variable's value, like lower in ${name|lower}.
+// Pipes must not have side effects: they are also run on an empty value when
+// a template is validated, so invalid arguments are reported at load time.
+type PipeFunc func(value string, args []string) (string, error)
+
+// pipe is a registered PipeFunc with the number of arguments it accepts.
+type pipe struct {
+	minArgs int
+	maxArgs int
+	fn      PipeFunc
+}
+
+// pipeRegistry holds registered pipes.
+var pipeRegistry = map[string]pipe{}
+
+// RegisterPipe registers a template pipe by name.
+func RegisterPipe(name string, minArgs, maxArgs int, fn PipeFunc) {
+	pipeRegistry[name] = pipe{minArgs: minArgs, maxArgs: maxArgs, fn: fn}
+}
+
+// pipeCall is a single "|name:arg:arg" stage of a variable expression.
+type pipeCall struct {
+	name string
+	args []string
+}
+
+// parseExpression splits the contents of ${...} into the variable reference
+// and its pipes. Arguments are separated by colons and may be double-quoted
+// to contain colons, pipes or spaces, e.g. ${name|replace:" ":"_"}.
+func parseExpression(expr string) (string, []pipeCall, error) {
+	stages := splitUnquoted(expr, '|')
+	ref := strings.TrimSpace(stages[0])
+
+	var pipes []pipeCall
+	for _, stage := range stages[1:] {
+		parts := splitUnquoted(stage, ':')
+		call := pipeCall{name: strings.TrimSpace(parts[0])}
+		if call.name == "" {
+			return "", nil, fmt.Errorf("empty pipe in ${%s}", expr)
+		}
+		for _, arg := range parts[1:] {
+			arg = strings.TrimSpace(arg)
+			if strings.HasPrefix(arg, `"`) {
+				unquoted, err := strconv.Unquote(arg)
+				if err != nil {
+					return "", nil, fmt.Errorf("invalid argument %s in ${%s}", arg, expr)
+				}
+				arg = unquoted
+			}
+			call.args = append(call.args, arg)
+		}
+		pipes = append(pipes, call)
+	}
+	return ref, pipes, nil
+}
+
+// splitUnquoted splits s at every sep that is not inside double quotes.
+func splitUnquoted(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && inQuotes:
+			i++ // Skip the escaped character
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// applyPipes runs value through each pipe in order.
+func applyPipes(value string, pipes []pipeCall) (string, error) {
+	for _, call := range pipes {
+		p, ok := pipeRegistry[call.name]
+		if !ok {
+			return "", fmt.Errorf("unknown pipe %q, available: %v", call.name, availablePipes())
+		}
+		if len(call.args) < p.minArgs || len(call.args) > p.maxArgs {
+			return "", fmt.Errorf("pipe %s takes %s, got %d", call.name, describeArgs(p.minArgs, p.maxArgs), len(call.args))
+		}
+
+		var err error
+		value, err = p.fn(value, call.args)
+		if err != nil {
+			return "", fmt.Errorf("pipe %s: %w", call.name, err)
+		}
+	}
+	return value, nil
+}
+
+// availablePipes returns the sorted names of all registered pipes.
+func availablePipes() []string {
+	names := make([]string, 0, len(pipeRegistry))
+	for name := range pipeRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describeArgs formats an argument count range, e.g. "1 argument".
+func describeArgs(minArgs, maxArgs int) string {
+	noun := "arguments"
+	if maxArgs == 1 {
+		noun = "argument"
+	}
+	switch {
+	case maxArgs == 0:
+		return "no arguments"
+	case minArgs == maxArgs:
+		return fmt.Sprintf("%d %s", maxArgs, noun)
+	default:
+		return fmt.Sprintf("%d to %d %s", minArgs, maxArgs, noun)
+	}
+}
+
+// pipeLower converts the value to lower case.
+func pipeLower(value string, _ []string) (string, error) {
+	return strings.ToLower(value), nil
+}
+
+// pipeUpper converts the value to upper case.
+func pipeUpper(value string, _ []string) (string, error) {
+	return strings.ToUpper(value), nil
+}
+
+// pipeSlug lowercases the value and joins runs of letters and digits with
+// hyphens, e.g. "My Scan (1) FINAL" becomes "my-scan-1-final".
+func pipeSlug(value string, _ []string) (string, error) {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			pendingHyphen = b.Len() > 0
+			continue
+		}
+		if pendingHyphen {
+			b.WriteByte('-')
+			pendingHyphen = false
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String(), nil
+}
+
+// pipeTrim removes leading and trailing whitespace, or the given characters.
+func pipeTrim(value string, args []string) (string, error) {
+	if len(args) == 1 {
+		return strings.Trim(value, args[0]), nil
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// pipeReplace replaces every occurrence of the first argument with the second.
+func pipeReplace(value string, args []string) (string, error) {
+	if args[0] == "" {
+		return "", fmt.Errorf("text to replace must not be empty")
+	}
+	return strings.ReplaceAll(value, args[0], args[1]), nil
+}
+
+// pipeTruncate shortens the value to at most n characters.
+func pipeTruncate(value string, args []string) (string, error) {
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return "", fmt.Errorf("length must be a positive number, got %q", args[0])
+	}
+	runes := []rune(value)
+	if len(runes) <= n {
+		return value, nil
+	}
+	return string(runes[:n]), nil
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+)
+
+func TestTemplate_Pipes(t *testing.T) {
+	path := testutil.Path("/", "dir", "My Scan (1) FINAL.PDF")
+
+	tests := []struct {
+		name     string
+		template Template
+		expected string
+	}{
+		{
+			name:     "lower",
+			template: Template("${name|lower}${ext|lower}"),
+			expected: "my scan (1) final.pdf",
+		},
+		{
+			name:     "upper",
+			template: Template("${name|upper}"),
+			expected: "MY SCAN (1) FINAL",
+		},
+		{
+			name:     "slug",
+			template: Template("${name|slug}${ext|lower}"),
+			expected: "my-scan-1-final.pdf",
+		},
+		{
+			name:     "replace with quoted arguments",
+			template: Template(`${name|replace:" ":"_"}`),
+			expected: "My_Scan_(1)_FINAL",
+		},
+		{
+			name:     "replace with bare arguments",
+			template: Template("${name|replace:FINAL:v2}"),
+			expected: "My Scan (1) v2",
+		},
+		{
+			name:     "quoted argument containing pipe and brace",
+			template: Template(`${name|replace:" ":"|}"}`),
+			expected: "My|}Scan|}(1)|}FINAL",
+		},
+		{
+			name:     "truncate",
+			template: Template("${name|truncate:7}"),
+			expected: "My Scan",
+		},
+		{
+			name:     "truncate shorter value unchanged",
+			template: Template("${ext|truncate:40}"),
+			expected: ".PDF",
+		},
+		{
+			name:     "trim characters",
+			template: Template(`${ext|trim:"."}`),
+			expected: "PDF",
+		},
+		{
+			name:     "chained pipes",
+			template: Template("${name|truncate:7|lower|replace:\" \":\"-\"}"),
+			expected: "my-scan",
+		},
+		{
+			name:     "spaces around pipes",
+			template: Template("${name | slug}"),
+			expected: "my-scan-1-final",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.template.Expand(FileContext{Path: path})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.String() != tt.expected {
+				t.Errorf("result = %q, want %q", result.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestTemplate_ExpandWith_Pipes(t *testing.T) {
+	result := Template("${client|lower}/${year}/${other|lower}").ExpandWith(map[string]string{
+		"client": "ACME",
+		"year":   "2024",
+	})
+
+	// Pipes apply to known variables, unknown ones are left for later expansion
+	expected := "acme/2024/${other|lower}"
+	if result.String() != expected {
+		t.Errorf("result = %q, want %q", result.String(), expected)
+	}
+}
+
+func TestTemplate_Validate_Pipes(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    Template
+		errContains string
+	}{
+		{name: "known pipes", template: Template(`${name|slug|truncate:40}${ext|lower}`)},
+		{name: "trim without arguments", template: Template("${name|trim}")},
+		{name: "unknown pipe", template: Template("${name|camel}"), errContains: `unknown pipe "camel"`},
+		{name: "unknown variable with pipe", template: Template("${nmae|lower}"), errContains: "unknown variable ${nmae}"},
+		{name: "missing arguments", template: Template("${name|replace:x}"), errContains: "pipe replace takes 2 arguments, got 1"},
+		{name: "unexpected argument", template: Template("${name|lower:x}"), errContains: "pipe lower takes no arguments"},
+		{name: "invalid truncate length", template: Template("${name|truncate:abc}"), errContains: "length must be a positive number"},
+		{name: "empty replace text", template: Template(`${name|replace:"":x}`), errContains: "text to replace must not be empty"},
+		{name: "empty pipe", template: Template("${name|}"), errContains: "empty pipe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.template.Validate()
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", tt.errContains)
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %q, want it to contain %q", err.Error(), tt.errContains)
+			}
+		})
+	}
+}
+
+func TestRegisterPipe(t *testing.T) {
+	RegisterPipe("test_reverse", 0, 0, func(value string, _ []string) (string, error) {
+		runes := []rune(value)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	})
+
+	result, err := Template("${name|test_reverse}").Expand(FileContext{Path: testutil.Path("/", "abc.txt")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.String() != "cba" {
+		t.Errorf("result = %q, want %q", result.String(), "cba")
+	}
+}
@@ -11,11 +11,12 @@ import (
 	"github.com/itchyny/timefmt-go"
 )
 
-// variablePattern matches ${var} patterns.
-var variablePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+// variablePattern matches ${var} patterns. Quoted pipe arguments may contain "}".
+var variablePattern = regexp.MustCompile(`\$\{((?:[^}"]|"(?:[^"\\]|\\.)*")+)\}`)
 
 // Template is a string that supports template expansion.
-// It can contain ${name}, ${ext} variables and strftime tokens like %Y, %m, %d.
+// It can contain variables like ${name} and ${ext} (see Expand), optionally
+// transformed by pipes like ${name|lower}, and strftime tokens like %Y, %m, %d.
 // Unlike TemplatePath, it does not perform tilde expansion.
 type Template string
 
@@ -34,23 +35,72 @@ func (t Template) ExpandWithNameExt(path string) Template {
 	})
 }
 
+// ExpandWith replaces ${var} occurrences with values from vars.
+// Variables not in vars are left unchanged for later expansion.
+func (t Template) ExpandWith(vars map[string]string) Template {
+	return replaceVariables(t, vars)
+}
+
 func (t Template) ExpandWithTime() Template {
-	now := time.Now()
-	return Template(timefmt.Format(now, string(t)))
+	return t.ExpandWithTimeAt(time.Now())
+}
+
+// ExpandWithTimeAt formats strftime tokens like %Y and %m from the given time.
+func (t Template) ExpandWithTimeAt(at time.Time) Template {
+	return Template(timefmt.Format(at, string(t)))
 }
 
 func (t Template) String() string {
 	return string(t)
 }
 
+// References reports whether the template uses the variable ref, with or
+// without pipes, e.g. ${path} or ${path|lower}.
+func (t Template) References(ref string) bool {
+	for _, m := range variablePattern.FindAllStringSubmatch(string(t), -1) {
+		if r, _, err := parseExpression(m[1]); err == nil && r == ref {
+			return true
+		}
+	}
+	return false
+}
+
 func replaceVariables(template Template, vars map[string]string) Template {
+	result, _ := expandVariables(template, func(ref string) (string, bool, error) {
+		val, ok := vars[ref]
+		return val, ok, nil
+	})
+	return result
+}
+
+// expandVariables replaces each ${ref|pipe...} whose ref is found by lookup,
+// applying its pipes. Variables lookup doesn't know are left unchanged.
+func expandVariables(template Template, lookup func(ref string) (string, bool, error)) (Template, error) {
+	var firstErr error
 	result := variablePattern.ReplaceAllStringFunc(string(template), func(match string) string {
-		// Extract variable name from ${name}
-		varName := match[2 : len(match)-1]
-		if val, ok := vars[varName]; ok {
-			return val
+		if firstErr != nil {
+			return match
+		}
+		ref, pipes, err := parseExpression(match[2 : len(match)-1])
+		if err != nil {
+			firstErr = err
+			return match
+		}
+		val, ok, err := lookup(ref)
+		if err == nil && ok {
+			val, err = applyPipes(val, pipes)
+		}
+		if err != nil {
+			firstErr = err
+			return match
+		}
+		if !ok {
+			return match // leave unchanged if not found
 		}
-		return match // leave unchanged if not found
+		return val
 	})
-	return Template(result)
+	if firstErr != nil {
+		return template, firstErr
+	}
+	return Template(result), nil
 }
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"testing"
+	"time"
 
 	"github.com/prettymuchbryce/autotidy/internal/testutil"
 )
@@ -102,6 +103,15 @@ func TestTemplate_ExpandWithTime(t *testing.T) {
 	}
 }
 
+func TestTemplate_ExpandWithTimeAt(t *testing.T) {
+	at := time.Date(2019, time.July, 14, 9, 30, 0, 0, time.UTC)
+	result := Template("%Y/%B/%d").ExpandWithTimeAt(at)
+
+	if result.String() != "2019/July/14" {
+		t.Errorf("result = %q, want %q", result.String(), "2019/July/14")
+	}
+}
+
 func TestTemplate_Chained(t *testing.T) {
 	// Test chaining ExpandWithNameExt and ExpandWithTime
 	template := Template("${name}_copy${ext}")
@@ -119,6 +129,32 @@ func TestTemplate_String(t *testing.T) {
 	}
 }
 
+func TestTemplate_References(t *testing.T) {
+	tests := []struct {
+		template Template
+		ref      string
+		expected bool
+	}{
+		{"${path}", "path", true},
+		{"${path|lower}", "path", true},
+		{"${path}.bak", "path", true},
+		{"--in=${ path }", "path", true},
+		{"${parent}/${name}", "path", false},
+		{"$path", "path", false},
+		{"${pathname}", "path", false},
+		{"${env:path}", "path", false},
+		{"${hash:8}", "hash:8", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.template), func(t *testing.T) {
+			if got := tt.template.References(tt.ref); got != tt.expected {
+				t.Errorf("References(%q) = %v, want %v", tt.ref, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestReplaceVariables(t *testing.T) {
 	tests := []struct {
 		name     string
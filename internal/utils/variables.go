@@ -0,0 +1,273 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/spf13/afero"
+
+	"github.com/prettymuchbryce/autotidy/internal/pathutil"
+)
+
+// maxCounterWidth bounds the zero padding of ${counter:N}.
+const maxCounterWidth = 20
+
+// FileContext describes the file a template is expanded for.
+type FileContext struct {
+	Fs        afero.Fs // Reads the file for ${size}, ${mime} and ${hash}, defaults to the OS filesystem
+	Path      string
+	Locations []string          // Rule locations, for ${location} and ${relpath}
+	Counter   int               // Per-run sequence number, for ${counter}
+	Vars      map[string]string // Extra variables, like regex captures from filters
+}
+
+// location returns the most specific location containing the file,
+// falling back to the file's directory once it has moved outside of them.
+func (c FileContext) location() string {
+	best := ""
+	for _, loc := range c.Locations {
+		if pathutil.IsBelow(loc, c.Path) && len(loc) > len(best) {
+			best = loc
+		}
+	}
+	if best == "" {
+		return filepath.Dir(c.Path)
+	}
+	return best
+}
+
+// fileValues lazily computes the values of variables that read the file,
+// so a template referencing ${hash} twice only hashes the file once.
+type fileValues struct {
+	ctx  FileContext
+	fs   afero.Fs
+	size *int64
+	mime string
+	hash string
+}
+
+func (v *fileValues) getSize() (int64, error) {
+	if v.size == nil {
+		info, err := v.fs.Stat(v.ctx.Path)
+		if err != nil {
+			return 0, err
+		}
+		size := info.Size()
+		v.size = &size
+	}
+	return *v.size, nil
+}
+
+func (v *fileValues) getMime() (string, error) {
+	if v.mime == "" {
+		f, err := v.fs.Open(v.ctx.Path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+
+		detected, err := mimetype.DetectReader(f)
+		if err != nil {
+			return "", err
+		}
+		// Drop parameters like "; charset=utf-8"
+		v.mime, _, _ = strings.Cut(detected.String(), ";")
+	}
+	return v.mime, nil
+}
+
+func (v *fileValues) getHash() (string, error) {
+	if v.hash == "" {
+		f, err := v.fs.Open(v.ctx.Path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+		v.hash = hex.EncodeToString(h.Sum(nil))
+	}
+	return v.hash, nil
+}
+
+// value returns the value of a single variable, and false if it is not
+// one of the file variables.
+func (v *fileValues) value(name, arg string) (string, bool, error) {
+	path := v.ctx.Path
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+
+	switch name {
+	case "path":
+		return path, true, nil
+	case "name":
+		return strings.TrimSuffix(base, ext), true, nil
+	case "ext":
+		return ext, true, nil
+	case "parent":
+		return filepath.Base(filepath.Dir(path)), true, nil
+	case "location":
+		return v.ctx.location(), true, nil
+	case "relpath":
+		rel, err := filepath.Rel(v.ctx.location(), filepath.Dir(path))
+		return rel, true, err
+	case "size":
+		size, err := v.getSize()
+		return strconv.FormatInt(size, 10), true, err
+	case "size_human":
+		size, err := v.getSize()
+		return FormatSize(size), true, err
+	case "mime":
+		mime, err := v.getMime()
+		return mime, true, err
+	case "mime_major":
+		mime, err := v.getMime()
+		major, _, _ := strings.Cut(mime, "/")
+		return major, true, err
+	case "hash":
+		hash, err := v.getHash()
+		if err != nil || arg == "" {
+			return hash, true, err
+		}
+		if n, err := strconv.Atoi(arg); err == nil && n > 0 && n < len(hash) {
+			hash = hash[:n]
+		}
+		return hash, true, nil
+	case "counter":
+		width, _ := strconv.Atoi(arg)
+		return fmt.Sprintf("%0*d", width, v.ctx.Counter), true, nil
+	case "env":
+		val, ok := os.LookupEnv(arg)
+		if !ok {
+			return "", true, fmt.Errorf("environment variable %s is not set", arg)
+		}
+		return val, true, nil
+	}
+	return "", false, nil
+}
+
+// Expand replaces file variables like ${name}, ${parent}, ${size} and
+// ${hash:8}, ${counter}, ${env:VAR} and the variables in ctx.Vars with their
+// values for the file. Other variables are left unchanged for later expansion.
+func (t Template) Expand(ctx FileContext) (Template, error) {
+	fs := ctx.Fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+	values := &fileValues{ctx: ctx, fs: fs}
+
+	return expandVariables(t, func(ref string) (string, bool, error) {
+		if val, ok := ctx.Vars[ref]; ok {
+			return val, true, nil
+		}
+		name, arg, _ := strings.Cut(ref, ":")
+		return values.value(name, arg)
+	})
+}
+
+// Validate returns an error if the template references a variable that is
+// neither a file variable nor one of extra, or uses an invalid argument or pipe.
+func (t Template) Validate(extra ...string) error {
+	for _, m := range variablePattern.FindAllStringSubmatch(string(t), -1) {
+		err := validateExpression(m[1], extra)
+		if err != nil {
+			return fmt.Errorf("%w in template %q", err, string(t))
+		}
+	}
+	return nil
+}
+
+// validateExpression checks a variable reference and its pipes.
+func validateExpression(expr string, extra []string) error {
+	ref, pipes, err := parseExpression(expr)
+	if err != nil {
+		return err
+	}
+	if err := validateVariable(ref, extra); err != nil {
+		return err
+	}
+	// Pipes are pure, so running them reports unknown names and bad arguments
+	if _, err := applyPipes("", pipes); err != nil {
+		return fmt.Errorf("${%s}: %w", expr, err)
+	}
+	return nil
+}
+
+// plainVariables are the file variables that take no argument.
+var plainVariables = []string{"path", "name", "ext", "parent", "location", "relpath", "size", "size_human", "mime", "mime_major"}
+
+// IsFileVariable reports whether name is a built-in variable, like name or hash.
+func IsFileVariable(name string) bool {
+	switch name {
+	case "hash", "counter", "env":
+		return true
+	}
+	return slices.Contains(plainVariables, name)
+}
+
+// validateVariable checks a single variable reference, without the ${}.
+func validateVariable(ref string, extra []string) error {
+	name, arg, hasArg := strings.Cut(ref, ":")
+	switch {
+	case slices.Contains(plainVariables, name):
+		if hasArg {
+			return fmt.Errorf("variable ${%s} does not take an argument", name)
+		}
+		return nil
+	case name == "hash":
+		if !hasArg {
+			return nil
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > sha256.Size*2 {
+			return fmt.Errorf("invalid ${%s}: length must be between 1 and %d", ref, sha256.Size*2)
+		}
+		return nil
+	case name == "counter":
+		if !hasArg {
+			return nil
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > maxCounterWidth {
+			return fmt.Errorf("invalid ${%s}: width must be between 1 and %d", ref, maxCounterWidth)
+		}
+		return nil
+	case name == "env":
+		if arg == "" {
+			return fmt.Errorf("invalid ${%s}: expected an environment variable name, e.g. ${env:HOME}", ref)
+		}
+		return nil
+	}
+
+	for _, e := range extra {
+		if name == e && !hasArg {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown variable ${%s}", ref)
+}
+
+// FormatSize formats a byte count with a binary unit, e.g. "1.5MB".
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "KMGTP"[exp])
+}
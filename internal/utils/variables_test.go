@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prettymuchbryce/autotidy/internal/testutil"
+
+	"github.com/spf13/afero"
+)
+
+func TestTemplate_Expand(t *testing.T) {
+	docs := testutil.Path("/", "home", "user", "docs")
+	path := testutil.Path(docs, "work", "2024", "report.pdf")
+	t.Setenv("AUTOTIDY_TEST_DEST", "archive")
+
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, path, []byte("%PDF-1.4 hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		template Template
+		ctx      FileContext
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "name and ext",
+			template: Template("${name}_copy${ext}"),
+			expected: "report_copy.pdf",
+		},
+		{
+			name:     "path",
+			template: Template("${path}"),
+			expected: path,
+		},
+		{
+			name:     "parent",
+			template: Template("${parent}"),
+			expected: "2024",
+		},
+		{
+			name:     "location and relpath",
+			template: Template("${location}|${relpath}"),
+			ctx:      FileContext{Locations: []string{docs}},
+			expected: docs + "|" + testutil.Path("work", "2024"),
+		},
+		{
+			name:     "most specific location",
+			template: Template("${relpath}"),
+			ctx:      FileContext{Locations: []string{docs, testutil.Path(docs, "work")}},
+			expected: "2024",
+		},
+		{
+			name:     "file outside locations",
+			template: Template("${location}|${relpath}"),
+			ctx:      FileContext{Locations: []string{testutil.Path("/", "other")}},
+			expected: testutil.Path(docs, "work", "2024") + "|.",
+		},
+		{
+			name:     "size",
+			template: Template("${size} ${size_human}"),
+			expected: "20 20B",
+		},
+		{
+			name:     "mime",
+			template: Template("${mime_major}/${mime}"),
+			expected: "application/application/pdf",
+		},
+		{
+			name:     "short hash",
+			template: Template("${hash:8}"),
+			expected: "bbc3a056",
+		},
+		{
+			name:     "counter",
+			template: Template("${counter}-${counter:3}"),
+			ctx:      FileContext{Counter: 7},
+			expected: "7-007",
+		},
+		{
+			name:     "environment variable",
+			template: Template("${env:AUTOTIDY_TEST_DEST}"),
+			expected: "archive",
+		},
+		{
+			name:     "unset environment variable",
+			template: Template("${env:AUTOTIDY_TEST_UNSET}"),
+			wantErr:  true,
+		},
+		{
+			name:     "other variables unchanged",
+			template: Template("${client}/${name}"),
+			expected: "${client}/report",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			ctx.Fs = fs
+			ctx.Path = path
+
+			result, err := tt.template.Expand(ctx)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.String() != tt.expected {
+				t.Errorf("result = %q, want %q", result.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestTemplate_Expand_MissingFile(t *testing.T) {
+	ctx := FileContext{Fs: afero.NewMemMapFs(), Path: testutil.Path("/", "missing.txt")}
+
+	// Variables derived from the path don't read the file
+	result, err := Template("${name}").Expand(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.String() != "missing" {
+		t.Errorf("result = %q, want %q", result.String(), "missing")
+	}
+
+	if _, err := Template("${size}").Expand(ctx); err == nil {
+		t.Error("expected error for ${size} of a missing file")
+	}
+}
+
+func TestTemplate_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    Template
+		extra       []string
+		errContains string
+	}{
+		{name: "no variables", template: Template("~/Archive/%Y")},
+		{name: "file variables", template: Template("${location}/${relpath}/${parent}/${name}${ext}")},
+		{name: "content variables", template: Template("${size}${size_human}${mime}${mime_major}${hash}${hash:8}")},
+		{name: "counter", template: Template("${counter}${counter:4}")},
+		{name: "environment variable", template: Template("${env:HOME}/Archive")},
+		{name: "extra variable", template: Template("${client}"), extra: []string{"client"}},
+		{name: "unknown variable", template: Template("~/${nmae}"), errContains: "unknown variable ${nmae}"},
+		{name: "argument on plain variable", template: Template("${name:3}"), errContains: "does not take an argument"},
+		{name: "hash length too long", template: Template("${hash:65}"), errContains: "length must be between 1 and 64"},
+		{name: "hash length not a number", template: Template("${hash:x}"), errContains: "length must be between"},
+		{name: "counter width zero", template: Template("${counter:0}"), errContains: "width must be between"},
+		{name: "env without name", template: Template("${env:}"), errContains: "environment variable name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.template.Validate(tt.extra...)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", tt.errContains)
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %q, want it to contain %q", err.Error(), tt.errContains)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1.0KB"},
+		{1536, "1.5KB"},
+		{5 * 1024 * 1024, "5.0MB"},
+		{3 * 1024 * 1024 * 1024, "3.0GB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatSize(tt.size); got != tt.expected {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.size, got, tt.expected)
+		}
+	}
+}
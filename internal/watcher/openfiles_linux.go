@@ -0,0 +1,97 @@
+//go:build linux
+
+package watcher
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// procOpenFileFinder finds open files by scanning /proc/*/fd. Only processes
+// whose /proc entries are readable are seen, which covers the user's own
+// processes like browsers.
+type procOpenFileFinder struct {
+	procDir string
+
+	// fds maps each open path to the /proc/<pid>/fdinfo/<fd> files of the
+	// descriptors referring to it.
+	fds map[string][]string
+}
+
+// newOpenFileFinder returns the open file finder for Linux.
+func newOpenFileFinder() openFileFinder {
+	return &procOpenFileFinder{procDir: "/proc"}
+}
+
+// Refresh records the targets of every readable file descriptor. The access
+// mode is only read later for the files that are checked, since most open
+// files aren't in a rule's locations.
+func (f *procOpenFileFinder) Refresh() {
+	f.fds = make(map[string][]string)
+
+	procs, err := os.ReadDir(f.procDir)
+	if err != nil {
+		return
+	}
+	for _, proc := range procs {
+		if _, err := strconv.Atoi(proc.Name()); err != nil {
+			continue
+		}
+		fdDir := filepath.Join(f.procDir, proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !filepath.IsAbs(target) {
+				continue
+			}
+			fdinfo := filepath.Join(f.procDir, proc.Name(), "fdinfo", fd.Name())
+			f.fds[target] = append(f.fds[target], fdinfo)
+		}
+	}
+}
+
+// OpenForWriting reports whether any descriptor found by the last Refresh
+// refers to path and was opened for writing.
+func (f *procOpenFileFinder) OpenForWriting(path string) bool {
+	for _, fdinfo := range f.fds[path] {
+		flags, ok := readFdFlags(fdinfo)
+		if !ok {
+			continue
+		}
+		if mode := flags & syscall.O_ACCMODE; mode == syscall.O_WRONLY || mode == syscall.O_RDWR {
+			return true
+		}
+	}
+	return false
+}
+
+// readFdFlags reads the open flags from a /proc/<pid>/fdinfo/<fd> file,
+// where they are stored in octal, e.g. "flags:	0100001".
+func readFdFlags(fdinfo string) (int, bool) {
+	file, err := os.Open(fdinfo)
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "flags:")
+		if !ok {
+			continue
+		}
+		flags, err := strconv.ParseInt(strings.TrimSpace(value), 8, 64)
+		if err != nil {
+			return 0, false
+		}
+		return int(flags), true
+	}
+	return 0, false
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcOpenFileFinder_OpenForWriting(t *testing.T) {
+	dir := t.TempDir()
+	writing := filepath.Join(dir, "writing.bin")
+	reading := filepath.Join(dir, "reading.bin")
+	closed := filepath.Join(dir, "closed.bin")
+	for _, path := range []string{reading, closed} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := os.Create(writing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	r, err := os.Open(reading)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	finder := newOpenFileFinder()
+	finder.Refresh()
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{writing, true},
+		{reading, false},
+		{closed, false},
+	}
+	for _, tt := range tests {
+		if got := finder.OpenForWriting(tt.path); got != tt.expected {
+			t.Errorf("OpenForWriting(%s) = %v, want %v", filepath.Base(tt.path), got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package watcher
+
+// noOpenFileFinder is used where open files can't be listed. Stability is
+// then only based on the size and modification time.
+type noOpenFileFinder struct{}
+
+// newOpenFileFinder returns an open file finder that never finds any.
+func newOpenFileFinder() openFileFinder {
+	return noOpenFileFinder{}
+}
+
+func (noOpenFileFinder) Refresh() {}
+
+func (noOpenFileFinder) OpenForWriting(path string) bool {
+	return false
+}
@@ -0,0 +1,235 @@
+package watcher
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/afero"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+)
+
+// pollResolution is how often the poller checks which directories are due.
+const pollResolution = 500 * time.Millisecond
+
+// pollWatcher watches directories by periodically listing them, for network
+// and FUSE mounts where fsnotify delivers no events. Changes between two
+// listings are sent as synthetic fsnotify events, so the rest of the
+// pipeline can't tell the two backends apart.
+type pollWatcher struct {
+	fs fs.FileSystem
+
+	// Events receives the synthetic events.
+	Events chan fsnotify.Event
+
+	mu   sync.Mutex
+	dirs map[string]*polledDir
+
+	// now returns the current time, replaceable in tests.
+	now func() time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// polledDir is a directory being polled.
+type polledDir struct {
+	interval time.Duration
+	next     time.Time
+
+	// entries is the directory's listing when it was last polled.
+	entries map[string]polledEntry
+}
+
+// polledEntry is the part of a directory entry compared between listings.
+type polledEntry struct {
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// newPollWatcher creates a pollWatcher. Polling starts with Run.
+func newPollWatcher(filesystem fs.FileSystem) *pollWatcher {
+	return &pollWatcher{
+		fs:     filesystem,
+		Events: make(chan fsnotify.Event, 100),
+		dirs:   make(map[string]*polledDir),
+		now:    time.Now,
+		done:   make(chan struct{}),
+	}
+}
+
+// Add starts polling the directory at path every interval. Its current
+// entries are listed right away, so only later changes produce events.
+func (p *pollWatcher) Add(path string, interval time.Duration) error {
+	entries, err := p.list(path)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dirs[path] = &polledDir{
+		interval: interval,
+		next:     p.now().Add(interval),
+		entries:  entries,
+	}
+	return nil
+}
+
+// Remove stops polling the directory at path.
+func (p *pollWatcher) Remove(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.dirs, path)
+	return nil
+}
+
+// Run polls the directories that are due until Close is called.
+func (p *pollWatcher) Run() {
+	ticker := time.NewTicker(pollResolution)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			for _, event := range p.poll() {
+				select {
+				case p.Events <- event:
+				case <-p.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Close stops polling.
+func (p *pollWatcher) Close() {
+	p.closeOnce.Do(func() { close(p.done) })
+}
+
+// poll lists the directories that are due, and returns the events for the
+// changes since their last listing. The lock isn't held while listing, since
+// that can be slow on network mounts.
+func (p *pollWatcher) poll() []fsnotify.Event {
+	now := p.now()
+	var due []string
+	p.mu.Lock()
+	for path, dir := range p.dirs {
+		if !now.Before(dir.next) {
+			due = append(due, path)
+		}
+	}
+	p.mu.Unlock()
+
+	var events []fsnotify.Event
+	for _, path := range due {
+		entries, err := p.list(path)
+
+		p.mu.Lock()
+		dir, ok := p.dirs[path]
+		if !ok {
+			// Removed while it was being listed
+			p.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// Like fsnotify, report the directory itself as removed and stop watching it
+				delete(p.dirs, path)
+				events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+			} else {
+				slog.Warn("failed to poll directory", "path", path, "error", err)
+				dir.next = now.Add(dir.interval)
+			}
+			p.mu.Unlock()
+			continue
+		}
+		events = append(events, diffListings(path, dir.entries, entries)...)
+		dir.entries = entries
+		dir.next = now.Add(dir.interval)
+		p.mu.Unlock()
+	}
+	return events
+}
+
+// list reads the entries of the directory at path.
+func (p *pollWatcher) list(path string) (map[string]polledEntry, error) {
+	infos, err := afero.ReadDir(p.fs, path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]polledEntry, len(infos))
+	for _, info := range infos {
+		entries[info.Name()] = polledEntry{size: info.Size(), modTime: info.ModTime(), isDir: info.IsDir()}
+	}
+	return entries, nil
+}
+
+// diffListings returns the events turning the old listing of the directory
+// at path into the new one. An entry replaced by one of a different type is
+// reported as removed and created again.
+func diffListings(path string, old, new map[string]polledEntry) []fsnotify.Event {
+	var events []fsnotify.Event
+	for name, before := range old {
+		after, ok := new[name]
+		switch {
+		case !ok:
+			events = append(events, fsnotify.Event{Name: filepath.Join(path, name), Op: fsnotify.Remove})
+		case after.isDir != before.isDir:
+			events = append(events,
+				fsnotify.Event{Name: filepath.Join(path, name), Op: fsnotify.Remove},
+				fsnotify.Event{Name: filepath.Join(path, name), Op: fsnotify.Create})
+		case !after.isDir && (after.size != before.size || !after.modTime.Equal(before.modTime)):
+			events = append(events, fsnotify.Event{Name: filepath.Join(path, name), Op: fsnotify.Write})
+		}
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			events = append(events, fsnotify.Event{Name: filepath.Join(path, name), Op: fsnotify.Create})
+		}
+	}
+	return events
+}
+
+// watchRouter sends each directory's watch to the poller or to fsnotify,
+// depending on whether the directory is polled. It implements fsnotifyWatcher.
+type watchRouter struct {
+	events fsnotifyWatcher
+	poller *pollWatcher
+
+	// pollInterval returns how often the directory at path is polled, or 0
+	// to use fsnotify.
+	pollInterval func(path string) time.Duration
+
+	// polled records the directories added to the poller.
+	polled map[string]bool
+}
+
+// Add watches the directory at path with the backend it needs.
+func (r *watchRouter) Add(path string) error {
+	if interval := r.pollInterval(path); interval > 0 {
+		if err := r.poller.Add(path, interval); err != nil {
+			return err
+		}
+		r.polled[path] = true
+		return nil
+	}
+	return r.events.Add(path)
+}
+
+// Remove stops watching the directory at path.
+func (r *watchRouter) Remove(path string) error {
+	if r.polled[path] {
+		delete(r.polled, path)
+		return r.poller.Remove(path)
+	}
+	return r.events.Remove(path)
+}
@@ -0,0 +1,252 @@
+package watcher
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/afero"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+)
+
+// newTestPollWatcher creates a pollWatcher with an in-memory fs and a clock
+// that only moves when advanced.
+func newTestPollWatcher(t *testing.T) (*pollWatcher, *fs.MemFileSystem, *time.Time) {
+	t.Helper()
+	memFs := fs.NewMemTest()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := newPollWatcher(memFs)
+	p.now = func() time.Time { return now }
+	return p, memFs, &now
+}
+
+// eventStrings formats events as sorted "OP path" strings for comparison.
+func eventStrings(events []fsnotify.Event) []string {
+	var result []string
+	for _, e := range events {
+		result = append(result, e.Op.String()+" "+e.Name)
+	}
+	slices.Sort(result)
+	return result
+}
+
+func TestDiffListings(t *testing.T) {
+	dir := testPath("scans")
+	modTime := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	old := map[string]polledEntry{
+		"kept.pdf":    {size: 10, modTime: modTime},
+		"grown.pdf":   {size: 10, modTime: modTime},
+		"touched.pdf": {size: 10, modTime: modTime},
+		"removed.pdf": {size: 10, modTime: modTime},
+		"subdir":      {isDir: true, modTime: modTime},
+		"replaced":    {size: 10, modTime: modTime},
+	}
+	new := map[string]polledEntry{
+		"kept.pdf":    {size: 10, modTime: modTime},
+		"grown.pdf":   {size: 20, modTime: modTime},
+		"touched.pdf": {size: 10, modTime: modTime.Add(time.Second)},
+		"subdir":      {isDir: true, modTime: modTime.Add(time.Second)},
+		"replaced":    {isDir: true, modTime: modTime},
+		"created.pdf": {size: 10, modTime: modTime},
+	}
+
+	expected := []string{
+		"CREATE " + testPath("scans", "created.pdf"),
+		"CREATE " + testPath("scans", "replaced"),
+		"REMOVE " + testPath("scans", "removed.pdf"),
+		"REMOVE " + testPath("scans", "replaced"),
+		"WRITE " + testPath("scans", "grown.pdf"),
+		"WRITE " + testPath("scans", "touched.pdf"),
+	}
+	slices.Sort(expected)
+
+	if got := eventStrings(diffListings(dir, old, new)); !slices.Equal(got, expected) {
+		t.Errorf("events =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(expected, "\n  "))
+	}
+}
+
+func TestPollWatcher_Poll(t *testing.T) {
+	p, memFs, now := newTestPollWatcher(t)
+	dir := testPath("scans")
+	existing := testPath("scans", "existing.pdf")
+	created := testPath("scans", "created.pdf")
+
+	memFs.MustMkdirAll(dir)
+	afero.WriteFile(memFs, existing, []byte("old"), 0644)
+	if err := p.Add(dir, 10*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Entries present when the directory was added produce no events
+	afero.WriteFile(memFs, created, []byte("new"), 0644)
+	*now = now.Add(5 * time.Second)
+	if events := p.poll(); len(events) != 0 {
+		t.Fatalf("expected no events before the interval passed, got %v", eventStrings(events))
+	}
+
+	*now = now.Add(5 * time.Second)
+	if got := eventStrings(p.poll()); !slices.Equal(got, []string{"CREATE " + created}) {
+		t.Fatalf("events = %v, want the created file", got)
+	}
+
+	// The next poll only reports later changes
+	*now = now.Add(10 * time.Second)
+	if events := p.poll(); len(events) != 0 {
+		t.Fatalf("expected no events without changes, got %v", eventStrings(events))
+	}
+}
+
+func TestPollWatcher_RemovedDirectory(t *testing.T) {
+	p, memFs, now := newTestPollWatcher(t)
+	dir := testPath("scans")
+
+	memFs.MustMkdirAll(dir)
+	if err := p.Add(dir, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	memFs.RemoveAll(dir)
+	*now = now.Add(time.Second)
+	if got := eventStrings(p.poll()); !slices.Equal(got, []string{"REMOVE " + dir}) {
+		t.Fatalf("events = %v, want the directory removed", got)
+	}
+	if _, ok := p.dirs[dir]; ok {
+		t.Error("expected removed directory to no longer be polled")
+	}
+}
+
+func TestPollWatcher_AddMissingDirectory(t *testing.T) {
+	p, _, _ := newTestPollWatcher(t)
+	if err := p.Add(testPath("missing"), time.Second); err == nil {
+		t.Error("expected error adding a missing directory")
+	}
+}
+
+func TestWatchRouter(t *testing.T) {
+	p, memFs, _ := newTestPollWatcher(t)
+	mock := newMockFsWatcher()
+	polledDir := testPath("mnt", "scans")
+	localDir := testPath("home", "downloads")
+	memFs.MustMkdirAll(polledDir)
+	memFs.MustMkdirAll(localDir)
+
+	router := &watchRouter{
+		events: mock,
+		poller: p,
+		pollInterval: func(path string) time.Duration {
+			if path == polledDir {
+				return time.Minute
+			}
+			return 0
+		},
+		polled: make(map[string]bool),
+	}
+
+	router.Add(polledDir)
+	router.Add(localDir)
+	if _, ok := p.dirs[polledDir]; !ok || mock.hasAdded(polledDir) {
+		t.Errorf("expected %s to be polled only", polledDir)
+	}
+	if _, ok := p.dirs[localDir]; ok || !mock.hasAdded(localDir) {
+		t.Errorf("expected %s to use fsnotify only", localDir)
+	}
+
+	router.Remove(polledDir)
+	router.Remove(localDir)
+	if _, ok := p.dirs[polledDir]; ok || mock.hasRemoved(polledDir) {
+		t.Errorf("expected %s to be removed from the poller only", polledDir)
+	}
+	if !mock.hasRemoved(localDir) {
+		t.Errorf("expected %s to be removed from fsnotify", localDir)
+	}
+}
+
+func TestWatcher_PollInterval(t *testing.T) {
+	polledRule := func(location string, interval time.Duration) *rules.Rule {
+		recursive := true
+		return &rules.Rule{
+			Locations: rules.StringList{location},
+			Recursive: &recursive,
+			Watch:     &rules.Watch{Mode: rules.WatchPoll, Interval: interval},
+		}
+	}
+	eventsRule := func(location string) *rules.Rule {
+		recursive := true
+		return &rules.Rule{Locations: rules.StringList{location}, Recursive: &recursive}
+	}
+
+	tests := []struct {
+		name      string
+		rules     []*rules.Rule
+		pollPaths []string
+		path      string
+		expected  time.Duration
+	}{
+		{
+			name:     "events by default",
+			rules:    []*rules.Rule{eventsRule(testPath("home"))},
+			path:     testPath("home", "sub"),
+			expected: 0,
+		},
+		{
+			name:     "polled rule location and below",
+			rules:    []*rules.Rule{polledRule(testPath("mnt", "scans"), time.Minute)},
+			path:     testPath("mnt", "scans", "sub"),
+			expected: time.Minute,
+		},
+		{
+			name:     "polled rule uses daemon default",
+			rules:    []*rules.Rule{polledRule(testPath("mnt", "scans"), 0)},
+			path:     testPath("mnt", "scans"),
+			expected: 10 * time.Second,
+		},
+		{
+			name:     "shortest interval wins",
+			rules:    []*rules.Rule{polledRule(testPath("mnt"), time.Minute), polledRule(testPath("mnt", "scans"), 30*time.Second)},
+			path:     testPath("mnt", "scans"),
+			expected: 30 * time.Second,
+		},
+		{
+			name:      "poll paths",
+			rules:     []*rules.Rule{eventsRule(testPath("mnt", "scans"))},
+			pollPaths: []string{testPath("mnt")},
+			path:      testPath("mnt", "scans"),
+			expected:  10 * time.Second,
+		},
+		{
+			name:     "ancestor of polled location",
+			rules:    []*rules.Rule{polledRule(testPath("mnt", "scans"), time.Minute)},
+			path:     testPath("mnt"),
+			expected: time.Minute,
+		},
+		{
+			name:     "ancestor watched for events",
+			rules:    []*rules.Rule{polledRule(testPath("home", "share"), time.Minute), eventsRule(testPath("home"))},
+			path:     testPath("home"),
+			expected: 0,
+		},
+		{
+			name:     "sibling of polled location",
+			rules:    []*rules.Rule{polledRule(testPath("mnt", "scans"), time.Minute)},
+			path:     testPath("mnt", "scans2"),
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Watcher{defaultPollInterval: 10 * time.Second, pollPaths: tt.pollPaths}
+			for _, rule := range tt.rules {
+				w.runners = append(w.runners, rules.NewRuleRunner(rule, fs.NewMem(), nil))
+			}
+			if got := w.pollInterval(tt.path); got != tt.expected {
+				t.Errorf("pollInterval(%s) = %s, want %s", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package watcher
+
+import (
+	"time"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+)
+
+// stabilityTracker holds files back from rules with wait_until_stable until
+// they have stopped changing, e.g. while a browser is still downloading them.
+// It is only used from the watcher's event loop, so it needs no locking.
+type stabilityTracker struct {
+	fs fs.FileSystem
+
+	// files stores the last observed state of each file that is not yet
+	// stable, indexed by path. Stable files are forgotten.
+	files map[string]*fileObservation
+
+	// openFiles finds files open for writing by other processes.
+	openFiles openFileFinder
+
+	// now returns the current time, replaceable in tests.
+	now func() time.Time
+}
+
+// fileObservation is the state of a file when it was last checked.
+type fileObservation struct {
+	size    int64
+	modTime time.Time
+
+	// since is when the size and modification time were first observed with
+	// their current values.
+	since time.Time
+}
+
+// openFileFinder reports whether files are open for writing by any process.
+// Refresh takes a new snapshot of open files, which OpenForWriting consults.
+type openFileFinder interface {
+	Refresh()
+	OpenForWriting(path string) bool
+}
+
+// newStabilityTracker creates a stabilityTracker using the platform's way of
+// finding open files.
+func newStabilityTracker(filesystem fs.FileSystem) *stabilityTracker {
+	return &stabilityTracker{
+		fs:        filesystem,
+		files:     make(map[string]*fileObservation),
+		openFiles: newOpenFileFinder(),
+		now:       time.Now,
+	}
+}
+
+// StartRun prepares for checking files in a rule run: it refreshes the open
+// files, and forgets files that no longer exist, e.g. a browser's partial
+// download after it was renamed.
+func (t *stabilityTracker) StartRun() {
+	t.openFiles.Refresh()
+	for path := range t.files {
+		if _, err := t.fs.Stat(path); err != nil {
+			delete(t.files, path)
+		}
+	}
+}
+
+// Check reports whether the file at path has kept the same size and
+// modification time for at least wait, and is not open for writing. If it is
+// not stable yet, retry is how long to wait before checking again.
+// A file seen for the first time counts as unchanged since its modification
+// time. Directories and files that can't be read are always stable, leaving
+// them to the rule.
+func (t *stabilityTracker) Check(path string, wait time.Duration) (stable bool, retry time.Duration) {
+	info, err := t.fs.Stat(path)
+	if err != nil || info.IsDir() {
+		delete(t.files, path)
+		return true, 0
+	}
+
+	now := t.now()
+	obs, seen := t.files[path]
+	switch {
+	case !seen:
+		if now.Sub(info.ModTime()) >= wait && !t.openFiles.OpenForWriting(path) {
+			return true, 0
+		}
+		t.files[path] = &fileObservation{size: info.Size(), modTime: info.ModTime(), since: now}
+		return false, wait
+
+	case obs.size != info.Size() || !obs.modTime.Equal(info.ModTime()):
+		obs.size = info.Size()
+		obs.modTime = info.ModTime()
+		obs.since = now
+		return false, wait
+	}
+
+	if remaining := wait - now.Sub(obs.since); remaining > 0 {
+		return false, remaining
+	}
+	if t.openFiles.OpenForWriting(path) {
+		return false, wait
+	}
+
+	delete(t.files, path)
+	return true, 0
+}
@@ -0,0 +1,166 @@
+package watcher
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+)
+
+// fakeOpenFileFinder reports a fixed set of files as open for writing.
+type fakeOpenFileFinder struct {
+	open      map[string]bool
+	refreshes int
+}
+
+func (f *fakeOpenFileFinder) Refresh() {
+	f.refreshes++
+}
+
+func (f *fakeOpenFileFinder) OpenForWriting(path string) bool {
+	return f.open[path]
+}
+
+// newTestStabilityTracker creates a stabilityTracker with an in-memory fs and
+// a clock that only moves when advanced.
+func newTestStabilityTracker(t *testing.T) (*stabilityTracker, *fs.MemFileSystem, *fakeOpenFileFinder, *time.Time) {
+	t.Helper()
+	memFs := fs.NewMemTest()
+	openFiles := &fakeOpenFileFinder{open: map[string]bool{}}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tracker := &stabilityTracker{
+		fs:        memFs,
+		files:     make(map[string]*fileObservation),
+		openFiles: openFiles,
+		now:       func() time.Time { return now },
+	}
+	return tracker, memFs, openFiles, &now
+}
+
+// writeFileAt writes content to path with the given modification time.
+func writeFileAt(t *testing.T, memFs *fs.MemFileSystem, path string, content string, modTime time.Time) {
+	t.Helper()
+	if err := afero.WriteFile(memFs, path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := memFs.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStabilityTracker_OldFileIsStable(t *testing.T) {
+	tracker, memFs, _, now := newTestStabilityTracker(t)
+	path := testPath("downloads", "report.pdf")
+	writeFileAt(t, memFs, path, "done", now.Add(-time.Minute))
+
+	if stable, _ := tracker.Check(path, 5*time.Second); !stable {
+		t.Error("expected file unchanged for longer than the wait to be stable")
+	}
+}
+
+func TestStabilityTracker_GrowingFileIsHeld(t *testing.T) {
+	tracker, memFs, _, now := newTestStabilityTracker(t)
+	path := testPath("downloads", "video.mp4")
+	wait := 5 * time.Second
+
+	writeFileAt(t, memFs, path, "part", *now)
+	stable, retry := tracker.Check(path, wait)
+	if stable {
+		t.Fatal("expected new file to be held")
+	}
+	if retry != wait {
+		t.Errorf("retry = %v, want %v", retry, wait)
+	}
+
+	// Still growing: the wait starts over
+	*now = now.Add(3 * time.Second)
+	writeFileAt(t, memFs, path, "partial", *now)
+	if stable, retry := tracker.Check(path, wait); stable || retry != wait {
+		t.Errorf("Check() = %v, %v, want false, %v", stable, retry, wait)
+	}
+
+	// Unchanged, but not for long enough yet
+	*now = now.Add(2 * time.Second)
+	if stable, retry := tracker.Check(path, wait); stable || retry != 3*time.Second {
+		t.Errorf("Check() = %v, %v, want false, 3s", stable, retry)
+	}
+
+	// Unchanged for the whole wait
+	*now = now.Add(3 * time.Second)
+	if stable, _ := tracker.Check(path, wait); !stable {
+		t.Error("expected file to be stable")
+	}
+	if _, ok := tracker.files[path]; ok {
+		t.Error("expected stable file to be forgotten")
+	}
+}
+
+func TestStabilityTracker_SizeChangeWithSameModTime(t *testing.T) {
+	tracker, memFs, _, now := newTestStabilityTracker(t)
+	path := testPath("downloads", "copy.iso")
+	modTime := now.Add(-time.Second)
+	wait := 5 * time.Second
+
+	writeFileAt(t, memFs, path, "a", modTime)
+	tracker.Check(path, wait)
+
+	// Some copy tools set the final modification time before writing everything
+	*now = now.Add(10 * time.Second)
+	writeFileAt(t, memFs, path, "abc", modTime)
+	if stable, _ := tracker.Check(path, wait); stable {
+		t.Error("expected file whose size changed to be held")
+	}
+}
+
+func TestStabilityTracker_OpenForWritingIsHeld(t *testing.T) {
+	tracker, memFs, openFiles, now := newTestStabilityTracker(t)
+	path := testPath("downloads", "data.db")
+	writeFileAt(t, memFs, path, "data", now.Add(-time.Hour))
+	openFiles.open[path] = true
+
+	if stable, _ := tracker.Check(path, 5*time.Second); stable {
+		t.Error("expected file open for writing to be held")
+	}
+
+	openFiles.open[path] = false
+	*now = now.Add(5 * time.Second)
+	if stable, _ := tracker.Check(path, 5*time.Second); !stable {
+		t.Error("expected file to be stable once closed")
+	}
+}
+
+func TestStabilityTracker_DirectoriesAndMissingFilesAreStable(t *testing.T) {
+	tracker, memFs, _, _ := newTestStabilityTracker(t)
+	dir := testPath("downloads", "folder")
+	memFs.MustMkdirAll(dir)
+
+	if stable, _ := tracker.Check(dir, 5*time.Second); !stable {
+		t.Error("expected directory to be stable")
+	}
+	if stable, _ := tracker.Check(testPath("downloads", "missing"), 5*time.Second); !stable {
+		t.Error("expected missing file to be stable")
+	}
+}
+
+func TestStabilityTracker_StartRunForgetsRemovedFiles(t *testing.T) {
+	tracker, memFs, openFiles, now := newTestStabilityTracker(t)
+	partial := testPath("downloads", "video.mp4.crdownload")
+	writeFileAt(t, memFs, partial, "part", *now)
+	tracker.Check(partial, 5*time.Second)
+
+	// The browser renames the partial download when it completes
+	if err := memFs.Remove(partial); err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	tracker.StartRun()
+
+	if _, ok := tracker.files[partial]; ok {
+		t.Error("expected removed file to be forgotten")
+	}
+	if openFiles.refreshes != 1 {
+		t.Errorf("open files refreshed %d times, want 1", openFiles.refreshes)
+	}
+}
@@ -2,7 +2,9 @@ package watcher
 
 import (
 	"log/slog"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -50,6 +52,10 @@ type WatchedDirs struct {
 
 	// done is closed when the watcher is stopping, to unblock timer goroutines.
 	done chan struct{}
+
+	// skipSubdir reports whether a subdirectory of a recursive watch should not
+	// be watched, e.g. because every rule covering it excludes it. Nil watches all.
+	skipSubdir func(path string) bool
 }
 
 // NewWatchedDirs creates a new WatchedDirs manager.
@@ -73,6 +79,17 @@ func (w *WatchedDirs) Destroy() {
 	}
 }
 
+// SetSkipSubdir sets the function deciding which subdirectories of recursive
+// watches are skipped, along with everything below them.
+func (w *WatchedDirs) SetSkipSubdir(skip func(path string) bool) {
+	w.skipSubdir = skip
+}
+
+// watchesSubdir reports whether a subdirectory of a recursive watch should be watched.
+func (w *WatchedDirs) watchesSubdir(path string) bool {
+	return w.skipSubdir == nil || !w.skipSubdir(path)
+}
+
 // GetRecreatedRoots returns and clears the list of roots that were recreated.
 func (w *WatchedDirs) GetRecreatedRoots() []RecreatedRoot {
 	recreated := w.rootsRecreated
@@ -203,7 +220,7 @@ func (w *WatchedDirs) EvaluateDebounced(path string) {
 
 	slog.Debug("EvaluateDebounced: found new dirs", "path", we.path, "newDirs", newDirs)
 	for _, dir := range newDirs {
-		if we.isRecursive {
+		if we.isRecursive && w.watchesSubdir(dir) {
 			w.recursivelyAddSubdirectories(dir)
 		}
 
@@ -236,6 +253,44 @@ func (w *WatchedDirs) EvaluateDebounced(path string) {
 	}
 }
 
+// Rescan brings the watches up to date after events were lost, e.g. when the
+// kernel's event queue overflowed. Watches of directories that no longer
+// exist are removed, subdirectories created under recursive roots are added,
+// and lost roots whose targets appeared are restored.
+func (w *WatchedDirs) Rescan() {
+	slog.Debug("Rescan called", "entries", len(w.entries))
+
+	// Paths are sorted so parents are handled before their children
+	for _, path := range slices.Sorted(maps.Keys(w.entries)) {
+		if we, exists := w.entries[path]; exists {
+			w.removeWatchEntryIfUnreachable(we)
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(w.entries)) {
+		we, exists := w.entries[path]
+		if !exists {
+			continue
+		}
+		if we.rootType == RecursiveRoot {
+			w.recursivelyAddSubdirectories(path)
+		}
+		if len(we.lostRoots) > 0 {
+			// Treat every subdirectory as just created
+			dirs, err := afero.ReadDir(w.fs, path)
+			if err != nil {
+				continue
+			}
+			for _, dir := range dirs {
+				if dir.IsDir() {
+					we.createDebouncedPaths[filepath.Join(path, dir.Name())] = struct{}{}
+				}
+			}
+			w.EvaluateDebounced(path)
+		}
+	}
+}
+
 // addLostRoot watches an ancestor path while waiting for targetPath to be created.
 // The attempted map tracks paths we've already tried to prevent infinite loops
 // when directories are rapidly created/deleted.
@@ -441,6 +496,10 @@ func (w *WatchedDirs) recursivelyAddSubdirectories(path string) {
 	for _, dir := range dirs {
 		if dir.IsDir() {
 			subdirPath := filepath.Join(path, dir.Name())
+			if !w.watchesSubdir(subdirPath) {
+				slog.Debug("skipping excluded subdirectory", "path", subdirPath)
+				continue
+			}
 			w.recursivelyAddSubdirectories(subdirPath)
 		}
 	}
@@ -217,6 +217,43 @@ func TestAddRoot_Recursive(t *testing.T) {
 	}
 }
 
+func TestAddRoot_Recursive_SkipsSubdirs(t *testing.T) {
+	wd, mock, memFs := newTestWatchedDirs(t)
+	root := testPath("a")
+	kept := testPath("a", "src")
+	skipped := testPath("a", "node_modules")
+	skippedChild := testPath("a", "node_modules", "pkg")
+
+	memFs.MustMkdirAll(kept)
+	memFs.MustMkdirAll(skippedChild)
+
+	wd.SetSkipSubdir(func(path string) bool {
+		return filepath.Base(path) == "node_modules"
+	})
+	wd.AddRoot(root, true)
+
+	for _, p := range []string{root, kept} {
+		if !mock.hasAdded(p) {
+			t.Errorf("expected %s to be added, got: %v", p, mock.added)
+		}
+	}
+	for _, p := range []string{skipped, skippedChild} {
+		if mock.hasAdded(p) {
+			t.Errorf("expected %s to be skipped, got: %v", p, mock.added)
+		}
+	}
+
+	// Skipped directories created later aren't watched either
+	created := testPath("a", "src", "node_modules")
+	memFs.MustMkdirAll(created)
+	wd.ProcessEvent(makeEvent(created, fsnotify.Create))
+	wd.EvaluateDebounced(kept)
+
+	if mock.hasAdded(created) {
+		t.Errorf("expected %s to be skipped, got: %v", created, mock.added)
+	}
+}
+
 func TestAddRoot_Idempotent(t *testing.T) {
 	wd, mock, memFs := newTestWatchedDirs(t)
 	path := testPath("a")
@@ -1242,3 +1279,46 @@ func TestRemove_RelocatesRoot(t *testing.T) {
 		t.Errorf("expected root %s to be watched after subdir removed, got: %v", root, mock.added)
 	}
 }
+
+func TestRescan_UpdatesRecursiveWatches(t *testing.T) {
+	wd, mock, memFs := newTestWatchedDirs(t)
+	root := testPath("a")
+	removed := testPath("a", "b")
+	created := testPath("a", "c", "d")
+
+	memFs.MustMkdirAll(removed)
+	wd.AddRoot(root, true)
+
+	// Changes whose events were lost
+	memFs.RemoveAll(removed)
+	memFs.MustMkdirAll(created)
+
+	wd.Rescan()
+
+	if _, exists := wd.entries[removed]; exists {
+		t.Errorf("expected watch of removed %s to be dropped", removed)
+	}
+	for _, p := range []string{testPath("a", "c"), created} {
+		if !mock.hasAdded(p) {
+			t.Errorf("expected %s to be added, got: %v", p, mock.added)
+		}
+	}
+}
+
+func TestRescan_RestoresLostRoot(t *testing.T) {
+	wd, mock, memFs := newTestWatchedDirs(t)
+	ancestor := testPath("a")
+	target := testPath("a", "b", "c")
+
+	memFs.MustMkdirAll(ancestor)
+	wd.AddRoot(target, false)
+
+	// The target was created, but the events were lost
+	memFs.MustMkdirAll(target)
+
+	wd.Rescan()
+
+	if !mock.hasAdded(target) {
+		t.Errorf("expected target %s to be added, got: %v", target, mock.added)
+	}
+}
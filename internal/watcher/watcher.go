@@ -2,12 +2,16 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 
+	"github.com/prettymuchbryce/autotidy/internal/config"
 	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/journal"
+	"github.com/prettymuchbryce/autotidy/internal/pathutil"
 	"github.com/prettymuchbryce/autotidy/internal/rules"
 	"github.com/prettymuchbryce/autotidy/internal/state"
 )
@@ -33,6 +37,32 @@ type Watcher struct {
 	watchDebounceChan   chan string
 	watchRootsRecreated chan bool
 
+	// Whether runs triggered by events only evaluate the paths that changed
+	incremental bool
+	// Time between full sweeps of every rule, 0 disables them
+	rescanInterval time.Duration
+	// Per-runner paths that changed since the runner last ran
+	runnerPending map[*rules.RuleRunner]map[string]struct{}
+	// Runners whose next run must be a full sweep of their locations
+	runnerFullSweep map[*rules.RuleRunner]bool
+
+	// Per-runner timers for rules with a schedule, firing into scheduleChan
+	scheduleTimers map[*rules.RuleRunner]*time.Timer
+	scheduleChan   chan *rules.RuleRunner
+
+	// Polls directories where fsnotify delivers no events, like network mounts
+	poller *pollWatcher
+	// Default poll interval, and directories polled for every rule
+	defaultPollInterval time.Duration
+	pollPaths           []string
+
+	// Holds files back from rules with wait_until_stable until they stop changing
+	stability *stabilityTracker
+	// Runners that hold files until they are stable
+	stabilityRunners map[*rules.RuleRunner]bool
+	// Per-runner delay until held files should be checked again, set during a run
+	runnerRetry map[*rules.RuleRunner]time.Duration
+
 	// Closed when the watcher is stopping to unblock goroutines
 	done chan struct{}
 }
@@ -46,7 +76,10 @@ type TimestampedEvent struct {
 // New creates a new Watcher for the given rules.
 // Disabled rules are filtered out automatically.
 // If st is provided, execution stats will be persisted after each rule run.
-func New(ruleList []rules.Rule, debounce time.Duration, st *state.State) (*Watcher, error) {
+// If jrnl is provided, every executed action is recorded so it can be undone.
+func New(ruleList []rules.Rule, daemonConfig config.DaemonConfig, st *state.State, jrnl *journal.Journal) (*Watcher, error) {
+	debounce := daemonConfig.Debounce
+
 	fsw, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
@@ -58,7 +91,9 @@ func New(ruleList []rules.Rule, debounce time.Duration, st *state.State) (*Watch
 	for i := range ruleList {
 		rule := &ruleList[i]
 		if rule.IsEnabled() {
-			runners = append(runners, rules.NewRuleRunner(rule, realFs, nil))
+			runner := rules.NewRuleRunner(rule, realFs, nil)
+			runner.SetJournal(jrnl)
+			runners = append(runners, runner)
 		} else {
 			slog.Info("skipping disabled rule", "rule", rule.Name)
 		}
@@ -68,6 +103,10 @@ func New(ruleList []rules.Rule, debounce time.Duration, st *state.State) (*Watch
 	var watchRootsRecreated = make(chan bool, 1)
 	var done = make(chan struct{})
 
+	// Watches go to fsnotify, or to the poller for polled directories
+	poller := newPollWatcher(realFs)
+	router := &watchRouter{events: fsw, poller: poller, polled: make(map[string]bool)}
+
 	w := &Watcher{
 		fsWatcher:           fsw,
 		runners:             runners,
@@ -77,15 +116,48 @@ func New(ruleList []rules.Rule, debounce time.Duration, st *state.State) (*Watch
 		runnerTimers:        make(map[*rules.RuleRunner]*time.Timer),
 		runnerDebounceChan:  make(chan *rules.RuleRunner),
 		eventChan:           make(chan TimestampedEvent, 100),
-		watchManager:        NewWatchedDirs(realFs, fsw, debounce, watchDebounceChan, watchRootsRecreated, done),
+		watchManager:        NewWatchedDirs(realFs, router, debounce, watchDebounceChan, watchRootsRecreated, done),
 		watchDebounceChan:   watchDebounceChan,
 		watchRootsRecreated: watchRootsRecreated,
+		incremental:         daemonConfig.Incremental,
+		rescanInterval:      daemonConfig.RescanInterval,
+		runnerPending:       make(map[*rules.RuleRunner]map[string]struct{}),
+		runnerFullSweep:     make(map[*rules.RuleRunner]bool),
+		scheduleTimers:      make(map[*rules.RuleRunner]*time.Timer),
+		scheduleChan:        make(chan *rules.RuleRunner),
+		poller:              poller,
+		defaultPollInterval: daemonConfig.PollInterval,
+		pollPaths:           daemonConfig.PollPaths,
+		stability:           newStabilityTracker(realFs),
+		stabilityRunners:    make(map[*rules.RuleRunner]bool),
+		runnerRetry:         make(map[*rules.RuleRunner]time.Duration),
 		done:                done,
 	}
 
 	// Initialize per-runner timers
 	w.initRunnerTimers()
 
+	// The first run of every rule is a full sweep, since changes made while
+	// the watcher wasn't running are unknown
+	for _, runner := range w.runners {
+		w.runnerFullSweep[runner] = true
+	}
+
+	// Hold files that are still changing for rules that wait for them to be stable
+	for _, runner := range w.runners {
+		if wait := runner.Rule().StabilityWait(daemonConfig.WaitUntilStable); wait > 0 {
+			w.stabilityRunners[runner] = true
+			runner.SetHold(w.holdUnstable(runner, wait))
+		}
+	}
+
+	router.pollInterval = w.pollInterval
+
+	// Don't watch subtrees that no rule traverses, e.g. excluded ones
+	w.watchManager.SetSkipSubdir(func(path string) bool {
+		return w.skipSubdir(realFs, path)
+	})
+
 	// Add watches for all root locations
 	w.runners.EachRuleLocation(func(rule *rules.Rule, loc string) bool {
 		w.watchManager.AddRoot(loc, rule.IsRecursive())
@@ -95,35 +167,54 @@ func New(ruleList []rules.Rule, debounce time.Duration, st *state.State) (*Watch
 	return w, nil
 }
 
-// eventLoop reads from fsnotify and timestamps events before forwarding.
+// eventLoop reads from fsnotify and the poller, and timestamps events before forwarding.
 func (w *Watcher) eventLoop(ctx context.Context) {
 	for {
+		var event fsnotify.Event
 		select {
 		case <-ctx.Done():
 			return
-		case event, ok := <-w.fsWatcher.Events:
+		case fsEvent, ok := <-w.fsWatcher.Events:
 			if !ok {
 				return
 			}
-			te := TimestampedEvent{
-				Event: event,
-				Time:  time.Now(),
-			}
-			select {
-			case w.eventChan <- te:
-			case <-w.done:
-				return
-			}
+			event = fsEvent
+		case event = <-w.poller.Events:
+		}
+
+		te := TimestampedEvent{
+			Event: event,
+			Time:  time.Now(),
+		}
+		select {
+		case w.eventChan <- te:
+		case <-w.done:
+			return
 		}
 	}
 }
 
 // Run starts the watcher and blocks until context is cancelled.
 func (w *Watcher) Run(ctx context.Context) error {
-	slog.Info("watcher started", "debounce", w.debounceDelay)
+	slog.Info("watcher started", "debounce", w.debounceDelay, "incremental", w.incremental, "rescanInterval", w.rescanInterval)
 
 	// Start event goroutine to timestamp incoming events
 	go w.eventLoop(ctx)
+	go w.poller.Run()
+
+	// Run every rule once, for changes made while the watcher wasn't running
+	w.scheduleFullSweeps()
+
+	// Run scheduled rules on time
+	w.startSchedules(time.Now())
+
+	// Periodically sweep all locations, for changes that produced no events
+	var rescanTick <-chan time.Time
+	if w.rescanInterval > 0 {
+		ticker := time.NewTicker(w.rescanInterval)
+		defer ticker.Stop()
+		rescanTick = ticker.C
+	}
 
 	for {
 		// Execute all rules with pending timers before returning.
@@ -156,7 +247,11 @@ func (w *Watcher) Run(ctx context.Context) error {
 			for _, timer := range w.runnerTimers {
 				timer.Stop()
 			}
+			for _, timer := range w.scheduleTimers {
+				timer.Stop()
+			}
 			w.watchManager.Destroy()
+			w.poller.Close()
 			return w.fsWatcher.Close()
 
 		case event := <-w.eventChan:
@@ -169,6 +264,10 @@ func (w *Watcher) Run(ctx context.Context) error {
 			if !ok {
 				return nil
 			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				w.handleOverflow()
+				continue
+			}
 			slog.Error("watcher error", "error", err)
 		case path := <-w.watchDebounceChan:
 			w.watchManager.EvaluateDebounced(path)
@@ -178,6 +277,10 @@ func (w *Watcher) Run(ctx context.Context) error {
 				slog.Info("watch root recreated", "path", root.Path)
 				w.scheduleRulesForPath(root.Path, root.Time)
 			}
+		case <-rescanTick:
+			w.scheduleFullSweeps()
+		case runner := <-w.scheduleChan:
+			w.runScheduled(runner, time.Now())
 		case runner := <-w.runnerDebounceChan:
 			w.executeRunner(runner)
 		}
@@ -200,29 +303,195 @@ func (w *Watcher) initRunnerTimers() {
 	}
 }
 
+// startSchedules starts the timers of rules with a schedule.
+func (w *Watcher) startSchedules(now time.Time) {
+	for _, runner := range w.runners {
+		rule := runner.Rule()
+		if rule.Schedule == nil {
+			continue
+		}
+
+		r := runner // capture for closure
+		next := w.nextScheduledRun(rule, now)
+		w.scheduleTimers[runner] = time.AfterFunc(next.Sub(now), func() {
+			select {
+			case w.scheduleChan <- r:
+			case <-w.done:
+			}
+		})
+		slog.Info("scheduled rule", "rule", rule.Name, "schedule", rule.Schedule.String(), "next", next)
+	}
+}
+
+// nextScheduledRun returns when a scheduled rule runs next. A next run
+// persisted before the daemon restarted is kept as long as the schedule is
+// unchanged, so restarts neither skip nor repeat runs. If it has already
+// passed, the rule runs right away.
+func (w *Watcher) nextScheduledRun(rule *rules.Rule, now time.Time) time.Time {
+	if w.state != nil {
+		if next, ok := w.state.NextRun(rule.Name, rule.Schedule.String()); ok {
+			return next
+		}
+	}
+	next := rule.Schedule.Next(now)
+	w.persistNextRun(rule, next)
+	return next
+}
+
+// runScheduled runs a scheduled rule as a full sweep, and schedules its next
+// run. The next run is persisted first, so a crash during the run doesn't
+// repeat it after a restart.
+func (w *Watcher) runScheduled(runner *rules.RuleRunner, now time.Time) {
+	rule := runner.Rule()
+	next := rule.Schedule.Next(now)
+	w.persistNextRun(rule, next)
+
+	slog.Info("running scheduled rule", "rule", rule.Name, "schedule", rule.Schedule.String())
+	w.runnerFullSweep[runner] = true
+	w.executeRunner(runner)
+
+	w.scheduleTimers[runner].Reset(time.Until(next))
+}
+
+// persistNextRun records the next run of a scheduled rule in the state.
+func (w *Watcher) persistNextRun(rule *rules.Rule, next time.Time) {
+	if w.state == nil {
+		return
+	}
+	if err := w.state.SetNextRun(rule.Name, rule.Schedule.String(), next); err != nil {
+		slog.Warn("failed to persist next scheduled run", "rule", rule.Name, "error", err)
+	}
+}
+
+// pollInterval returns how often the directory at path is polled, or 0 if it
+// is watched through fsnotify. Directories in poll_paths and those watched for
+// rules with watch: poll are polled, at the shortest interval that applies.
+// Ancestors watched while a polled location doesn't exist yet are polled too,
+// unless a rule relying on events watches them.
+func (w *Watcher) pollInterval(path string) time.Duration {
+	var interval, ancestorInterval time.Duration
+	shortest := func(current, d time.Duration) time.Duration {
+		if current == 0 || d < current {
+			return d
+		}
+		return current
+	}
+
+	for _, pollPath := range w.pollPaths {
+		if path == pollPath || pathutil.IsBelow(pollPath, path) {
+			interval = shortest(interval, w.defaultPollInterval)
+		}
+	}
+
+	watchedForEvents := false
+	for _, runner := range w.runners {
+		rule := runner.Rule()
+		d := rule.PollInterval(w.defaultPollInterval)
+		for _, loc := range rule.Locations {
+			switch {
+			case path == loc || pathutil.IsBelow(loc, path):
+				if d > 0 {
+					interval = shortest(interval, d)
+				} else {
+					watchedForEvents = true
+				}
+			case d > 0 && pathutil.IsBelow(path, loc):
+				ancestorInterval = shortest(ancestorInterval, d)
+			}
+		}
+	}
+
+	if interval == 0 && !watchedForEvents {
+		interval = ancestorInterval
+	}
+	return interval
+}
+
+// skipSubdir reports whether no recursive rule descends into the directory at
+// path, so it doesn't need to be watched.
+func (w *Watcher) skipSubdir(filesystem fs.FileSystem, path string) bool {
+	for _, runner := range w.runners {
+		if runner.Rule().DescendsInto(filesystem, path) {
+			return false
+		}
+	}
+	return true
+}
+
 // WatchCount returns the number of directories currently being watched.
 func (w *Watcher) WatchCount() int {
 	return w.watchManager.WatchCount()
 }
 
+// holdUnstable returns the hold function for a runner with a stability wait.
+// Files that aren't stable yet are held, and the runner is scheduled to run
+// again once the first of them may have become stable.
+func (w *Watcher) holdUnstable(runner *rules.RuleRunner, wait time.Duration) func(path string) bool {
+	return func(path string) bool {
+		stable, retry := w.stability.Check(path, wait)
+		if stable {
+			return false
+		}
+		if current, ok := w.runnerRetry[runner]; !ok || retry < current {
+			w.runnerRetry[runner] = retry
+		}
+		w.addPending(runner, path)
+		return true
+	}
+}
+
 // executeRunner runs a single runner and persists execution stats.
 func (w *Watcher) executeRunner(runner *rules.RuleRunner) {
 	rule := runner.Rule()
-	stats, err := runner.Execute()
+	if w.stabilityRunners[runner] {
+		w.stability.StartRun()
+	}
+
+	// Take the pending paths before running, so paths held during the run
+	// are pending for the next one
+	pending := w.runnerPending[runner]
+	delete(w.runnerPending, runner)
+
+	var stats *rules.ExecutionStats
+	var err error
+	if !w.incremental || w.runnerFullSweep[runner] {
+		delete(w.runnerFullSweep, runner)
+		stats, err = runner.Execute()
+	} else {
+		paths := make([]string, 0, len(pending))
+		for path := range pending {
+			paths = append(paths, path)
+		}
+		slog.Debug("executing rule on changed paths", "rule", rule.Name, "paths", len(paths))
+		stats, err = runner.ExecutePaths(paths)
+	}
 	if err != nil {
 		slog.Error("rule execution failed", "rule", rule.Name, "error", err)
 	}
 
+	// Run again later for files that were held because they were still changing
+	if retry, ok := w.runnerRetry[runner]; ok {
+		delete(w.runnerRetry, runner)
+		slog.Debug("files held until stable, scheduling another run", "rule", rule.Name, "retry", retry)
+		w.runnerTimers[runner].Reset(retry)
+	}
+
 	// Persist execution stats
 	if w.state != nil && stats != nil {
 		if err := w.state.UpdateRuleStats(rule.Name, stats.StartTime, stats.Duration, stats.FilesProcessed, stats.ErrorCount); err != nil {
 			slog.Warn("failed to persist rule stats", "rule", rule.Name, "error", err)
 		}
 	}
+
+	// Persist file hashes computed by filters such as duplicate
+	if err := state.SaveSharedHashCache(); err != nil {
+		slog.Warn("failed to save hash cache", "rule", rule.Name, "error", err)
+	}
 }
 
 // scheduleRulesForPath schedules execution for all rules that cover the given path.
-// Events that occurred before the cooldown period after rule completion are ignored.
+// Events during the cooldown period after rule completion don't schedule a
+// run, but their paths are still included in the rule's next run.
 func (w *Watcher) scheduleRulesForPath(path string, eventTime time.Time) {
 	for _, runner := range w.runners {
 		rule := runner.Rule()
@@ -230,11 +499,15 @@ func (w *Watcher) scheduleRulesForPath(path string, eventTime time.Time) {
 			continue
 		}
 
-		// Time-based filtering: ignore events during cooldown period after rule execution.
+		// The path may have changed for another reason than the rule's own
+		// actions, so an incremental run must not lose it
+		w.addPending(runner, path)
+
+		// Time-based filtering: don't trigger a run during cooldown period after rule execution.
 		// This prevents cascading re-triggers from the rule's own filesystem changes.
 		filterUntil := runner.LastCompletedTime().Add(w.eventCooldown)
 		if eventTime.Before(filterUntil) {
-			slog.Debug("ignoring event during cooldown", "path", path, "rule", rule.Name)
+			slog.Debug("not scheduling rule during cooldown", "path", path, "rule", rule.Name)
 			continue
 		}
 
@@ -243,3 +516,29 @@ func (w *Watcher) scheduleRulesForPath(path string, eventTime time.Time) {
 		w.runnerTimers[runner].Reset(w.debounceDelay)
 	}
 }
+
+// addPending records that path changed, for the runner's next incremental run.
+func (w *Watcher) addPending(runner *rules.RuleRunner, path string) {
+	if w.runnerPending[runner] == nil {
+		w.runnerPending[runner] = make(map[string]struct{})
+	}
+	w.runnerPending[runner][path] = struct{}{}
+}
+
+// handleOverflow recovers from lost events. The event queue is shared by all
+// watches, so any directory may have missed changes: watches are brought up to
+// date and every rule sweeps its locations.
+func (w *Watcher) handleOverflow() {
+	slog.Warn("filesystem events were lost, rescanning all rules")
+	w.watchManager.Rescan()
+	w.scheduleFullSweeps()
+}
+
+// scheduleFullSweeps schedules a full sweep of every rule.
+func (w *Watcher) scheduleFullSweeps() {
+	slog.Debug("scheduling full sweep of all rules")
+	for _, runner := range w.runners {
+		w.runnerFullSweep[runner] = true
+		w.runnerTimers[runner].Reset(w.debounceDelay)
+	}
+}
@@ -0,0 +1,232 @@
+package watcher
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/prettymuchbryce/autotidy/internal/fs"
+	"github.com/prettymuchbryce/autotidy/internal/rules"
+	"github.com/prettymuchbryce/autotidy/internal/state"
+)
+
+// recordingExecutable records the paths it is executed on.
+type recordingExecutable struct {
+	paths []string
+}
+
+func (e *recordingExecutable) Execute(path string, filesystem fs.FileSystem) (*rules.ExecutionResult, error) {
+	e.paths = append(e.paths, path)
+	return nil, nil
+}
+
+// newTestWatcher creates a Watcher with a single non-recursive rule on the
+// given location, without watching anything. Runs are triggered by calling
+// executeRunner directly.
+func newTestWatcher(t *testing.T, filesystem fs.FileSystem, location string, incremental bool) (*Watcher, *rules.RuleRunner, *recordingExecutable) {
+	t.Helper()
+	recursive := false
+	action := &recordingExecutable{}
+	rule := &rules.Rule{
+		Name:      "test-rule",
+		Locations: rules.StringList{location},
+		Recursive: &recursive,
+		Actions:   []rules.Action{{Name: "record", Inner: action}},
+	}
+	runner := rules.NewRuleRunner(rule, filesystem, nil)
+
+	timer := time.NewTimer(time.Hour)
+	t.Cleanup(func() { timer.Stop() })
+
+	w := &Watcher{
+		runners:          rules.RuleRunners{runner},
+		debounceDelay:    time.Hour,
+		runnerTimers:     map[*rules.RuleRunner]*time.Timer{runner: timer},
+		incremental:      incremental,
+		runnerPending:    make(map[*rules.RuleRunner]map[string]struct{}),
+		runnerFullSweep:  map[*rules.RuleRunner]bool{runner: true},
+		stabilityRunners: make(map[*rules.RuleRunner]bool),
+		runnerRetry:      make(map[*rules.RuleRunner]time.Duration),
+	}
+	return w, runner, action
+}
+
+func TestExecuteRunner_Incremental(t *testing.T) {
+	root := testPath("downloads")
+	a := testPath("downloads", "a.txt")
+	b := testPath("downloads", "b.txt")
+
+	filesystem := fs.NewMem()
+	afero.WriteFile(filesystem, a, []byte("a"), 0644)
+	afero.WriteFile(filesystem, b, []byte("b"), 0644)
+
+	w, runner, action := newTestWatcher(t, filesystem, root, true)
+
+	// The first run sweeps the whole location
+	w.executeRunner(runner)
+	if !slices.Equal(action.paths, []string{a, b}) {
+		t.Fatalf("first run processed %v, want %v", action.paths, []string{a, b})
+	}
+
+	// Later runs only evaluate the changed paths
+	action.paths = nil
+	w.scheduleRulesForPath(b, time.Now())
+	w.executeRunner(runner)
+	if !slices.Equal(action.paths, []string{b}) {
+		t.Fatalf("incremental run processed %v, want %v", action.paths, []string{b})
+	}
+
+	// Pending paths are cleared by the run
+	action.paths = nil
+	w.executeRunner(runner)
+	if len(action.paths) != 0 {
+		t.Fatalf("run without changes processed %v, want nothing", action.paths)
+	}
+
+	// A scheduled full sweep evaluates everything again
+	action.paths = nil
+	w.scheduleFullSweeps()
+	w.executeRunner(runner)
+	if !slices.Equal(action.paths, []string{a, b}) {
+		t.Fatalf("full sweep processed %v, want %v", action.paths, []string{a, b})
+	}
+}
+
+func TestExecuteRunner_NotIncremental(t *testing.T) {
+	root := testPath("downloads")
+	a := testPath("downloads", "a.txt")
+	b := testPath("downloads", "b.txt")
+
+	filesystem := fs.NewMem()
+	afero.WriteFile(filesystem, a, []byte("a"), 0644)
+	afero.WriteFile(filesystem, b, []byte("b"), 0644)
+
+	w, runner, action := newTestWatcher(t, filesystem, root, false)
+	w.executeRunner(runner)
+
+	action.paths = nil
+	w.scheduleRulesForPath(b, time.Now())
+	w.executeRunner(runner)
+	if !slices.Equal(action.paths, []string{a, b}) {
+		t.Errorf("run processed %v, want %v", action.paths, []string{a, b})
+	}
+}
+
+func TestScheduleRulesForPath_Cooldown(t *testing.T) {
+	root := testPath("downloads")
+	a := testPath("downloads", "a.txt")
+	b := testPath("downloads", "b.txt")
+
+	filesystem := fs.NewMem()
+	afero.WriteFile(filesystem, a, []byte("a"), 0644)
+
+	w, runner, action := newTestWatcher(t, filesystem, root, true)
+	w.eventCooldown = time.Hour
+	w.executeRunner(runner)
+	timer := w.runnerTimers[runner]
+	timer.Stop()
+
+	// A change during the cooldown doesn't schedule a run...
+	afero.WriteFile(filesystem, b, []byte("b"), 0644)
+	w.scheduleRulesForPath(b, time.Now())
+	if timer.Stop() {
+		t.Error("expected no run to be scheduled during the cooldown")
+	}
+
+	// ...but the next run still includes it
+	action.paths = nil
+	w.executeRunner(runner)
+	if !slices.Equal(action.paths, []string{b}) {
+		t.Errorf("run after the cooldown processed %v, want %v", action.paths, []string{b})
+	}
+}
+
+func TestHoldUnstable_KeepsPathPending(t *testing.T) {
+	root := testPath("downloads")
+	path := testPath("downloads", "partial.zip")
+
+	filesystem := fs.NewMemTest()
+	w, runner, _ := newTestWatcher(t, filesystem, root, true)
+	tracker, memFs, _, now := newTestStabilityTracker(t)
+	w.stability = tracker
+	writeFileAt(t, memFs, path, "par", *now)
+
+	if !w.holdUnstable(runner, 5*time.Second)(path) {
+		t.Fatal("expected a file modified just now to be held")
+	}
+	if _, ok := w.runnerPending[runner][path]; !ok {
+		t.Error("expected held file to be pending for the retry run")
+	}
+	if w.runnerRetry[runner] != 5*time.Second {
+		t.Errorf("retry = %s, want 5s", w.runnerRetry[runner])
+	}
+}
+
+func TestNextScheduledRun_PersistsAcrossRestarts(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	st, err := state.LoadFrom(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	rule := &rules.Rule{Name: "cleanup", Schedule: &rules.Schedule{Every: 6 * time.Hour}}
+
+	w := &Watcher{state: st}
+	if next := w.nextScheduledRun(rule, now); !next.Equal(now.Add(6 * time.Hour)) {
+		t.Fatalf("next = %s, want %s", next, now.Add(6*time.Hour))
+	}
+
+	// After a restart, the persisted next run is kept
+	st, err = state.LoadFrom(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = &Watcher{state: st}
+	if next := w.nextScheduledRun(rule, now.Add(time.Hour)); !next.Equal(now.Add(6 * time.Hour)) {
+		t.Errorf("next after restart = %s, want %s", next, now.Add(6*time.Hour))
+	}
+
+	// A changed schedule is computed anew
+	rule.Schedule = &rules.Schedule{Every: time.Hour}
+	if next := w.nextScheduledRun(rule, now.Add(time.Hour)); !next.Equal(now.Add(2 * time.Hour)) {
+		t.Errorf("next after schedule change = %s, want %s", next, now.Add(2*time.Hour))
+	}
+}
+
+func TestRunScheduled(t *testing.T) {
+	root := testPath("downloads")
+	a := testPath("downloads", "a.txt")
+	b := testPath("downloads", "b.txt")
+
+	filesystem := fs.NewMem()
+	afero.WriteFile(filesystem, a, []byte("a"), 0644)
+	afero.WriteFile(filesystem, b, []byte("b"), 0644)
+
+	st, err := state.LoadFrom(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, runner, action := newTestWatcher(t, filesystem, root, true)
+	w.state = st
+	runner.Rule().Schedule = &rules.Schedule{Every: time.Hour}
+	timer := time.NewTimer(time.Hour)
+	t.Cleanup(func() { timer.Stop() })
+	w.scheduleTimers = map[*rules.RuleRunner]*time.Timer{runner: timer}
+
+	// Scheduled runs sweep the whole location, even in incremental mode
+	w.executeRunner(runner)
+	action.paths = nil
+	now := time.Now()
+	w.runScheduled(runner, now)
+	if !slices.Equal(action.paths, []string{a, b}) {
+		t.Errorf("scheduled run processed %v, want %v", action.paths, []string{a, b})
+	}
+
+	next, ok := st.NextRun("test-rule", "every 1h")
+	if !ok || !next.Equal(now.Add(time.Hour)) {
+		t.Errorf("persisted next run = %s, %v, want %s", next, ok, now.Add(time.Hour))
+	}
+}